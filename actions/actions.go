@@ -5,6 +5,8 @@ const (
 	ACTION_CALL          byte = 0x01
 	ACTION_REPLY_SUCCESS byte = 0x02 // 别名
 	ACTION_REPLY_ERROR   byte = 0x03 // 别名
+	// 连接建立后交换能力（message.Hello），ID 为 0
+	ACTION_HELLO byte = 0x04
 	// 无效操作
 	ACTION_INVALID byte = 0x00
 	//广播
@@ -0,0 +1,76 @@
+package bench
+
+import (
+	"context"
+	"testing"
+
+	"github.com/w6xian/sloth/v3"
+	"github.com/w6xian/sloth/v3/decoder"
+	"github.com/w6xian/sloth/v3/decoder/ag"
+	"github.com/w6xian/sloth/v3/types/trpc"
+)
+
+// ---------------------------------------------------------------------------
+// 服务方法调用（Connect.CallFunc：方法查找 + 参数解码 + 反射调用 + 返回值编码）
+// ---------------------------------------------------------------------------
+
+type benchUser struct {
+	Id   int64    `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type benchService struct{}
+
+func (s *benchService) Add(ctx context.Context, a int64, b int64) (int64, error) {
+	return a + b, nil
+}
+
+func (s *benchService) Echo(ctx context.Context, data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (s *benchService) Save(ctx context.Context, u *benchUser) (*benchUser, error) {
+	return u, nil
+}
+
+func (s *benchService) Rename(ctx context.Context, id int64, name string, u benchUser) (string, error) {
+	return name, nil
+}
+
+func benchCall(b *testing.B, mtd string, args ...any) {
+	conn := sloth.ServerConn(sloth.DefaultServer())
+	if err := conn.Register("bench", &benchService{}, ""); err != nil {
+		b.Fatal(err)
+	}
+	encoded, err := decoder.EncodeArgs(args, ag.Encoder)
+	if err != nil {
+		b.Fatal(err)
+	}
+	req := &trpc.RpcCaller{Method: mtd, Header: map[string]string{}, Args: encoded}
+	ctx := context.Background()
+	if _, err := conn.CallFunc(ctx, nil, nil, req); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = conn.CallFunc(ctx, nil, nil, req)
+	}
+}
+
+func BenchmarkCallScalar(b *testing.B) {
+	benchCall(b, "bench.Add", int64(1), int64(2))
+}
+
+func BenchmarkCallBytes(b *testing.B) {
+	benchCall(b, "bench.Echo", make([]byte, 128))
+}
+
+func BenchmarkCallStruct(b *testing.B) {
+	benchCall(b, "bench.Save", &benchUser{Id: 1, Name: "sloth", Tags: []string{"a", "b"}})
+}
+
+func BenchmarkCallMixed(b *testing.B) {
+	benchCall(b, "bench.Rename", int64(1), "sloth", benchUser{Id: 2, Name: "x"})
+}
@@ -0,0 +1,96 @@
+package bench
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/w6xian/sloth/v3/decoder/frame"
+)
+
+// ---------------------------------------------------------------------------
+// 分片线上字节开销：JSON 文本分片（D 为 base64）与 frame.Encode 二进制分片
+// wire-B/op 为一条消息全部分片的线上字节数，overhead-% 为相对载荷多出的比例
+// ---------------------------------------------------------------------------
+
+func slicePayload(n int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte(i*31 + i>>8)
+	}
+	return p
+}
+
+func benchSliceWire(b *testing.B, size int, binary bool) {
+	payload := slicePayload(size)
+	mt := frame.TextMessage
+	if binary {
+		mt = frame.BinaryMessage
+	}
+	wire := 0
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		slices, err := frame.Split("bw", payload, 1024, mt)
+		if err != nil {
+			b.Fatal(err)
+		}
+		wire = 0
+		for _, s := range slices {
+			if binary {
+				wire += len(s.Encode())
+			} else {
+				wire += len(s.Bytes())
+			}
+		}
+	}
+	b.ReportMetric(float64(wire), "wire-B/op")
+	b.ReportMetric(float64(wire-size)*100/float64(size), "overhead-%")
+}
+
+func BenchmarkSliceWire(b *testing.B) {
+	for _, size := range []int{512, 4 << 10, 64 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("text/%d", size), func(b *testing.B) { benchSliceWire(b, size, false) })
+		b.Run(fmt.Sprintf("binary/%d", size), func(b *testing.B) { benchSliceWire(b, size, true) })
+	}
+}
+
+// 接收侧：解析 + 重组
+func benchSliceReceive(b *testing.B, size int, binary bool) {
+	slices, err := frame.Split("br", slicePayload(size), 1024, frame.BinaryMessage)
+	if err != nil {
+		b.Fatal(err)
+	}
+	mt := frame.TextMessage
+	raws := make([][]byte, len(slices))
+	for i, s := range slices {
+		if binary {
+			mt = frame.BinaryMessage
+			raws[i] = s.Encode()
+		} else {
+			raws[i] = s.Bytes()
+		}
+	}
+	r := frame.NewReassembler()
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, raw := range raws {
+			s, err := frame.FromType(raw, mt)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := r.Push(s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkSliceReceive(b *testing.B) {
+	for _, size := range []int{4 << 10, 64 << 10} {
+		b.Run(fmt.Sprintf("text/%d", size), func(b *testing.B) { benchSliceReceive(b, size, false) })
+		b.Run(fmt.Sprintf("binary/%d", size), func(b *testing.B) { benchSliceReceive(b, size, true) })
+	}
+}
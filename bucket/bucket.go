@@ -5,6 +5,7 @@ package bucket
 
 import (
 	"context"
+	"errors"
 	"log"
 	"sync"
 	"sync/atomic"
@@ -12,9 +13,27 @@ import (
 	"github.com/w6xian/sloth/v3/message"
 )
 
+// DevicePolicy 用户在线设备数达到上限时对新设备的处理
+type DevicePolicy int
+
+const (
+	// KickOldest 踢掉最早登录的设备，接纳新设备
+	KickOldest DevicePolicy = iota
+	// RejectNew 拒绝新设备，PutDevice 返回 ErrTooManyDevices
+	RejectNew
+)
+
+var ErrTooManyDevices = errors.New("bucket: too many devices")
+
+// Session 用户在一台设备上的连接
+type Session struct {
+	DeviceId string
+	Channel  IChannel
+}
+
 type Bucket struct {
-	cLock sync.RWMutex       // protect the channels for chs
-	chs   map[int64]IChannel // map sub key to a channel
+	cLock sync.RWMutex         // protect the channels for chs
+	chs   map[int64][]*Session // userId -> 各设备的连接，按登录先后排列，最后一个最新
 
 	rooms       map[int64]*Room // bucket room channels
 	routines    []chan *message.PushRoomMsgRequest
@@ -29,6 +48,9 @@ type Bucket struct {
 	RoomSize      int
 	RoutineAmount uint64
 	RoutineSize   int
+	// MaxDevices 每个用户同时在线的设备数上限，<=0 不限制；DevicePolicy 达到上限时的处理
+	MaxDevices   int
+	DevicePolicy DevicePolicy
 }
 
 func NewBucket(opts ...BucketOption) (b *Bucket) {
@@ -41,7 +63,7 @@ func NewBucket(opts ...BucketOption) (b *Bucket) {
 		opt(b)
 	}
 
-	b.chs = make(map[int64]IChannel, b.ChannelSize)
+	b.chs = make(map[int64][]*Session, b.ChannelSize)
 	b.routines = make([]chan *message.PushRoomMsgRequest, b.RoutineAmount)
 	b.rooms = make(map[int64]*Room, b.RoomSize)
 	b.ctx, b.cancel = context.WithCancel(context.Background())
@@ -113,14 +135,16 @@ func (b *Bucket) BroadcastAll(ctx context.Context, msg *message.Msg) (dropped in
 }
 
 // RangeChannels 安全遍历桶内所有在线连接：读锁下快照后锁外回调，fn 返回 false 提前终止。
-// b.chs 是 user->channel 的唯一映射：同一连接即使同时在多个房间，也只会被遍历一次，
-// 天然去重，适合"对每个连接做一次操作"的场景（如 CallBucket 全服 RPC）。
+// b.chs 按用户、设备登记每个连接一次：同一连接即使同时在多个房间，也只会被遍历一次，
+// 天然去重，适合"对每个连接做一次操作"的场景（如 CallBucket 全服 RPC）；多设备用户的每台设备各遍历一次。
 // 与 RangeRooms 的差异：覆盖全部在线连接（含未入任何房间的连接），而非仅房间成员。
 func (b *Bucket) RangeChannels(fn func(ch IChannel) bool) {
 	b.cLock.RLock()
 	chs := make([]IChannel, 0, len(b.chs))
-	for _, ch := range b.chs {
-		chs = append(chs, ch)
+	for _, ss := range b.chs {
+		for _, s := range ss {
+			chs = append(chs, s.Channel)
+		}
 	}
 	b.cLock.RUnlock()
 	for _, ch := range chs {
@@ -137,7 +161,14 @@ func (b *Bucket) Room(rid int64) (room *Room) {
 	return
 }
 
+// Put 登记用户连接，等同于设备 ID 为空的 PutDevice：不区分设备时每个用户只保留一个连接
 func (b *Bucket) Put(userId int64, roomId int64, token string, ch IChannel) (err error) {
+	return b.PutDevice(userId, "", roomId, token, ch)
+}
+
+// PutDevice 登记用户在设备 deviceId 上的连接，同一用户的不同设备可以同时在线。
+// 同一设备的新连接抢占旧连接；新设备超过 MaxDevices 时按 DevicePolicy 踢掉最早的设备或返回 ErrTooManyDevices
+func (b *Bucket) PutDevice(userId int64, deviceId string, roomId int64, token string, ch IChannel) (err error) {
 	var (
 		room *Room
 		ok   bool
@@ -147,21 +178,41 @@ func (b *Bucket) Put(userId int64, roomId int64, token string, ch IChannel) (err
 
 	// 用入参 userId 查重（而非 ch.UserId()）：重连的新连接在 Put 时其 UserId 可能
 	// 尚未设置（=0），若按 ch.UserId() 查会查不到旧连接，导致旧连接残留注册表。
-	if ch0, ch_ok := b.chs[userId]; ch_ok {
-		ch0Room := ch0.Room()
+	ss := b.chs[userId]
+	for i, s := range ss {
+		if s.Channel != ch {
+			continue
+		}
 		// 同一连接对象重复 login：仅更新 token，幂等返回
-		if ch0 == ch {
-			ch0.Token(token)
+		if s.DeviceId == deviceId {
+			ch.Token(token)
 			return
 		}
-		// 不同连接对象（如 Web 端 F5 强刷新重连）：新连接抢占，回收旧连接。
+		// 同一连接换了设备 ID：去掉原登记，按新设备重新登记
+		ss = append(ss[:i:i], ss[i+1:]...)
+		break
+	}
+	if b.DevicePolicy == RejectNew && b.overLimit(ss, deviceId) {
+		return ErrTooManyDevices
+	}
+	for i, s := range ss {
+		if s.DeviceId != deviceId {
+			continue
+		}
+		// 同一设备的不同连接对象（如 Web 端 F5 强刷新重连）：新连接抢占，回收旧连接。
 		// 否则旧连接残留在注册表/房间成员里，后续 CallRoom/Broadcast 全部打在
 		// 已断开的旧连接上 → 稳定超时，且新连接被吞掉（原 bug 的表现）。
-		if ch0Room != nil {
-			ch0Room.Leave(ch0) // 旧连接退出其房间（房间空后 Drop，由后续逻辑重建）
+		b.kick(s.Channel)
+		ss = append(ss[:i:i], ss[i+1:]...) // 移除旧注册，防止残留/误删
+		break
+	}
+	// 超过上限（KickOldest）：踢掉最早登录的设备
+	if b.MaxDevices > 0 && len(ss) >= b.MaxDevices {
+		n := len(ss) - b.MaxDevices + 1
+		for _, s := range ss[:n] {
+			b.kick(s.Channel)
 		}
-		ch0.Close()           // 关闭旧连接（对已断开连接幂等）
-		delete(b.chs, userId) // 移除旧注册，防止残留/误删
+		ss = append(ss[:0:0], ss[n:]...)
 	}
 	// 原来有房间，先退出房间
 	if curRoom := ch.Room(); curRoom != nil {
@@ -182,7 +233,7 @@ func (b *Bucket) Put(userId int64, roomId int64, token string, ch IChannel) (err
 	}
 	ch.UserId(userId)
 	ch.Token(token)
-	b.chs[userId] = ch
+	b.chs[userId] = append(ss, &Session{DeviceId: deviceId, Channel: ch})
 	if room != nil {
 		err = room.Join(ch)
 	}
@@ -223,25 +274,85 @@ func (b *Bucket) DeleteChannel(ch IChannel) {
 	b.cLock.Lock()
 	defer b.cLock.Unlock()
 	// 只删除传入的连接自身：断开的旧连接若晚于新连接执行清理（F5 重连竞态），
-	// b.chs 中该 userId 已是新连接，无 cur==ch 保护会误删新连接。
-	if cur, ok := b.chs[ch.UserId()]; ok && cur == ch {
-		room := cur.Room()
+	// b.chs 中该设备已是新连接，按连接对象查找才不会误删新连接。
+	userId := ch.UserId()
+	ss := b.chs[userId]
+	for i, s := range ss {
+		if s.Channel != ch {
+			continue
+		}
+		room := ch.Room()
 		// delete from bucket
-		delete(b.chs, ch.UserId())
+		if len(ss) == 1 {
+			delete(b.chs, userId)
+		} else {
+			b.chs[userId] = append(ss[:i:i], ss[i+1:]...)
+		}
 		// 房间清空后解散并回收：Leave 返回 Drop（空且非 Plaza）
-		if room != nil && room.Leave(cur) && room.Drop {
+		if room != nil && room.Leave(ch) && room.Drop {
 			delete(b.rooms, room.Id)
 		}
+		return
 	}
 }
 
+// Channel 用户最近登录的设备上的连接
 func (b *Bucket) Channel(userId int64) (ch IChannel) {
 	b.cLock.RLock()
 	defer b.cLock.RUnlock()
-	ch = b.chs[userId]
+	if ss := b.chs[userId]; len(ss) > 0 {
+		ch = ss[len(ss)-1].Channel
+	}
 	return
 }
 
+// Device 用户在设备 deviceId 上的连接，不在线时返回 nil
+func (b *Bucket) Device(userId int64, deviceId string) IChannel {
+	b.cLock.RLock()
+	defer b.cLock.RUnlock()
+	for _, s := range b.chs[userId] {
+		if s.DeviceId == deviceId {
+			return s.Channel
+		}
+	}
+	return nil
+}
+
+// Sessions 用户所有在线设备的连接快照，按登录先后排列，最后一个最新
+func (b *Bucket) Sessions(userId int64) []Session {
+	b.cLock.RLock()
+	defer b.cLock.RUnlock()
+	ss := b.chs[userId]
+	out := make([]Session, len(ss))
+	for i, s := range ss {
+		out[i] = *s
+	}
+	return out
+}
+
+// overLimit 登记 deviceId 上的 ch 是否会超过 MaxDevices（不计同一设备的原登记）
+func (b *Bucket) overLimit(ss []*Session, deviceId string) bool {
+	if b.MaxDevices <= 0 {
+		return false
+	}
+	n := 0
+	for _, s := range ss {
+		if s.DeviceId != deviceId {
+			n++
+		}
+	}
+	return n >= b.MaxDevices
+}
+
+// kick 回收被抢占/踢下线的连接：退出其房间并关闭（对已断开连接幂等），房间因此清空时一并回收。
+// 调用方持有写锁
+func (b *Bucket) kick(ch IChannel) {
+	if room := ch.Room(); room != nil && room.Leave(ch) && room.Drop && b.rooms[room.Id] == room {
+		delete(b.rooms, room.Id)
+	}
+	ch.Close()
+}
+
 // BroadcastRoom 向 worker 池投递房间广播请求（异步），返回是否投递成功。
 // 投递非阻塞：队列满时返回 false（广播是尽力而为，不应让调用方被队列阻塞），
 // 由调用方决定降级（如同步直推）或忽略。
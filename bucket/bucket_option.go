@@ -25,3 +25,11 @@ func WithRoutineSize(routineSize int) BucketOption {
 		b.RoutineSize = routineSize
 	}
 }
+
+// WithMaxDevices 每个用户同时在线的设备数上限（<=0 不限制）及达到上限时的处理
+func WithMaxDevices(max int, policy DevicePolicy) BucketOption {
+	return func(b *Bucket) {
+		b.MaxDevices = max
+		b.DevicePolicy = policy
+	}
+}
@@ -0,0 +1,146 @@
+package bucket
+
+import (
+	"context"
+	"slices"
+
+	"github.com/w6xian/sloth/v3/message"
+)
+
+// Registry 跨 bucket 的房间注册表。
+//
+// 用户按 userId 散列到不同 bucket，同一个逻辑房间在每个有成员的 bucket 里各有一个 Room（分片）。
+// Registry 不另存成员：每次操作时从各 bucket 取该房间未解散的分片，成员增删、房间解散/重建
+// 都由 bucket 维护，不存在两份记录不一致的问题。一个连接只登记在所属 bucket，分片之间没有重复成员。
+type Registry struct {
+	buckets []*Bucket
+}
+
+func NewRegistry(buckets []*Bucket) *Registry {
+	return &Registry{buckets: buckets}
+}
+
+// Room 房间 roomId 的全服视图，没有任何 bucket 有该房间（或都已解散）时返回 nil
+func (g *Registry) Room(roomId int64) *ServerRoom {
+	r := &ServerRoom{Id: roomId, g: g}
+	if len(r.Parts()) == 0 {
+		return nil
+	}
+	return r
+}
+
+// Range 按房间 ID 升序遍历所有未解散的房间，fn 返回 false 时提前终止
+func (g *Registry) Range(fn func(room *ServerRoom) bool) {
+	for _, id := range g.ids() {
+		if !fn(&ServerRoom{Id: id, g: g}) {
+			return
+		}
+	}
+}
+
+// Len 未解散的房间数（同一房间在多个 bucket 中只计一次）
+func (g *Registry) Len() int {
+	return len(g.ids())
+}
+
+func (g *Registry) ids() []int64 {
+	seen := make(map[int64]struct{})
+	for _, b := range g.buckets {
+		if b == nil {
+			continue
+		}
+		b.RangeRooms(func(room *Room) bool {
+			if !room.IsDrop() {
+				seen[room.Id] = struct{}{}
+			}
+			return true
+		})
+	}
+	ids := make([]int64, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// ServerRoom 一个逻辑房间在全服的视图，汇总各 bucket 中同 ID 的 Room。
+// 每次调用都重新取分片，持有的 ServerRoom 能看到之后在其他 bucket 加入的成员
+type ServerRoom struct {
+	Id int64
+	g  *Registry
+}
+
+// Parts 各 bucket 中该房间未解散的分片
+func (r *ServerRoom) Parts() []*Room {
+	var parts []*Room
+	for _, b := range r.g.buckets {
+		if b == nil {
+			continue
+		}
+		if room := b.Room(r.Id); room != nil && !room.IsDrop() {
+			parts = append(parts, room)
+		}
+	}
+	return parts
+}
+
+// Len 全服在线成员数
+func (r *ServerRoom) Len() int {
+	n := 0
+	for _, room := range r.Parts() {
+		n += room.Len()
+	}
+	return n
+}
+
+// IsDrop 所有分片都已解散（或不存在）
+func (r *ServerRoom) IsDrop() bool {
+	return len(r.Parts()) == 0
+}
+
+// Contains 判断 channel 是否在房间中（任一分片）
+func (r *ServerRoom) Contains(ch IChannel) bool {
+	for _, room := range r.Parts() {
+		if room.Contains(ch) {
+			return true
+		}
+	}
+	return false
+}
+
+// Range 遍历全服所有成员，fn 返回 false 时提前终止。
+// 逐个分片快照后回调（见 Room.Range），fn 中可安全调用 Join/Leave
+func (r *ServerRoom) Range(fn func(ch IChannel) bool) {
+	stop := false
+	for _, room := range r.Parts() {
+		room.Range(func(ch IChannel) bool {
+			stop = !fn(ch)
+			return !stop
+		})
+		if stop {
+			return
+		}
+	}
+}
+
+// Each 遍历全服所有成员
+func (r *ServerRoom) Each(fn func(ch IChannel)) {
+	for _, room := range r.Parts() {
+		room.Each(fn)
+	}
+}
+
+// EachBatch 分批遍历全服成员，批次不跨分片
+func (r *ServerRoom) EachBatch(batchSize int, fn func(chs []IChannel)) {
+	for _, room := range r.Parts() {
+		room.EachBatch(batchSize, fn)
+	}
+}
+
+// Broadcast 向全服所有成员广播消息（见 Room.Broadcast）
+func (r *ServerRoom) Broadcast(ctx context.Context, msg *message.Msg) {
+	for _, room := range r.Parts() {
+		room.Broadcast(ctx, msg)
+	}
+}
@@ -2,6 +2,7 @@ package bucket
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -515,11 +516,8 @@ func TestBucketReconnectReplace(t *testing.T) {
 	}
 
 	// 新连接接管注册表
-	b.cLock.RLock()
-	cur := b.chs[1]
-	b.cLock.RUnlock()
-	if cur != newCh {
-		t.Fatalf("chs[1] = %v, want new channel", cur)
+	if cur := b.Channel(1); cur != newCh {
+		t.Fatalf("Channel(1) = %v, want new channel", cur)
 	}
 	// 旧连接被关闭并退出房间
 	if !oldCh.closed.Load() {
@@ -555,10 +553,7 @@ func TestBucketDeleteChannelOnlySelf(t *testing.T) {
 	}
 	// 旧连接的清理晚到：不得删除新连接
 	b.DeleteChannel(oldCh)
-	b.cLock.RLock()
-	cur, ok := b.chs[1]
-	b.cLock.RUnlock()
-	if !ok || cur != newCh {
+	if cur := b.Channel(1); cur != newCh {
 		t.Fatal("DeleteChannel of a stale connection must not remove the new one")
 	}
 	if r := b.Room(10); r == nil || !r.Contains(newCh) {
@@ -595,3 +590,195 @@ func TestBucketRangeChannelsConcurrentPutDelete(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+// ---------------------------------------------------------------------------
+// 多设备
+// ---------------------------------------------------------------------------
+
+// TestBucketMultiDevice 验证同一用户多设备同时在线：Channel 返回最近登录的设备，
+// Device 按设备 ID 查找，同一设备重连仍抢占旧连接，DeleteChannel 只删自身。
+func TestBucketMultiDevice(t *testing.T) {
+	b := NewBucket(WithRoutineAmount(2))
+	defer b.Close()
+
+	phone, desktop := &mockChannel{id: 1}, &mockChannel{id: 2}
+	if err := b.PutDevice(7, "phone", 10, "t1", phone); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.PutDevice(7, "desktop", 10, "t2", desktop); err != nil {
+		t.Fatal(err)
+	}
+	if phone.closed.Load() {
+		t.Fatal("second device must not kick the first")
+	}
+	if b.Channel(7) != desktop || b.Device(7, "phone") != phone || b.Device(7, "tablet") != nil {
+		t.Fatal("Channel/Device lookup mismatch")
+	}
+	if r := b.Room(10); r == nil || !r.Contains(phone) || !r.Contains(desktop) {
+		t.Fatal("room 10 should contain both devices")
+	}
+	n := 0
+	b.RangeChannels(func(ch IChannel) bool { n++; return true })
+	if n != 2 {
+		t.Fatalf("RangeChannels visited %d, want 2", n)
+	}
+
+	// 同一设备重连：抢占旧连接，且成为最近登录的设备
+	phone2 := &mockChannel{id: 3}
+	if err := b.PutDevice(7, "phone", 10, "t3", phone2); err != nil {
+		t.Fatal(err)
+	}
+	if !phone.closed.Load() || desktop.closed.Load() {
+		t.Fatal("only the old phone connection should be closed")
+	}
+	ss := b.Sessions(7)
+	if len(ss) != 2 || ss[0].Channel != desktop || ss[1].Channel != phone2 || ss[1].DeviceId != "phone" {
+		t.Fatalf("Sessions = %+v", ss)
+	}
+
+	b.DeleteChannel(phone) // 晚到的旧连接清理
+	b.DeleteChannel(phone2)
+	if b.Channel(7) != desktop || len(b.Sessions(7)) != 1 {
+		t.Fatal("DeleteChannel should only remove its own device")
+	}
+	b.DeleteChannel(desktop)
+	if b.Channel(7) != nil || b.Room(10) != nil {
+		t.Fatal("user and empty room should be removed")
+	}
+}
+
+// TestBucketMaxDevices 验证设备数上限：KickOldest 踢掉最早的设备，RejectNew 拒绝新设备，
+// 同一设备重连不计入上限。
+func TestBucketMaxDevices(t *testing.T) {
+	t.Run("kick-oldest", func(t *testing.T) {
+		b := NewBucket(WithRoutineAmount(2), WithMaxDevices(2, KickOldest))
+		defer b.Close()
+		chs := newMockChannels(3)
+		for i, ch := range chs {
+			if err := b.PutDevice(1, string(rune('a'+i)), 10, "t", ch); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !chs[0].closed.Load() || chs[1].closed.Load() || chs[2].closed.Load() {
+			t.Fatal("the oldest device should be kicked")
+		}
+		if b.Device(1, "a") != nil || b.Room(10).Contains(chs[0]) || len(b.Sessions(1)) != 2 {
+			t.Fatal("kicked device should be removed from registry and room")
+		}
+	})
+	t.Run("reject-new", func(t *testing.T) {
+		b := NewBucket(WithRoutineAmount(2), WithMaxDevices(2, RejectNew))
+		defer b.Close()
+		chs := newMockChannels(4)
+		for i, ch := range chs[:2] {
+			if err := b.PutDevice(1, string(rune('a'+i)), 10, "t", ch); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := b.PutDevice(1, "c", 10, "t", chs[2]); !errors.Is(err, ErrTooManyDevices) {
+			t.Fatalf("PutDevice err = %v, want ErrTooManyDevices", err)
+		}
+		if chs[0].closed.Load() || chs[1].closed.Load() || b.Room(10).Contains(chs[2]) {
+			t.Fatal("rejected device must not affect existing ones")
+		}
+		// 同一设备重连不受上限影响
+		if err := b.PutDevice(1, "a", 10, "t", chs[3]); err != nil {
+			t.Fatal(err)
+		}
+		if !chs[0].closed.Load() || b.Device(1, "a") != chs[3] {
+			t.Fatal("same-device reconnect should replace the old connection")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// 跨 bucket 房间
+// ---------------------------------------------------------------------------
+
+// TestRegistryServerRoom 验证同一房间分散在多个 bucket 时，ServerRoom 汇总全部成员：
+// Len/Range/Contains/Broadcast 覆盖所有分片，后加入其他 bucket 的成员也可见。
+func TestRegistryServerRoom(t *testing.T) {
+	bs := []*Bucket{NewBucket(WithRoutineAmount(2)), NewBucket(WithRoutineAmount(2)), NewBucket(WithRoutineAmount(2))}
+	for _, b := range bs {
+		defer b.Close()
+	}
+	g := NewRegistry(bs)
+
+	chs := newMockChannels(7)
+	// 房间 10 的成员分布在三个 bucket；chs[6] 在房间 20
+	for i, ch := range chs[:6] {
+		if err := bs[i%3].Put(int64(i+500), 10, "t", ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := bs[0].Put(506, 20, "t", chs[6]); err != nil {
+		t.Fatal(err)
+	}
+
+	room := g.Room(10)
+	if room == nil || len(room.Parts()) != 3 {
+		t.Fatalf("Room(10) should span 3 buckets, got %v", room)
+	}
+	if room.Len() != 6 {
+		t.Fatalf("Len() = %d, want 6", room.Len())
+	}
+	if !room.Contains(chs[5]) || room.Contains(chs[6]) {
+		t.Fatal("Contains mismatch")
+	}
+	seen := make(map[IChannel]int)
+	room.Range(func(ch IChannel) bool { seen[ch]++; return true })
+	if len(seen) != 6 {
+		t.Fatalf("Range visited %d members, want 6", len(seen))
+	}
+	cnt := 0
+	room.Range(func(ch IChannel) bool { cnt++; return cnt < 4 })
+	if cnt != 4 {
+		t.Fatalf("Range early stop got %d, want 4", cnt)
+	}
+	room.Broadcast(context.Background(), &message.Msg{})
+	for i, ch := range chs[:6] {
+		if ch.push.Load() != 1 {
+			t.Fatalf("member %d pushed %d times, want 1", i, ch.push.Load())
+		}
+	}
+	if chs[6].push.Load() != 0 {
+		t.Fatal("member of another room should not receive the broadcast")
+	}
+
+	// 之后加入的成员对已持有的 ServerRoom 可见
+	late := &mockChannel{id: 99}
+	if err := bs[1].Put(599, 10, "t", late); err != nil {
+		t.Fatal(err)
+	}
+	if room.Len() != 7 || !room.Contains(late) {
+		t.Fatal("ServerRoom should see members joined later")
+	}
+
+	var ids []int64
+	g.Range(func(r *ServerRoom) bool { ids = append(ids, r.Id); return true })
+	if g.Len() != 2 || len(ids) != 2 || ids[0] != 10 || ids[1] != 20 {
+		t.Fatalf("Registry rooms = %v, want [10 20]", ids)
+	}
+}
+
+// TestRegistryRoomDropped 验证所有分片解散后 Room 返回 nil，单个分片解散不影响其他分片。
+func TestRegistryRoomDropped(t *testing.T) {
+	bs := []*Bucket{NewBucket(WithRoutineAmount(2)), NewBucket(WithRoutineAmount(2))}
+	for _, b := range bs {
+		defer b.Close()
+	}
+	g := NewRegistry(bs)
+	a, c := &mockChannel{id: 1}, &mockChannel{id: 2}
+	_ = bs[0].Put(1, 10, "t", a)
+	_ = bs[1].Put(2, 10, "t", c)
+
+	bs[0].DeleteChannel(a)
+	room := g.Room(10)
+	if room == nil || room.Len() != 1 || len(room.Parts()) != 1 {
+		t.Fatal("room 10 should survive in the other bucket")
+	}
+	bs[1].DeleteChannel(c)
+	if !room.IsDrop() || g.Room(10) != nil || g.Len() != 0 {
+		t.Fatal("room 10 should be gone after all parts dropped")
+	}
+}
@@ -51,6 +51,13 @@ func (c *ClientRpc) SetDecoder(decoder Decoder) {
 	c.Decoder = decoder
 }
 
+func (c *ClientRpc) SetCodec(name string) {
+	if c.Header == nil {
+		c.Header = message.Header{}
+	}
+	c.Header.Set(CodecHeader, name)
+}
+
 func GetChannel(ctx context.Context) (bucket.IChannel, error) {
 	ch, ok := ctx.Value(ChannelKey).(bucket.IChannel)
 	if !ok {
@@ -74,6 +81,7 @@ func GetHeader(ctx context.Context) (message.Header, error) {
 }
 
 // @call client
+// Call 调用用户最近登录的设备；指定设备用 CallDevice，所有设备用 CallDevices
 func (c *ClientRpc) Call(ctx context.Context, userId int64, mtd string, arg ...any) ([]byte, error) {
 	if c.Serve == nil {
 		return nil, errors.New("server not found")
@@ -83,7 +91,7 @@ func (c *ClientRpc) Call(ctx context.Context, userId int64, mtd string, arg ...a
 	if ch == nil {
 		return nil, errors.New("channel not found")
 	}
-	args, err := decoder.EncodeArgs(arg, c.Encoder)
+	args, err := encode_args(peer_encoder(c.Encoder, ch), arg, c.Header)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +103,61 @@ func (c *ClientRpc) Call(ctx context.Context, userId int64, mtd string, arg ...a
 	return resp, nil
 }
 
+// CallDevice 调用用户在设备 deviceId 上的连接（Bucket.PutDevice 登记的设备）
+func (c *ClientRpc) CallDevice(ctx context.Context, userId int64, deviceId string, mtd string, arg ...any) ([]byte, error) {
+	if c.Serve == nil {
+		return nil, errors.New("server not found")
+	}
+	ch := c.Serve.Bucket(userId).Device(userId, deviceId)
+	if ch == nil {
+		return nil, errors.New("channel not found")
+	}
+	args, err := encode_args(peer_encoder(c.Encoder, ch), arg, c.Header)
+	if err != nil {
+		return nil, err
+	}
+	return ch.Call(ctx, c.Header.Clone(), mtd, args...)
+}
+
+// CallDevices 并发调用用户所有在线设备，每设备独立超时（defaultCallTimeout）。
+// 返回成功设备的结果（按设备 ID），失败的设备合并为一个 error；用户不在线时返回 channel not found
+func (c *ClientRpc) CallDevices(ctx context.Context, userId int64, mtd string, arg ...any) (map[string][]byte, error) {
+	if c.Serve == nil {
+		return nil, errors.New("server not found")
+	}
+	sessions := c.Serve.Bucket(userId).Sessions(userId)
+	if len(sessions) == 0 {
+		return nil, errors.New("channel not found")
+	}
+	args, err := encode_args(c.Encoder, arg, c.Header)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		resp = make(map[string][]byte, len(sessions))
+		errs []error
+	)
+	for _, s := range sessions {
+		header := c.Header.Clone()
+		wg.Go(func() {
+			callCtx, cancel := context.WithTimeout(ctx, defaultCallTimeout)
+			defer cancel()
+			b, err := s.Channel.Call(callCtx, header, mtd, args...)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("device %q: %w", s.DeviceId, err))
+				return
+			}
+			resp[s.DeviceId] = b
+		})
+	}
+	wg.Wait()
+	return resp, errors.Join(errs...)
+}
+
 // @call clientNet
 func (c *ClientRpc) CallNet(ctx context.Context, proxyService int64, msgId uint64, data []byte) ([]byte, error) {
 	if c.Serve == nil {
@@ -122,7 +185,7 @@ func (c *ClientRpc) CallWithHeader(ctx context.Context, header message.Header, u
 	if ch == nil {
 		return nil, errors.New("channel not found")
 	}
-	args, err := decoder.EncodeArgs(arg, c.Encoder)
+	args, err := encode_args(peer_encoder(c.Encoder, ch), arg, header, c.Header)
 	if err != nil {
 		return nil, err
 	}
@@ -177,15 +240,16 @@ const defaultCallTimeout = 5 * time.Second
 // callRoomConcurrency CallRoom 并发调用上限，防止房间成员过多时 goroutine 爆炸。
 const callRoomConcurrency = 64
 
+// CallRoom 调用房间 roomId 的全部成员（汇总所有 bucket）
 func (c *ClientRpc) CallRoom(ctx context.Context, roomId int64, mtd string, arg ...any) ([]byte, error) {
 	if c.Serve == nil {
 		return nil, errors.New("server not found")
 	}
-	room := c.Serve.Room(roomId)
-	if room == nil || room.IsDrop() {
+	room := c.Serve.ServerRoom(roomId)
+	if room == nil {
 		return nil, errors.New("room not found")
 	}
-	args, err := decoder.EncodeArgs(arg, c.Encoder)
+	args, err := encode_args(c.Encoder, arg, c.Header)
 	if err != nil {
 		return nil, err
 	}
@@ -225,7 +289,7 @@ func (c *ClientRpc) CallBucket(ctx context.Context, mtd string, arg ...any) ([]b
 	if c.Serve == nil {
 		return nil, errors.New("server not found")
 	}
-	args, err := decoder.EncodeArgs(arg, c.Encoder)
+	args, err := encode_args(c.Encoder, arg, c.Header)
 	if err != nil {
 		return nil, err
 	}
@@ -264,17 +328,15 @@ func (c *ClientRpc) CallBucket(ctx context.Context, mtd string, arg ...any) ([]b
 	return []byte{}, nil
 }
 
+// Room 向房间 roomId 的全部成员（汇总所有 bucket）推送消息
 func (c *ClientRpc) Room(ctx context.Context, roomId int64, action int, data string) {
 	if c.Serve == nil {
 		return
 	}
-	room := c.Serve.Room(roomId)
+	room := c.Serve.ServerRoom(roomId)
 	if room == nil {
 		return
 	}
-	if room.IsDrop() {
-		return
-	}
 	cmd := message.CmdReq{
 		Id:     decoder.NextId(),
 		Ts:     time.Now().Unix(),
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"log"
 
-	"github.com/w6xian/sloth/v3/decoder"
 	"github.com/w6xian/sloth/v3/decoder/ag"
 	"github.com/w6xian/sloth/v3/message"
 	"github.com/w6xian/sloth/v3/types/auth"
@@ -29,6 +28,13 @@ func (c *ServerRpc) SetEncoder(encoder Encoder) {
 func (c *ServerRpc) SetDecoder(decoder Decoder) {
 	c.Decoder = decoder
 }
+
+func (c *ServerRpc) SetCodec(name string) {
+	if c.Header == nil {
+		c.Header = message.Header{}
+	}
+	c.Header.Set(CodecHeader, name)
+}
 func (c *ServerRpc) SetAuthInfo(auth *auth.AuthInfo) error {
 	if auth == nil {
 		return errors.New("auth is nil")
@@ -71,7 +77,7 @@ func (c *ServerRpc) Call(ctx context.Context, mtd string, arg ...any) ([]byte, e
 	if c.Listen == nil {
 		return nil, errors.New("server not found")
 	}
-	args, err := decoder.EncodeArgs(arg, c.Encoder)
+	args, err := encode_args(peer_encoder(c.Encoder, c.Listen), arg, c.Header)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +93,7 @@ func (c *ServerRpc) CallWithHeader(ctx context.Context, header message.Header, m
 	if c.Listen == nil {
 		return nil, errors.New("server not found")
 	}
-	args, err := decoder.EncodeArgs(arg, c.Encoder)
+	args, err := encode_args(peer_encoder(c.Encoder, c.Listen), arg, header, c.Header)
 	if err != nil {
 		return nil, err
 	}
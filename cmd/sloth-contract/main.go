@@ -0,0 +1,137 @@
+// sloth-contract 连接运行中的 sloth 服务，调用 sys.Contract 导出服务契约（JSON Schema + 方法目录），
+// 或据此生成 Web SDK 之上的 TypeScript/JavaScript 客户端存根（见 tsgen）
+//
+// 用法：
+//
+//	sloth-contract -addr localhost:8990 -o api.json
+//	sloth-contract -addr localhost:8990 -service v1
+//	sloth-contract -addr localhost:8990 -format ts -o sloth_api.ts
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/w6xian/sloth/v3"
+	"github.com/w6xian/sloth/v3/option"
+	"github.com/w6xian/sloth/v3/schema"
+	"github.com/w6xian/sloth/v3/tsgen"
+)
+
+func main() {
+	network := flag.String("network", "ws", "network: ws or wss")
+	addr := flag.String("addr", "localhost:8990", "server address")
+	path := flag.String("path", "/ws", "websocket uri path")
+	service := flag.String("service", "", "only export this service")
+	format := flag.String("format", "json", "output format: json, ts or js")
+	out := flag.String("o", "", "output file, default stdout")
+	timeout := flag.Duration("timeout", 10*time.Second, "connect and call timeout")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	ct, err := fetch(ctx, *network, *addr, *path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sloth-contract:", err)
+		os.Exit(1)
+	}
+	if *service != "" {
+		if ct, err = filter(ct, *service); err != nil {
+			fmt.Fprintln(os.Stderr, "sloth-contract:", err)
+			os.Exit(1)
+		}
+	}
+	b, err := render(ct, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sloth-contract:", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		os.Stdout.Write(b)
+		return
+	}
+	if err := os.WriteFile(*out, b, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "sloth-contract:", err)
+		os.Exit(1)
+	}
+}
+
+func render(ct *sloth.Contract, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(ct, "", "  ")
+		return append(b, '\n'), err
+	case "ts":
+		return tsgen.Generate(ct, tsgen.LangTS)
+	case "js":
+		return tsgen.Generate(ct, tsgen.LangJS)
+	}
+	return nil, fmt.Errorf("unknown format %q, want json, ts or js", format)
+}
+
+// fetch 建立连接并调用 sys.Contract，连接建立前的调用失败会重试直到超时
+func fetch(ctx context.Context, network, addr, path string) (*sloth.Contract, error) {
+	client := sloth.DefaultClient()
+	conn := sloth.ClientConn(client)
+	go conn.Dial(ctx, network, addr, option.WithUriPath(path))
+
+	method := sloth.SysService + ".Contract"
+	for {
+		ct, err := sloth.Invoke[*sloth.Contract](ctx, client, method)
+		if err == nil {
+			return ct, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("call %s: %w", method, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// filter 只保留一个服务，$defs 中只留下它引用到的定义
+func filter(ct *sloth.Contract, service string) (*sloth.Contract, error) {
+	for _, s := range ct.Services {
+		if s.Name != service {
+			continue
+		}
+		defs := make(map[string]*schema.Schema)
+		for _, m := range s.Methods {
+			for _, p := range m.Params {
+				collect(p.Schema, ct.Defs, defs)
+			}
+			collect(m.Result, ct.Defs, defs)
+		}
+		ct.Services = []sloth.ServiceContract{s}
+		ct.Defs = defs
+		return ct, nil
+	}
+	return nil, fmt.Errorf("service %s not found", service)
+}
+
+// collect 把 s 引用到的定义（递归）从 all 复制到 dst
+func collect(s *schema.Schema, all, dst map[string]*schema.Schema) {
+	if s == nil {
+		return
+	}
+	if name, ok := strings.CutPrefix(s.Ref, schema.DefsPrefix); ok {
+		if _, seen := dst[name]; !seen && all[name] != nil {
+			dst[name] = all[name]
+			collect(all[name], all, dst)
+		}
+	}
+	collect(s.Items, all, dst)
+	for _, p := range s.PrefixItems {
+		collect(p, all, dst)
+	}
+	collect(s.AdditionalProperties, all, dst)
+	for _, p := range s.Properties {
+		collect(p, all, dst)
+	}
+}
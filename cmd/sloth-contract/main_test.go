@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/w6xian/sloth/v3"
+)
+
+type Order struct {
+	Id   int64 `json:"id"`
+	Line *Line `json:"line"`
+}
+
+type Line struct {
+	Sku string `json:"sku"`
+}
+
+type Note struct {
+	Text string `json:"text"`
+}
+
+type orderSvc struct{}
+
+// Get 多返回值的结果为 prefixItems 元组
+func (s *orderSvc) Get(ctx context.Context, id int64) (*Order, int, error) { return nil, 0, nil }
+
+type noteSvc struct{}
+
+func (s *noteSvc) Add(ctx context.Context, n *Note) error { return nil }
+
+func TestFilter(t *testing.T) {
+	c := sloth.ServerConn(sloth.DefaultServer())
+	if err := c.Register("order", &orderSvc{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Register("note", &noteSvc{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	ct, err := filter(c.Contract(), "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ct.Services) != 1 || ct.Services[0].Name != "order" {
+		t.Fatalf("services = %+v", ct.Services)
+	}
+	// 元组中的结构体及其引用的定义都保留，其他服务的定义去掉
+	for _, name := range []string{"main.Order", "main.Line"} {
+		if ct.Defs[name] == nil {
+			t.Errorf("missing def %s in %v", name, ct.Defs)
+		}
+	}
+	if ct.Defs["main.Note"] != nil {
+		t.Errorf("unexpected def Note")
+	}
+	if _, err := filter(c.Contract(), "none"); err == nil {
+		t.Fatal("want error for unknown service")
+	}
+}
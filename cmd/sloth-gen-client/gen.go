@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const slothImport = "github.com/w6xian/sloth/v3"
+
+// config 生成参数
+type config struct {
+	TypeName string // 服务结构体名，如 HelloService
+	Service  string // 注册名，如 v1
+	Client   string // 生成的客户端类型名，如 HelloClient
+	Rpc      string // server: 基于 *sloth.ServerRpc；client: 基于 *sloth.ClientRpc（按 userId 调用）
+	Package  string // 输出包名
+	SrcPkg   string // 源包名，与 Package 不同时需要限定类型
+	SrcPath  string // 源包导入路径，与 Package 不同时需要
+}
+
+// method 待生成的方法
+type method struct {
+	Name     string
+	Params   []param
+	Result   ast.Expr // nil 表示仅返回 error
+	Variadic bool
+	Doc      string
+}
+
+type param struct {
+	Name string
+	Type ast.Expr
+}
+
+var errNoMethods = errors.New("no callable methods found")
+
+// collect 从已解析的文件中收集 TypeName 的可调用方法，并记录方法签名用到的导入
+func collect(files []*ast.File, typeName string) ([]method, map[string]string, error) {
+	var methods []method
+	imports := make(map[string]string) // 包名 -> 导入路径
+	for _, f := range files {
+		fileImports := file_imports(f)
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 || !fd.Name.IsExported() {
+				continue
+			}
+			if recv_name(fd.Recv.List[0].Type) != typeName {
+				continue
+			}
+			m, reason := to_method(fd, fileImports)
+			if reason != "" {
+				fmt.Fprintf(stderr, "skip %s.%s: %s\n", typeName, fd.Name.Name, reason)
+				continue
+			}
+			for _, p := range m.Params {
+				used_imports(p.Type, fileImports, imports)
+			}
+			if m.Result != nil {
+				used_imports(m.Result, fileImports, imports)
+			}
+			methods = append(methods, m)
+		}
+	}
+	if len(methods) == 0 {
+		return nil, nil, fmt.Errorf("%w on %s", errNoMethods, typeName)
+	}
+	slices.SortFunc(methods, func(a, b method) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return methods, imports, nil
+}
+
+// to_method 校验签名（与 ref.suitable_methods 一致）：第一个参数 context.Context，返回 error 或 (T, error)
+func to_method(fd *ast.FuncDecl, imports map[string]string) (method, string) {
+	m := method{Name: fd.Name.Name}
+	if fd.Doc != nil {
+		m.Doc = strings.TrimSpace(fd.Doc.Text())
+	}
+	var params []param
+	for _, field := range fd.Type.Params.List {
+		if len(field.Names) == 0 {
+			params = append(params, param{Type: field.Type})
+			continue
+		}
+		for _, n := range field.Names {
+			params = append(params, param{Name: n.Name, Type: field.Type})
+		}
+	}
+	if len(params) == 0 || !is_context(params[0].Type, imports) {
+		return m, "first argument must be context.Context"
+	}
+	params = params[1:]
+	used := map[string]bool{"c": true, "ctx": true, "userId": true, "args": true}
+	for i := range params {
+		name := params[i].Name
+		if name == "" || name == "_" {
+			name = "arg" + strconv.Itoa(i)
+		}
+		for used[name] {
+			name += "_"
+		}
+		used[name] = true
+		params[i].Name = name
+		if _, ok := params[i].Type.(*ast.Ellipsis); ok {
+			m.Variadic = true
+		}
+	}
+	m.Params = params
+
+	var results []ast.Expr
+	if fd.Type.Results != nil {
+		for _, field := range fd.Type.Results.List {
+			n := max(len(field.Names), 1)
+			for range n {
+				results = append(results, field.Type)
+			}
+		}
+	}
+	if len(results) < 1 {
+		return m, "last return value must be error"
+	}
+	if len(results) > 2 {
+		return m, "multiple results (tuple reply) are not supported, call it with sloth.Invoke[[]any]"
+	}
+	if id, ok := results[len(results)-1].(*ast.Ident); !ok || id.Name != "error" {
+		return m, "last return value must be error"
+	}
+	if len(results) == 2 {
+		m.Result = results[0]
+	}
+	return m, ""
+}
+
+// generate 输出格式化后的 Go 源码
+func generate(cfg config, methods []method, imports map[string]string) ([]byte, error) {
+	qualify := cfg.SrcPkg != "" && cfg.SrcPkg != cfg.Package
+	if qualify {
+		imports[cfg.SrcPkg] = cfg.SrcPath
+	}
+	imports["context"] = "context"
+	imports["sloth"] = slothImport
+
+	rpcType, invoke, call := "*sloth.ServerRpc", "sloth.Invoke", "c.rpc.Call(ctx, "
+	userArg, userParam := "", ""
+	if cfg.Rpc == "client" {
+		rpcType, invoke, call = "*sloth.ClientRpc", "sloth.InvokeUser", "c.rpc.Call(ctx, userId, "
+		userArg, userParam = "userId, ", "userId int64, "
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by sloth-gen-client. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", cfg.Package)
+	// 标准库在前，第三方在后，两组之间空一行
+	var std, other bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(imports)) {
+		path := imports[name]
+		w := &other
+		if !strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			w = &std
+		}
+		if name == import_name(path) {
+			fmt.Fprintf(w, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(w, "\t%s %q\n", name, path)
+		}
+	}
+	b.WriteString("import (\n")
+	b.Write(std.Bytes())
+	if std.Len() > 0 && other.Len() > 0 {
+		b.WriteString("\n")
+	}
+	b.Write(other.Bytes())
+	b.WriteString(")\n\n")
+
+	fmt.Fprintf(&b, "// %s %s 服务（%s）的类型化客户端\n", cfg.Client, cfg.Service, cfg.TypeName)
+	fmt.Fprintf(&b, "type %s struct {\n\trpc %s\n}\n\n", cfg.Client, rpcType)
+	fmt.Fprintf(&b, "func New%s(rpc %s) *%s {\n\treturn &%s{rpc: rpc}\n}\n", cfg.Client, rpcType, cfg.Client, cfg.Client)
+
+	for _, m := range methods {
+		if qualify {
+			if name := unexported_type(m); name != "" {
+				return nil, fmt.Errorf("%s.%s uses unexported type %s, generate into package %s instead", cfg.TypeName, m.Name, name, cfg.SrcPkg)
+			}
+		}
+		b.WriteString("\n")
+		if m.Doc != "" {
+			for _, line := range strings.Split(m.Doc, "\n") {
+				fmt.Fprintf(&b, "// %s\n", line)
+			}
+		}
+		decls := make([]string, 0, len(m.Params))
+		names := make([]string, 0, len(m.Params))
+		for _, p := range m.Params {
+			t := p.Type
+			if qualify {
+				t = qualify_expr(t, cfg.SrcPkg)
+			}
+			decls = append(decls, p.Name+" "+expr_string(t))
+			names = append(names, p.Name)
+		}
+		mtd := strconv.Quote(cfg.Service + "." + m.Name)
+		fmt.Fprintf(&b, "func (c *%s) %s(ctx context.Context, %s%s) ", cfg.Client, m.Name, userParam, strings.Join(decls, ", "))
+		// 变参逐个展开为独立参数
+		args := strings.Join(names, ", ")
+		pre := ""
+		if m.Variadic {
+			last := names[len(names)-1]
+			pre = fmt.Sprintf("\targs := make([]any, 0, %d+len(%s))\n", len(names)-1, last)
+			if len(names) > 1 {
+				pre += fmt.Sprintf("\targs = append(args, %s)\n", strings.Join(names[:len(names)-1], ", "))
+			}
+			pre += fmt.Sprintf("\tfor _, v := range %s {\n\t\targs = append(args, v)\n\t}\n", last)
+			args = "args..."
+		}
+		if args != "" {
+			args = ", " + args
+		}
+		if m.Result == nil {
+			fmt.Fprintf(&b, "error {\n%s\t_, err := %s%s%s)\n\treturn err\n}\n", pre, call, mtd, args)
+			continue
+		}
+		rt := m.Result
+		if qualify {
+			rt = qualify_expr(rt, cfg.SrcPkg)
+		}
+		rs := expr_string(rt)
+		fmt.Fprintf(&b, "(%s, error) {\n%s\treturn %s[%s](ctx, c.rpc, %s%s%s)\n}\n", rs, pre, invoke, rs, userArg, mtd, args)
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return b.Bytes(), fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+func recv_name(e ast.Expr) string {
+	switch t := e.(type) {
+	case *ast.StarExpr:
+		return recv_name(t.X)
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return recv_name(t.X)
+	case *ast.IndexListExpr:
+		return recv_name(t.X)
+	}
+	return ""
+}
+
+func is_context(e ast.Expr, imports map[string]string) bool {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && imports[x.Name] == "context"
+}
+
+// file_imports 文件内 包名 -> 导入路径（按路径最后一段推断未命名导入的包名）
+func file_imports(f *ast.File) map[string]string {
+	m := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := import_name(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		m[name] = path
+	}
+	return m
+}
+
+// import_name 未命名导入的默认包名：路径最后一段，跳过 /vN 版本后缀，去掉 .vN（gopkg.in）
+func import_name(path string) string {
+	segs := strings.Split(path, "/")
+	name := segs[len(segs)-1]
+	if len(segs) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = segs[len(segs)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// used_imports 记录类型表达式中 pkg.Type 用到的导入
+func used_imports(e ast.Expr, fileImports, dst map[string]string) {
+	ast.Inspect(e, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); ok {
+			if path, ok := fileImports[x.Name]; ok {
+				dst[x.Name] = path
+			}
+		}
+		return false
+	})
+}
+
+// qualify_expr 把源包内的导出类型改写为 pkg.Type，返回新的表达式
+func qualify_expr(e ast.Expr, pkg string) ast.Expr {
+	switch t := e.(type) {
+	case *ast.Ident:
+		if ast.IsExported(t.Name) {
+			return &ast.SelectorExpr{X: ast.NewIdent(pkg), Sel: ast.NewIdent(t.Name)}
+		}
+		return t
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualify_expr(t.X, pkg)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: t.Len, Elt: qualify_expr(t.Elt, pkg)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualify_expr(t.Key, pkg), Value: qualify_expr(t.Value, pkg)}
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: qualify_expr(t.Elt, pkg)}
+	case *ast.ChanType:
+		return &ast.ChanType{Dir: t.Dir, Value: qualify_expr(t.Value, pkg)}
+	case *ast.IndexExpr:
+		return &ast.IndexExpr{X: qualify_expr(t.X, pkg), Index: qualify_expr(t.Index, pkg)}
+	case *ast.IndexListExpr:
+		idx := make([]ast.Expr, len(t.Indices))
+		for i, x := range t.Indices {
+			idx[i] = qualify_expr(x, pkg)
+		}
+		return &ast.IndexListExpr{X: qualify_expr(t.X, pkg), Indices: idx}
+	}
+	// SelectorExpr（已限定）、StructType、InterfaceType 等原样保留
+	return e
+}
+
+// unexported_type 方法签名中第一个源包内未导出的类型名
+func unexported_type(m method) string {
+	exprs := make([]ast.Expr, 0, len(m.Params)+1)
+	for _, p := range m.Params {
+		exprs = append(exprs, p.Type)
+	}
+	if m.Result != nil {
+		exprs = append(exprs, m.Result)
+	}
+	name := ""
+	for _, e := range exprs {
+		ast.Inspect(e, func(n ast.Node) bool {
+			switch t := n.(type) {
+			case *ast.SelectorExpr:
+				return false
+			case *ast.Ident:
+				if name == "" && !t.IsExported() && types.Universe.Lookup(t.Name) == nil {
+					name = t.Name
+				}
+			}
+			return name == ""
+		})
+	}
+	return name
+}
+
+func expr_string(e ast.Expr) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, token.NewFileSet(), e)
+	return b.String()
+}
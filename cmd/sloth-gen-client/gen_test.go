@@ -0,0 +1,145 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strings"
+	"testing"
+)
+
+const helloSrc = `package hello
+
+import (
+	"context"
+	stdtime "time"
+
+	"github.com/w6xian/sloth/v3/message"
+)
+
+type Result struct{ Code int }
+
+type req struct{}
+
+type HelloService struct{}
+
+// TestByte 测试
+func (h *HelloService) TestByte(ctx context.Context, b []byte, i int, req *Result, m map[string]Result) (*Result, error) {
+	return nil, nil
+}
+func (h *HelloService) Ping(ctx context.Context) error                         { return nil }
+func (h *HelloService) At(ctx context.Context, _ stdtime.Time, c string) (message.Header, error) { return nil, nil }
+func (h *HelloService) Sum(ctx context.Context, base int, n ...int64) (int64, error) { return 0, nil }
+func (h *HelloService) Hidden(ctx context.Context, r req) (int, error)        { return 0, nil }
+func (h *HelloService) NoCtx(b []byte) error                                   { return nil }
+func (h *HelloService) Three(ctx context.Context) (int, int, error)            { return 0, 0, nil }
+func (h *HelloService) notExported(ctx context.Context) error                  { return nil }
+func (o *Other) Foo(ctx context.Context) error                                 { return nil }
+`
+
+func parse_src(t *testing.T) []*ast.File {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "hello.go", helloSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*ast.File{f}
+}
+
+func TestCollect(t *testing.T) {
+	stderr = io.Discard
+	methods, imports, err := collect(parse_src(t), "HelloService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range methods {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); got != "At,Hidden,Ping,Sum,TestByte" {
+		t.Fatalf("methods = %s", got)
+	}
+	if imports["stdtime"] != "time" || imports["message"] != "github.com/w6xian/sloth/v3/message" || len(imports) != 2 {
+		t.Fatalf("imports = %v", imports)
+	}
+	// _ 与保留名改名
+	at := methods[0]
+	if at.Params[0].Name != "arg0" || at.Params[1].Name != "c_" {
+		t.Fatalf("At params = %s, %s", at.Params[0].Name, at.Params[1].Name)
+	}
+	if !methods[3].Variadic {
+		t.Fatal("Sum should be variadic")
+	}
+}
+
+func TestGenerate_SamePackage(t *testing.T) {
+	stderr = io.Discard
+	methods, imports, _ := collect(parse_src(t), "HelloService")
+	src, err := generate(config{TypeName: "HelloService", Service: "v1", Client: "HelloClient", Rpc: "server", Package: "hello", SrcPkg: "hello"}, methods, imports)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "out.go", src, 0); err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	out := string(src)
+	for _, want := range []string{
+		`stdtime "time"`,
+		`"github.com/w6xian/sloth/v3"`,
+		"func NewHelloClient(rpc *sloth.ServerRpc) *HelloClient",
+		"// TestByte 测试",
+		`func (c *HelloClient) TestByte(ctx context.Context, b []byte, i int, req *Result, m map[string]Result) (*Result, error) {`,
+		`return sloth.Invoke[*Result](ctx, c.rpc, "v1.TestByte", b, i, req, m)`,
+		`func (c *HelloClient) Ping(ctx context.Context) error {`,
+		`_, err := c.rpc.Call(ctx, "v1.Ping")`,
+		`func (c *HelloClient) Sum(ctx context.Context, base int, n ...int64) (int64, error) {`,
+		`return sloth.Invoke[int64](ctx, c.rpc, "v1.Sum", args...)`,
+		`func (c *HelloClient) Hidden(ctx context.Context, r req) (int, error) {`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerate_OtherPackage(t *testing.T) {
+	stderr = io.Discard
+	methods, imports, _ := collect(parse_src(t), "HelloService")
+	cfg := config{TypeName: "HelloService", Service: "v1", Client: "HelloUserClient", Rpc: "client", Package: "api", SrcPkg: "hello", SrcPath: "example.com/hello"}
+	// Hidden 使用未导出类型，不能生成到其他包
+	if _, err := generate(cfg, methods, imports); err == nil || !strings.Contains(err.Error(), "unexported type req") {
+		t.Fatalf("err = %v", err)
+	}
+	methods = append(methods[:1], methods[2:]...)
+	src, err := generate(cfg, methods, imports)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	out := string(src)
+	for _, want := range []string{
+		`"example.com/hello"`,
+		"func NewHelloUserClient(rpc *sloth.ClientRpc) *HelloUserClient",
+		`func (c *HelloUserClient) TestByte(ctx context.Context, userId int64, b []byte, i int, req *hello.Result, m map[string]hello.Result) (*hello.Result, error) {`,
+		`return sloth.InvokeUser[*hello.Result](ctx, c.rpc, userId, "v1.TestByte", b, i, req, m)`,
+		`_, err := c.rpc.Call(ctx, userId, "v1.Ping")`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestImportName(t *testing.T) {
+	cases := map[string]string{
+		"context":                    "context",
+		"github.com/w6xian/sloth/v3": "sloth",
+		"gopkg.in/yaml.v3":           "yaml",
+		"github.com/a/go-kit":        "go_kit",
+	}
+	for in, want := range cases {
+		if got := import_name(in); got != want {
+			t.Errorf("import_name(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
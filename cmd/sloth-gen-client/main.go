@@ -0,0 +1,152 @@
+// sloth-gen-client 读取服务结构体的源码，生成类型化的 Go 客户端
+//
+// 每个可调用方法（ctx 为第一个参数，返回 error 或 (T, error)）生成一个同名方法：
+//
+//	func (c *HelloClient) TestByte(ctx context.Context, b []byte, i int) (*Result, error)
+//
+// 基于 *sloth.ServerRpc（-rpc server，调用服务端）或 *sloth.ClientRpc（-rpc client，按 userId 调用用户端）。
+//
+// 用法：
+//
+//	sloth-gen-client -type HelloService -service v1
+//	sloth-gen-client -dir ./service -type HelloService -service v1 -rpc client -pkg api -o ./api/hello_client.go
+//
+// 也可以写在源文件里：
+//
+//	//go:generate go run github.com/w6xian/sloth/v3/cmd/sloth-gen-client -type HelloService -service v1
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var stderr io.Writer = os.Stderr
+
+func main() {
+	dir := flag.String("dir", ".", "source package directory")
+	typeName := flag.String("type", "", "service struct name, e.g. HelloService (required)")
+	service := flag.String("service", "", "registered service name, e.g. v1 (required)")
+	client := flag.String("client", "", "generated client type name, default <Type without Service>Client")
+	rpc := flag.String("rpc", "server", "server: call server via *sloth.ServerRpc; client: call users via *sloth.ClientRpc")
+	pkg := flag.String("pkg", "", "output package name, default the source package")
+	out := flag.String("o", "", "output file, default <dir>/<type>_client.go")
+	flag.Parse()
+
+	if *typeName == "" || *service == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *rpc != "server" && *rpc != "client" {
+		fatal(fmt.Errorf("unknown -rpc %q, want server or client", *rpc))
+	}
+	cfg := config{
+		TypeName: *typeName,
+		Service:  *service,
+		Client:   *client,
+		Rpc:      *rpc,
+		Package:  *pkg,
+	}
+	if cfg.Client == "" {
+		cfg.Client = strings.TrimSuffix(cfg.TypeName, "Service") + "Client"
+	}
+	if *out == "" {
+		*out = filepath.Join(*dir, strings.ToLower(cfg.TypeName)+"_client.go")
+	}
+
+	files, srcPkg, err := parse_dir(*dir, *out)
+	if err != nil {
+		fatal(err)
+	}
+	cfg.SrcPkg = srcPkg
+	if cfg.Package == "" {
+		cfg.Package = srcPkg
+	}
+	if cfg.Package != srcPkg {
+		if cfg.SrcPath, err = import_path(*dir); err != nil {
+			fatal(err)
+		}
+	}
+	methods, imports, err := collect(files, cfg.TypeName)
+	if err != nil {
+		fatal(err)
+	}
+	src, err := generate(cfg, methods, imports)
+	if err != nil {
+		fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+// parse_dir 解析目录下的非测试 Go 文件，跳过输出文件本身（重复生成时）
+func parse_dir(dir, out string) ([]*ast.File, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	absOut, _ := filepath.Abs(out)
+	fset := token.NewFileSet()
+	var files []*ast.File
+	pkg := ""
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if abs, _ := filepath.Abs(path); abs == absOut {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, "", err
+		}
+		if pkg != "" && f.Name.Name != pkg {
+			continue
+		}
+		pkg = f.Name.Name
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("no go files in %s", dir)
+	}
+	return files, pkg, nil
+}
+
+// import_path 由最近的 go.mod 推算 dir 的导入路径
+func import_path(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		b, err := os.ReadFile(filepath.Join(d, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(b), "\n") {
+				if mod, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+					rel, _ := filepath.Rel(d, abs)
+					return strings.TrimSuffix(strings.Trim(mod, `" `)+"/"+filepath.ToSlash(rel), "/."), nil
+				}
+			}
+			return "", fmt.Errorf("no module line in %s", filepath.Join(d, "go.mod"))
+		}
+		if filepath.Dir(d) == d {
+			return "", errors.New("go.mod not found, cannot qualify source types")
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(stderr, "sloth-gen-client:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,66 @@
+package sloth
+
+import (
+	"reflect"
+
+	"github.com/w6xian/sloth/v3/codec"
+	"github.com/w6xian/sloth/v3/decoder"
+	"github.com/w6xian/sloth/v3/decoder/ag"
+	"github.com/w6xian/sloth/v3/message"
+)
+
+// 编解码协商：header 中携带 CodecHeader=<name> 时，参数与返回值都按 codec 注册表中的
+// 同名编解码器处理（见 codec 包）；未携带时沿用 Encoder/Decoder，旧客户端不受影响。
+// @example
+//
+//	client := sloth.DefaultClient(sloth.UseCodec(codec.NameJSON))                    // 该客户端的全部调用
+//	client.CallWithHeader(ctx, message.Header{sloth.CodecHeader: "json"}, "v1.Test", req) // 单次调用
+const CodecHeader = "codec"
+
+// codec_name 依次取各 header 中的 codec，前面的优先
+func codec_name(headers ...message.Header) string {
+	for _, h := range headers {
+		if name := h.Get(CodecHeader); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// encode_args 按协商的编解码器编码参数，未指定时使用 encoder
+func encode_args(encoder Encoder, args []any, headers ...message.Header) ([][]byte, error) {
+	name := codec_name(headers...)
+	if name == "" {
+		return decoder.EncodeArgs(args, encoder)
+	}
+	c, err := codec.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.EncodeArgs(args, c.Marshal)
+}
+
+// peer_encoder 对端协商了 ag_ext 时把默认编码器 ag.Encoder 换成 ag.EncodeExt（复合类型编码为嵌套帧），
+// 旧版对端与自定义编码器原样返回
+func peer_encoder(encoder Encoder, peer any) Encoder {
+	if encoder == nil || !PeerCapabilities(peer).Has(message.FeatureAgExt) {
+		return encoder
+	}
+	if reflect.ValueOf(encoder).Pointer() == reflect.ValueOf(ag.Encoder).Pointer() {
+		return ag.EncodeExt
+	}
+	return encoder
+}
+
+// decode_reply 按协商的编解码器解码返回值，未指定时按 ag 帧解码
+func decode_reply(data []byte, v any, headers ...message.Header) error {
+	name := codec_name(headers...)
+	if name == "" {
+		return ag.DecodeInto(data, v)
+	}
+	c, err := codec.Lookup(name)
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(data, v)
+}
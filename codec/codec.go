@@ -0,0 +1,101 @@
+// Package codec 参数与返回值的编解码器及其注册表。
+//
+// 调用方在 header 中携带 codec=<name>（sloth.CodecHeader）时，双方按该编解码器处理本次调用：
+// 调用方用 Marshal 逐个编码参数，服务方用 Unmarshal 把参数直接解码为方法声明的类型，
+// 返回值再用 Marshal 编码。未携带时沿用 Encoder/Decoder 的旧路径，旧客户端不受影响。
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/w6xian/sloth/v3/decoder/ag"
+)
+
+const (
+	NameAg   = "ag"
+	NameJSON = "json"
+)
+
+var ErrUnknownCodec = errors.New("codec: unknown codec")
+
+// Codec 编解码器，实现需并发安全
+type Codec interface {
+	// Name 注册名，即 header 中 codec 的取值
+	Name() string
+	// Marshal 编码单个参数或返回值
+	Marshal(v any) ([]byte, error)
+	// Unmarshal 把 data 解码到 v 指向的值，v 为非 nil 指针
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = map[string]Codec{}
+)
+
+func init() {
+	Register(Ag{})
+	Register(JSON{})
+	Register(TLV{})
+}
+
+// Register 注册编解码器，同名的后注册者覆盖先注册者；通常在 init 中调用，
+// 调用方与服务方需注册同一实现
+func Register(c Codec) {
+	if c == nil || c.Name() == "" {
+		panic("codec: Register with nil codec or empty name")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	codecs[c.Name()] = c
+}
+
+// Get 按名字取编解码器
+func Get(name string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// Lookup 同 Get，未注册时返回 ErrUnknownCodec
+func Lookup(name string) (Codec, error) {
+	if c, ok := Get(name); ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownCodec, name)
+}
+
+// Names 已注册的编解码器名，按字母排序
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Ag 带类型标签的 ag 帧：编码见 ag.EncodeTyped，解码见 ag.DecodeInto
+type Ag struct{}
+
+func (Ag) Name() string { return NameAg }
+
+func (Ag) Marshal(v any) ([]byte, error) { return ag.EncodeTyped(v) }
+
+func (Ag) Unmarshal(data []byte, v any) error { return ag.DecodeInto(data, v) }
+
+// JSON encoding/json
+type JSON struct{}
+
+func (JSON) Name() string { return NameJSON }
+
+func (JSON) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (JSON) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+type item struct {
+	Id   int64    `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+type upper struct{ JSON }
+
+func (upper) Name() string { return "upper" }
+
+func TestRegistry(t *testing.T) {
+	Register(upper{})
+	if !slices.Equal(Names(), []string{NameAg, NameJSON, NameTLV, "upper"}) {
+		t.Fatalf("names = %v", Names())
+	}
+	if c, ok := Get("upper"); !ok || c.Name() != "upper" {
+		t.Fatalf("get = %v, %v", c, ok)
+	}
+	if _, err := Lookup("xml"); !errors.Is(err, ErrUnknownCodec) || err.Error() != `codec: unknown codec "xml"` {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{NameAg, NameJSON, NameTLV} {
+		c, _ := Get(name)
+		for _, in := range []any{int64(-3), uint16(7), 1.5, true, "sloth", []byte{1, 2}, &item{Id: 1, Name: "a", Tags: []string{"x"}}, []int{1, 2}, map[string]int{"a": 1}} {
+			data, err := c.Marshal(in)
+			if err != nil {
+				t.Fatalf("%s: marshal %T: %v", name, in, err)
+			}
+			out := reflect.New(reflect.TypeOf(in))
+			if err := c.Unmarshal(data, out.Interface()); err != nil {
+				t.Fatalf("%s: unmarshal %T: %v", name, in, err)
+			}
+			if !reflect.DeepEqual(out.Elem().Interface(), in) {
+				t.Errorf("%s: got %#v, want %#v", name, out.Elem().Interface(), in)
+			}
+		}
+	}
+}
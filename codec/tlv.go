@@ -0,0 +1,56 @@
+package codec
+
+import (
+	"reflect"
+
+	"github.com/w6xian/sloth/v3/decoder/ag"
+	"github.com/w6xian/tlv"
+)
+
+const NameTLV = "tlv"
+
+// TLV 结构体（含指向结构体的指针）按 tlv 库的结构体编码（字段取 tlv tag）直接编码，
+// 不再是 TLV 包裹的 JSON；其余类型与 nil 指针使用带类型标签的 ag 帧
+//
+//	type Req struct {
+//		Id   int64    `tlv:"id"`
+//		Tags []string `tlv:"tags"`
+//	}
+type TLV struct{}
+
+func (TLV) Name() string { return NameTLV }
+
+func (TLV) Marshal(v any) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return ag.EncodeTyped(v)
+	}
+	return tlv.Marshal(rv.Interface())
+}
+
+func (TLV) Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ag.ErrAgInvalidTarget
+	}
+	t := rv.Type().Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || ag.IsArgument(data) {
+		// 非结构体，或结构体指针为 nil（Nil 帧）
+		return ag.DecodeInto(data, v)
+	}
+	// 逐层分配指针，直到结构体本身
+	dst := rv.Elem()
+	for dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+	return tlv.Unmarshal(data, dst.Addr().Interface())
+}
@@ -0,0 +1,105 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+)
+
+type tlvB struct {
+	C string `tlv:"c"`
+}
+
+// tlvA 与 examples/sl 中的 A 相同，覆盖全部基础类型
+type tlvA struct {
+	Bool       bool           `tlv:"bool"`
+	Int1       int            `tlv:"int"`
+	Int8       int8           `tlv:"int8"`
+	Int16      int16          `tlv:"int16"`
+	Int32      int32          `tlv:"int32"`
+	Int64      int64          `tlv:"int64"`
+	Uint       uint           `tlv:"uint"`
+	Uint8      uint8          `tlv:"uint8"`
+	Uint16     uint16         `tlv:"uint16"`
+	Uint32     uint32         `tlv:"uint32"`
+	Uint64     uint64         `tlv:"uint64"`
+	Uintptr    uintptr        `tlv:"uintptr"`
+	Float32    float32        `tlv:"float32"`
+	Float64    float64        `tlv:"float64"`
+	Complex64  complex64      `tlv:"complex64"`
+	Complex128 complex128     `tlv:"complex128"`
+	String     string         `tlv:"string"`
+	Byte       byte           `tlv:"byte"`
+	Rune       rune           `tlv:"rune"`
+	B          tlvB           `tlv:"b"`
+	Slice      []int          `tlv:"slice"`
+	Slice16    []int16        `tlv:"slice16"`
+	Slice32    []int32        `tlv:"slice32"`
+	Slice64    []int64        `tlv:"slice64"`
+	Map        map[string]int `tlv:"map"`
+	Arraya     []string       `tlv:"arraya"`
+	Arrayb     []byte         `tlv:"arrayb"`
+	Float32s   []float32      `tlv:"float32s"`
+	Float64s   []float64      `tlv:"float64s"`
+}
+
+func TestTLV_RoundTrip(t *testing.T) {
+	a := tlvA{
+		Bool:       true,
+		Int1:       -42,
+		Int8:       -8,
+		Int16:      -16,
+		Int32:      -32,
+		Int64:      -64,
+		Uint:       42,
+		Uint8:      8,
+		Uint16:     16,
+		Uint32:     32,
+		Uint64:     64,
+		Uintptr:    100,
+		Float32:    3.14,
+		Float64:    3.141592653589793,
+		Complex64:  complex(1, 2),
+		Complex128: complex(3, 4),
+		String:     "Hello, Go!",
+		Byte:       'A',
+		Rune:       '中',
+		B:          tlvB{C: "中文ab1234`"},
+		Slice:      []int{-1, 2, 3, 4, 5},
+		Slice16:    []int16{1, -2, 3, 4, 5},
+		Slice32:    []int32{1, 2, -3, 4, 5},
+		Slice64:    []int64{1, 2, 3, -4, 5},
+		Map:        map[string]int{"a": 1, "b": -2},
+		Arraya:     []string{"a", "b", "c"},
+		Arrayb:     []byte{1, 2, 3},
+		Float32s:   []float32{1.1, -2.2},
+		Float64s:   []float64{3.3, -4.4},
+	}
+	c := TLV{}
+	for _, in := range []any{a, &a} {
+		data, err := c.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v tlvA
+		if err := c.Unmarshal(data, &v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, a) {
+			t.Fatalf("got %+v\nwant %+v", v, a)
+		}
+		p := &tlvA{}
+		if err := c.Unmarshal(data, &p); err != nil || !reflect.DeepEqual(*p, a) {
+			t.Fatalf("pointer target: %+v, %v", p, err)
+		}
+	}
+
+	// nil 指针编码为 Nil 帧，解码后仍为 nil
+	data, err := c.Marshal((*tlvA)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &tlvA{}
+	if err := c.Unmarshal(data, &p); err != nil || p != nil {
+		t.Fatalf("nil pointer: %+v, %v", p, err)
+	}
+}
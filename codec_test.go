@@ -0,0 +1,55 @@
+package sloth
+
+import (
+	"testing"
+
+	"github.com/w6xian/sloth/v3/decoder/ag"
+	"github.com/w6xian/sloth/v3/message"
+)
+
+// capsPeer 只实现 Capabilities 的连接
+type capsPeer struct{ caps *message.Capabilities }
+
+func (p capsPeer) Capabilities() *message.Capabilities { return p.caps }
+
+func TestPeerEncoder(t *testing.T) {
+	type item struct {
+		Id   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	in := item{Id: 1, Name: "a"}
+	ext := capsPeer{&message.Capabilities{Features: []string{message.FeatureAgExt}}}
+	legacy := capsPeer{&message.Capabilities{}}
+
+	tests := []struct {
+		name string
+		peer any
+		want uint8
+	}{
+		{"ag_ext", ext, ag.ArgumentTypeStruct},
+		{"legacy", legacy, ag.ArgumentTypeString},
+		{"unknown", nil, ag.ArgumentTypeString},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := encode_args(peer_encoder(ag.Encoder, tt.peer), []any{in})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := args[0][2]; got != tt.want {
+				t.Fatalf("type = %d, want %d", got, tt.want)
+			}
+			var out item
+			if err := ag.DecodeInto(args[0], &out); err != nil || out != in {
+				t.Fatalf("decode: %+v, %v", out, err)
+			}
+		})
+	}
+
+	// 自定义编码器不受协商影响
+	called := false
+	custom := func(v any) ([]byte, error) { called = true; return ag.Encode(v) }
+	if _, err := encode_args(peer_encoder(custom, ext), []any{in}); err != nil || !called {
+		t.Fatalf("custom encoder replaced: called=%v err=%v", called, err)
+	}
+}
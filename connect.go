@@ -15,7 +15,9 @@ import (
 	"sync"
 
 	"github.com/w6xian/sloth/v3/bucket"
+	"github.com/w6xian/sloth/v3/codec"
 	"github.com/w6xian/sloth/v3/decoder"
+	"github.com/w6xian/sloth/v3/decoder/ag"
 	"github.com/w6xian/sloth/v3/internal/logger"
 	"github.com/w6xian/sloth/v3/internal/ref"
 	"github.com/w6xian/sloth/v3/internal/utils/id"
@@ -57,7 +59,13 @@ type Connect struct {
 	ServerId   string
 	client     *ClientRpc
 	server     *ServerRpc
-	serviceMap map[string]*ref.ServiceFuncs
+	serviceMap map[string]*ref.ServiceFuncs // 键为 name 或 name@version
+	versions   map[string][]string          // name -> 已注册版本，按注册顺序
+	defaults   map[string]string            // name -> 未指定版本时使用的版本
+	svcMu      sync.RWMutex
+	// 已打印过弃用日志的服务
+	deprecatedLogged sync.Map
+
 	sleepTimes int
 	times      int
 	cpuNum     int
@@ -68,8 +76,6 @@ type Connect struct {
 	listeners []ProtocolListener
 	// httpHandlers []ServeHandler // HTTP 处理函数列表
 	proxyHandler func(ctx context.Context, service string) (int64, error)
-	// meta data
-	metaData string
 }
 
 func (c *Connect) CallNetFunc(ctx context.Context, r *http.Request, service string, msgId uint64, msg []byte) ([]byte, error) {
@@ -92,12 +98,13 @@ func (c *Connect) UseProxyHandler(proxyHandler func(ctx context.Context, service
 	return nil
 }
 
+// IsRegisteredService service 为调用名 service.method，服务名可以分层或带版本（name@version）
 func (c *Connect) IsRegisteredService(service string) bool {
-	ns := strings.Split(service, ".")
-	if len(ns) != 2 {
+	name, _, ok := split_node(service)
+	if !ok {
 		return false
 	}
-	_, ok := c.serviceMap[ns[0]]
+	_, ok = c.lookup(name, "")
 	return ok
 }
 
@@ -122,12 +129,15 @@ func newConnect(opts ...ConnOption) *Connect {
 	// svr.id = atomic.AddInt64(&instCount, 1)
 	svr.ServerId = id.ShortID()
 	svr.serviceMap = make(map[string]*ref.ServiceFuncs)
+	svr.versions = make(map[string][]string)
+	svr.defaults = make(map[string]string)
 	svr.sleepTimes = 15
 	svr.times = 8
 	svr.cpuNum = runtime.NumCPU()
 	svr.client = LinkClientFunc()
 	svr.server = LinkServerFunc()
 	svr.Option = option.NewOptions()
+	svr.Option.Version = Version
 	svr.listeners = make([]ProtocolListener, 0)
 	svr.proxyHandler = func(ctx context.Context, service string) (int64, error) {
 		return 0, nil
@@ -136,21 +146,19 @@ func newConnect(opts ...ConnOption) *Connect {
 	for _, opt := range opts {
 		opt(svr)
 	}
+	if svr.Option.NodeId != 0 {
+		if err := decoder.SetNodeId(svr.Option.NodeId); err != nil {
+			log.Printf("set node id err : %v", err)
+		}
+	}
+	// 内置服务：sys.Describe 等。服务名固定且 Connect 刚创建，注册不会失败，出错只可能是程序错误
+	if err := svr.Register(SysService, &sysService{c: svr}, "sloth built-in service"); err != nil {
+		panic(err)
+	}
 
 	return svr
 }
 
-// Register 注册一个服务，name是服务名，rcvr是服务实现，metadata是服务描述
-func (c *Connect) Register(name string, rcvr any, metadata string) error {
-	if _, ok := c.serviceMap[name]; ok {
-		return fmt.Errorf("service %s already registered", name)
-	}
-	funcs := ref.Register(rcvr)
-	c.metaData = metadata
-	c.serviceMap[name] = funcs
-	return nil
-}
-
 // Listen 注册协议监听器，不立即启动服务
 // 可以多次调用注册多个协议，最后用 Serve() 启动所有服务
 func (c *Connect) Listen(ctx context.Context, network, address string, opts ...option.ConnectOption) error {
@@ -329,16 +337,19 @@ func (c *Connect) CallFunc(ctx context.Context, r *http.Request, svr types.IBuck
 			c.Log(logger.Error, "connect.CallFunc %s recover stack : %s", msgReq.Method, string(debug.Stack()))
 		}
 	}()
-	node, err := GetNode(msgReq.Method)
-	if err != nil {
+	service, method, ok := split_node(msgReq.Method)
+	if !ok {
 		c.Log(logger.Info, "(%s) method format error", c.ServerId)
 		return nil, errors.New("method format error")
 	}
-	serviceFns, ok := c.serviceMap[node.Service]
+	serviceFns, ok := c.lookup(service, msgReq.Header[ServiceVersionHeader])
 	if !ok {
 		c.Log(logger.Info, "(%s) service not found", c.ServerId)
 		return nil, errors.New("service not found")
 	}
+	if serviceFns.Deprecated != "" {
+		c.warn_deprecated(service, serviceFns)
+	}
 
 	if svr != nil {
 		ctx = context.WithValue(ctx, BucketKey, svr)
@@ -354,14 +365,41 @@ func (c *Connect) CallFunc(ctx context.Context, r *http.Request, svr types.IBuck
 	// 克隆调用方 header 后追加 meta，避免写污染调用方持有的 map（RpcCaller 可能被复用）
 	header := make(message.Header, len(msgReq.Header)+1)
 	maps.Copy(header, msgReq.Header)
-	header.Set("meta", c.metaData)
+	header.Set("meta", serviceFns.D)
+	if serviceFns.Version != "" {
+		// 实际处理本次调用的版本
+		header.Set(ServiceVersionHeader, serviceFns.Version)
+	}
 	if r != nil {
 		header.Set("remote_addr", r.RemoteAddr)
 	}
 	ctx = context.WithValue(ctx, HeaderKey, header)
 
+	if name := header.Get(CodecHeader); name != "" {
+		// 调用方协商了编解码器：参数直接解码为方法声明的类型，返回值用同一编解码器编码
+		cc, err := codec.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		rst, err := ref.InvokeFuncWithCodec(ctx, serviceFns, method, cc.Unmarshal, msgReq.Args...)
+		if err != nil {
+			return nil, err
+		}
+		return cc.Marshal(rst)
+	}
 	funArgs := decoder.DecodeArgs(msgReq.Args, c.server.Decoder)
-	return ref.CallFuncWithContext(ctx, serviceFns, node.Method, funArgs...)
+	if header.Get(ReplyTypeHeader) == ReplyTypeAg {
+		// 调用方要求带类型标签的返回值（sloth.Invoke）
+		rst, err := ref.InvokeFuncWithContext(ctx, serviceFns, method, funArgs...)
+		if err != nil {
+			return nil, err
+		}
+		if PeerCapabilities(msgReq.Channel).Has(message.FeatureAgExt) {
+			return ag.EncodeTypedExt(rst)
+		}
+		return ag.EncodeTyped(rst)
+	}
+	return ref.CallFuncWithContext(ctx, serviceFns, method, funArgs...)
 }
 
 func (w *Connect) Log(lvl logger.LogLevel, line string, args ...any) {
@@ -3,6 +3,8 @@ package sloth
 import (
 	"context"
 	"time"
+
+	"github.com/w6xian/sloth/v3/bucket"
 )
 
 // connect options
@@ -119,6 +121,16 @@ func WithRoutineSize(routineSize int) ConnOption {
 	}
 }
 
+// WithMaxDevices 每个用户同时在线的设备数上限（<=0 不限制，默认不限制）及达到上限时的处理：
+// bucket.KickOldest 踢掉最早登录的设备，bucket.RejectNew 拒绝新设备（PutDevice 返回 bucket.ErrTooManyDevices）。
+// 只对 Bucket.PutDevice 登记的不同设备生效；Put 不区分设备，同一用户的新连接总是抢占旧连接
+func WithMaxDevices(max int, policy bucket.DevicePolicy) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.MaxDevices = max
+		ch.Option.DevicePolicy = policy
+	}
+}
+
 func WithMaxConnsGlobal(max int64) ConnOption {
 	return func(ch *Connect) {
 		ch.Option.MaxConnsGlobal = max
@@ -212,8 +224,100 @@ func UseDecoder(decoder Decoder) IRpcOption {
 	}
 }
 
+// UseCodec 该端发起的调用都按 codec 注册表中名为 name 的编解码器编码参数与返回值，
+// 对端需注册同名实现（见 CodecHeader）；未实现 ICodecRpc 的 IRpc 忽略该选项
+func UseCodec(name string) IRpcOption {
+	return func(ch IRpc) {
+		if c, ok := ch.(ICodecRpc); ok {
+			c.SetCodec(name)
+		}
+	}
+}
+
 func Listen(network, address string) ConnOption {
 	return func(ch *Connect) {
 
 	}
 }
+
+// WithTextFrames 只用 JSON 文本分片发送，关闭二进制分片
+func WithTextFrames(text bool) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.TextFrames = text
+	}
+}
+
+// WithCompressThreshold 超过 n 字节的消息压缩后发送（默认 4 KiB），n<=0 关闭；
+// 只对在握手中声明支持的 sloth 对端生效，浏览器端请用 WithWsCompression
+func WithCompressThreshold(n int) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.CompressThreshold = n
+	}
+}
+
+// WithWsCompression 启用 WebSocket permessage-deflate 扩展
+func WithWsCompression(enable bool) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.WsCompression = enable
+	}
+}
+
+// WithEncryption 启用 fn 帧端到端加密（X25519 + AES-GCM），双方都启用时生效，
+// 用于 TLS 在不可信代理处终结的部署。
+// GCM nonce 取自每个方向独立递增的会话序号（随密文一起发送），不是 fn 帧 ID：帧 ID 由调用方生成、会重复，
+// 回复还与请求同 ID，不能用作 nonce。
+// 只加密 fn 帧（调用与回复）；Push、ServerRpc.Send、Room/广播推送的 message.Msg 不是 fn 帧，以明文发送，
+// 敏感数据请走调用；公钥交换不带认证，能改写握手头的代理可以做中间人，需配合 WithEncryptionKey
+func WithEncryption(enable bool) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.Encrypt = enable
+	}
+}
+
+// WithEncryptionKey 设置双方预共享的密钥（两端必须相同），混入密钥派生：
+// 不知道 psk 的中间人替换公钥后，客户端核对密钥确认值失败并断开，隐含 WithEncryption(true)
+func WithEncryptionKey(psk []byte) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.EncryptKey = psk
+		if len(psk) > 0 {
+			ch.Option.Encrypt = true
+		}
+	}
+}
+
+// WithEncryptionRequired 对端不支持加密时拒绝连接（服务端返回 403，客户端断开）
+func WithEncryptionRequired(required bool) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.EncryptRequired = required
+		if required {
+			ch.Option.Encrypt = true
+		}
+	}
+}
+
+// WithSubprotocolRequired 服务端拒绝未声明 sloth 子协议的客户端（旧版客户端），返回 400；
+// 声明了但都不支持的客户端总是被拒绝
+func WithSubprotocolRequired(required bool) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.SubprotocolRequired = required
+	}
+}
+
+// WithNodeId 设置本进程调用 ID 的节点号（0..1023，0 表示不修改、保持默认节点 1），
+// 集群中每个进程配置不同的值即调用 ID 全局唯一。
+// 该设置是进程级的（即 decoder.SetNodeId）：同一进程内两个 Connect 配置不同的节点号会互相覆盖，以最后创建的为准；
+// 超出范围时打印错误日志并保持原节点
+func WithNodeId(node int64) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.NodeId = node
+	}
+}
+
+// WithStrictArgs 严格参数解码：结构体参数中出现方法未声明的 JSON 字段时返回 ErrBadArguments。
+// 该设置在 Register 时复制到服务上：ServerConn/ClientConn 的选项先于 Register 应用，一般无需关心；
+// 之后再修改 Connect.Option.StrictArgs 只影响修改后注册的服务，已注册的服务需 Register(..., WithReplace()) 重新注册
+func WithStrictArgs(strict bool) ConnOption {
+	return func(ch *Connect) {
+		ch.Option.StrictArgs = strict
+	}
+}
@@ -0,0 +1,20 @@
+package sloth
+
+import "testing"
+
+// plainRpc 只实现 IRpc，不支持 SetCodec
+type plainRpc struct{}
+
+func (plainRpc) SetEncoder(encoder Encoder) {}
+func (plainRpc) SetDecoder(decoder Decoder) {}
+
+func TestUseCodec(t *testing.T) {
+	if got := DefaultServer(UseCodec("json")).Header.Get(CodecHeader); got != "json" {
+		t.Errorf("ClientRpc codec header = %q", got)
+	}
+	if got := DefaultClient(UseCodec("json")).Header.Get(CodecHeader); got != "json" {
+		t.Errorf("ServerRpc codec header = %q", got)
+	}
+	// 未实现 ICodecRpc 的 IRpc 忽略该选项
+	UseCodec("json")(plainRpc{})
+}
@@ -0,0 +1,148 @@
+package sloth
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/w6xian/sloth/v3/schema"
+)
+
+// Contract 服务契约：方法目录 + 参数/返回值的 JSON Schema，结构体定义统一放在 Defs 中
+type Contract struct {
+	ServerId string                    `json:"server_id"`
+	Version  string                    `json:"version"`
+	Services []ServiceContract         `json:"services"`
+	Defs     map[string]*schema.Schema `json:"$defs"`
+}
+
+// ServiceContract 单个服务，Name 为可直接寻址的服务名（带版本时为 name@version）
+type ServiceContract struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	Meta       string           `json:"meta"`
+	Version    string           `json:"version,omitempty"`
+	Default    bool             `json:"default"`
+	Deprecated string           `json:"deprecated,omitempty"`
+	Methods    []MethodContract `json:"methods"`
+}
+
+// MethodContract 单个方法，Method 为调用名（service.Method），Params 不含 ctx
+type MethodContract struct {
+	Name       string          `json:"name"`
+	Method     string          `json:"method"`
+	Params     []ParamContract `json:"params"`
+	Result     *schema.Schema  `json:"result,omitempty"` // Notify 方法没有返回值
+	ResultType string          `json:"result_type,omitempty"`
+	ResultKind string          `json:"result_kind,omitempty"` // 多个返回值时为 tuple，结果为 JSON 数组
+	Notify     bool            `json:"notify"`
+	Variadic   bool            `json:"variadic,omitempty"` // 最后一个参数为可变参数，调用方逐个传入元素
+}
+
+// ParamContract 单个参数，Kind 为解引用后的 Go Kind（[]byte 为 bytes），用于按类型选择 ag 编码；
+// 可变参数的 Type 为 ...T，Kind 与 Schema 描述单个元素
+type ParamContract struct {
+	Name   string         `json:"name"`
+	Type   string         `json:"type"`
+	Kind   string         `json:"kind"`
+	Schema *schema.Schema `json:"schema"`
+}
+
+// Contract 导出当前连接注册的全部服务契约，服务与方法按名称排序
+func (c *Connect) Contract() *Contract {
+	g := schema.NewGenerator()
+	ct := &Contract{
+		ServerId: c.ServerId,
+		Version:  Version,
+		Defs:     g.Defs,
+	}
+	// 按名称顺序生成，保证 $defs 命名稳定
+	for _, r := range c.services() {
+		ct.Services = append(ct.Services, contractService(g, r))
+	}
+	return ct
+}
+
+// ContractService 单个服务的契约，Defs 只包含该服务用到的结构体；
+// name 为 name@version 或服务名（默认版本）
+func (c *Connect) ContractService(name string) (*Contract, bool) {
+	r, ok := c.service(name)
+	if !ok {
+		return nil, false
+	}
+	g := schema.NewGenerator()
+	return &Contract{
+		ServerId: c.ServerId,
+		Version:  Version,
+		Services: []ServiceContract{contractService(g, r)},
+		Defs:     g.Defs,
+	}, true
+}
+
+func contractService(g *schema.Generator, r registered) ServiceContract {
+	name, fns := r.Key, r.Funcs
+	sc := ServiceContract{
+		Name:       name,
+		Type:       fns.N,
+		Meta:       fns.D,
+		Version:    fns.Version,
+		Default:    r.Default,
+		Deprecated: fns.Deprecated,
+		Methods:    make([]MethodContract, 0, len(fns.M)),
+	}
+	for _, mName := range slices.Sorted(maps.Keys(fns.M)) {
+		m := fns.M[mName]
+		// In(0) 为接收者，In(1) 为 ctx
+		mc := MethodContract{
+			Name:     mName,
+			Method:   name + "." + mName,
+			Params:   make([]ParamContract, 0, m.Type.NumIn()-2),
+			Notify:   m.Type.NumOut() == 1,
+			Variadic: m.Type.IsVariadic(),
+		}
+		for i := 2; i < m.Type.NumIn(); i++ {
+			in := m.Type.In(i)
+			if mc.Variadic && i == m.Type.NumIn()-1 {
+				in = in.Elem()
+			}
+			mc.Params = append(mc.Params, ParamContract{
+				Name:   fns.A[mName].Args[i-2].Name,
+				Type:   fns.A[mName].Args[i-2].Type,
+				Kind:   kindOf(in),
+				Schema: g.Schema(in),
+			})
+		}
+		switch n := m.Type.NumOut() - 1; {
+		case n == 1:
+			out := m.Type.Out(0)
+			mc.Result = g.Schema(out)
+			mc.ResultType = out.String()
+			mc.ResultKind = kindOf(out)
+		case n > 1:
+			// 多个返回值合并为 JSON 数组
+			types := make([]string, n)
+			mc.Result = &schema.Schema{Type: "array", MinItems: &n, MaxItems: &n}
+			for i := range n {
+				out := m.Type.Out(i)
+				types[i] = out.String()
+				mc.Result.PrefixItems = append(mc.Result.PrefixItems, g.Schema(out))
+			}
+			mc.ResultType = "(" + strings.Join(types, ", ") + ")"
+			mc.ResultKind = "tuple"
+		}
+		sc.Methods = append(sc.Methods, mc)
+	}
+	return sc
+}
+
+// kindOf 解引用指针后的 Kind 名称，[]byte 记为 bytes
+func kindOf(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return "bytes"
+	}
+	return t.Kind().String()
+}
@@ -6,27 +6,32 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"reflect"
-
-	"github.com/w6xian/sloth/v3/internal/utils"
+	"time"
 )
 
 /**
  * @brief AG 协议 (Argument Grid) 参数帧格式
  *
  * MAGIC  :p   2 byte   0x3A 0x70  (ASCII ":p")
- * TYPE   t    1 byte   ArgumentType* 枚举
- * LEN    l    2 byte   big endian，Value 字节数 (0~65535)
+ * TYPE   t    1 byte   ArgumentType* 枚举，最高位为扩展长度标志 ArgumentTypeExtended
+ * LEN    l    2 byte   big endian，Value 字节数 (0~65535)；扩展帧为 4 byte (0~4294967295)
  * VALUE  d    l byte   payload，长度 = l
  *
- * 总帧最小 5 字节，最大 5 + 65535 = 65540 字节。
+ * 总帧最小 5 字节。Value 不超过 65535 字节时写普通帧（与旧版线上格式一致），
+ * 超过时写扩展帧：TYPE 置 0x80 标志、LEN 为 4 字节，帧头 7 字节。
  */
 
 const (
 	ArgumentMagic1      byte = 0x3A // ':'
 	ArgumentMagic2      byte = 0x70 // 'p'
 	ArgumentHeaderSize       = 2 + 1 + 2
-	ArgumentMaxDataSize      = 1 << 16
+	ArgumentMaxDataSize      = 1 << 16 // 普通帧 Value 长度上限（不含）
+	// 扩展帧
+	ArgumentExtHeaderSize        = 2 + 1 + 4
+	ArgumentMaxExtDataSize       = math.MaxUint32
+	ArgumentTypeExtended   uint8 = 0x80
 )
 
 // 基本类型穷举（与 Go 原语一一对应，0x01~0x1F 为基础标量；0x20~0x3F 为复合/扩展）
@@ -60,26 +65,46 @@ const (
 	ArgumentTypeMap
 	ArgumentTypeStruct
 	ArgumentTypeCustom
+
+	// 扩展标量，见 ext.go
+	ArgumentTypeTime
+	ArgumentTypeDuration
+	ArgumentTypeBigInt
+	ArgumentTypeBigFloat
 )
 
 var (
 	ErrAgTooShort       = errors.New("ag: payload too short for header")
 	ErrAgBadMagic       = errors.New("ag: bad magic header, expect :p")
 	ErrAgLengthMismatch = errors.New("ag: payload length mismatch")
-	ErrAgDataTooLarge   = fmt.Errorf("ag: data length exceeds %d", ArgumentMaxDataSize)
+	ErrAgDataTooLarge   = fmt.Errorf("ag: data length exceeds %d", uint64(ArgumentMaxExtDataSize))
 	ErrAgUnknownType    = errors.New("ag: unknown type tag")
 	ErrAgInvalidHeader  = errors.New("ag: invalid header")
 )
 
-// IsArgument O(1) 校验帧完整性（magic + length 匹配）
+// IsArgument O(1) 校验帧完整性（magic + length 匹配），普通帧与扩展帧均可
 func IsArgument(b []byte) bool {
 	if len(b) < ArgumentHeaderSize || b[0] != ArgumentMagic1 || b[1] != ArgumentMagic2 {
 		return false
 	}
-	t := b[2]
-	_ = t
-	length := binary.BigEndian.Uint16(b[3:5])
-	return len(b) == ArgumentHeaderSize+int(length)
+	hdr, length, ok := frame_size(b)
+	return ok && len(b)-hdr == length
+}
+
+// frame_size 帧头长度与 Value 长度；b 以 magic 开头且不短于普通帧头，扩展帧头不完整时 ok 为 false
+func frame_size(b []byte) (hdr int, length int, ok bool) {
+	if b[2]&ArgumentTypeExtended == 0 {
+		return ArgumentHeaderSize, int(binary.BigEndian.Uint16(b[3:5])), true
+	}
+	if len(b) < ArgumentExtHeaderSize {
+		return 0, 0, false
+	}
+	return ArgumentExtHeaderSize, int(binary.BigEndian.Uint32(b[3:7])), true
+}
+
+// frame_type 类型标签，去掉扩展长度标志
+func frame_type(b []byte) uint8 {
+	return b[2] &^ ArgumentTypeExtended
 }
 
 // Data 取 Value 段；非 AG 帧或不合法返回源切片（兼容旧调用方直接透传）
@@ -106,21 +131,20 @@ func Json(v any) []byte {
 }
 
 func get_data(b []byte) []byte {
-	length := binary.BigEndian.Uint16(b[3:5])
+	hdr, length, _ := frame_size(b)
 	if length == 0 {
 		return nil
 	}
 	out := make([]byte, length)
-	copy(out, b[ArgumentHeaderSize:ArgumentHeaderSize+int(length)])
-	t := b[2]
-	switch t {
+	copy(out, b[hdr:hdr+length])
+	switch frame_type(b) {
 	case ArgumentTypeUint8, ArgumentTypeInt8:
 		return zeroExtendN(out, 1)
 	case ArgumentTypeUint16, ArgumentTypeInt16:
 		return zeroExtend2byte(out)
 	case ArgumentTypeUint32, ArgumentTypeInt32:
 		return zeroExtend4byte(out)
-	case ArgumentTypeUint64, ArgumentTypeInt64, ArgumentTypeInt, ArgumentTypeUint, ArgumentTypeUintptr:
+	case ArgumentTypeUint64, ArgumentTypeInt64, ArgumentTypeInt, ArgumentTypeUint, ArgumentTypeUintptr, ArgumentTypeDuration:
 		return zeroExtend8byte(out)
 	}
 	return out
@@ -134,11 +158,11 @@ func Validate(b []byte) error {
 	if b[0] != ArgumentMagic1 || b[1] != ArgumentMagic2 {
 		return ErrAgBadMagic
 	}
-	length := binary.BigEndian.Uint16(b[3:5])
-	if int(length) > ArgumentMaxDataSize {
-		return ErrAgDataTooLarge
+	hdr, length, ok := frame_size(b)
+	if !ok {
+		return ErrAgTooShort
 	}
-	if len(b) != ArgumentHeaderSize+int(length) {
+	if len(b)-hdr != length {
 		return ErrAgLengthMismatch
 	}
 	return nil
@@ -148,13 +172,23 @@ func get_frame(b []byte) (byte, []byte, error) {
 	if err := Validate(b); err != nil {
 		return 0, nil, err
 	}
-	t := b[2]
-	v := get_data(b)
-	return t, v, nil
+	return frame_type(b), get_data(b), nil
 }
 
-// Bytes 把任意值按类型编码为一帧 AG；标量走原语编码，复合走 json fallback 映射成 String 帧。
+// Encode 把任意值按类型编码为一帧 AG：标量走原语编码，Slice/Map/Struct 以 JSON 文本编码为 String 帧，
+// 其余（指针、time.Time 等具名类型）为 JSON 的 Custom 帧。这是旧版对端都能解析的格式，
+// 对端协商了 ag_ext（message.FeatureAgExt）时改用 EncodeExt。
 func Encode(arg any) ([]byte, error) {
+	return encode(arg, false)
+}
+
+// EncodeExt 同 Encode，但复合类型、指针与具名类型反射编码为嵌套帧（见 composite.go），
+// time.Time、big.Int 等写扩展标签帧（见 ext.go）；只发给协商了 ag_ext 的对端。
+func EncodeExt(arg any) ([]byte, error) {
+	return encode(arg, true)
+}
+
+func encode(arg any, ext bool) ([]byte, error) {
 	if arg == nil {
 		return encode_ag(ArgumentTypeNil, nil)
 	}
@@ -216,15 +250,25 @@ func Encode(arg any) ([]byte, error) {
 		copy(out, b)
 		return encode_ag(t, out)
 
-	case ArgumentTypeSlice, ArgumentTypeMap, ArgumentTypeStruct:
-		s, err := jsonMarshalFallback(arg)
-		if err != nil {
-			return nil, err
-		}
-		return encode_ag(ArgumentTypeString, []byte(s))
 	}
-	// 兜底：json 字符串化
-	return encode_ag(ArgumentTypeCustom, utils.Serialize(arg))
+	if !ext {
+		return encode_legacy(arg)
+	}
+	// 复合类型、指针与具名类型：反射编码为嵌套帧（见 composite.go）
+	return encode_value(reflect.ValueOf(arg))
+}
+
+// encode_legacy 旧版格式：Slice/Map/Struct 为 JSON 的 String 帧，其余为 JSON 的 Custom 帧
+func encode_legacy(arg any) ([]byte, error) {
+	b, err := json.Marshal(arg)
+	if err != nil {
+		return nil, err
+	}
+	switch reflect.ValueOf(arg).Kind() {
+	case reflect.Slice, reflect.Map, reflect.Struct:
+		return encode_ag(ArgumentTypeString, b)
+	}
+	return encode_ag(ArgumentTypeCustom, b)
 }
 
 func Decode(b []byte) (any, error) {
@@ -234,17 +278,38 @@ func Decode(b []byte) (any, error) {
 	return get_value(b)
 }
 
+// Decoder 取 Value 段；Slice/Map/Struct 帧转为 JSON，与旧版（复合类型以 JSON 传输）的取值一致，
+// 服务端按 JSON 解码结构体参数、[]byte 参数拿到 JSON 文本的行为不变。
+// Nil 帧返回 nil，其余空 Value 返回非 nil 的空切片，参数解码据此区分 nil 指针与零值；
+// Time/BigInt/BigFloat 帧原样返回整帧，由参数解码按目标类型 DecodeInto（时区等信息不丢失）
 func Decoder(b []byte) ([]byte, error) {
 	if !IsArgument(b) {
 		return b, nil
 	}
-	return get_data(b), nil
+	switch t := frame_type(b); t {
+	case ArgumentTypeNil:
+		return nil, nil
+	case ArgumentTypeTime, ArgumentTypeBigInt, ArgumentTypeBigFloat:
+		return b, nil
+	case ArgumentTypeSlice, ArgumentTypeMap, ArgumentTypeStruct:
+		if data := payload(b); !is_legacy_json(data) {
+			v, err := get_composite(t, data)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(to_json(v))
+		}
+	}
+	if data := get_data(b); data != nil {
+		return data, nil
+	}
+	return []byte{}, nil
 }
 func Encoder(arg any) ([]byte, error) {
 	return Encode(arg)
 }
 
-// typeof 穷举 Go 原语，返回 ArgumentType* 常量；标量之外走 AnyToBytes 的 JSON 路径映射成 String/Bytes。
+// typeof 穷举 Go 原语，返回 ArgumentType* 常量；其余按 Kind 归为 Slice/Map/Struct/Custom。
 func typeof(arg any) uint8 {
 	if arg == nil {
 		return ArgumentTypeNil
@@ -286,6 +351,14 @@ func typeof(arg any) uint8 {
 		return ArgumentTypeString
 	case []byte:
 		return ArgumentTypeBytes
+	case time.Time:
+		return ArgumentTypeTime
+	case time.Duration:
+		return ArgumentTypeDuration
+	case *big.Int, big.Int:
+		return ArgumentTypeBigInt
+	case *big.Float, big.Float:
+		return ArgumentTypeBigFloat
 	}
 	rv := reflect.ValueOf(arg)
 	switch rv.Kind() {
@@ -299,17 +372,26 @@ func typeof(arg any) uint8 {
 	return ArgumentTypeCustom
 }
 
-// Encode 写一帧；Length 超 65535 返回 ErrAgDataTooLarge
+// encode_ag 写一帧；Length 超 65535 写扩展帧，超 ArgumentMaxExtDataSize 返回 ErrAgDataTooLarge
 func encode_ag(t uint8, data []byte) ([]byte, error) {
-	if len(data) > ArgumentMaxDataSize {
+	if len(data) < ArgumentMaxDataSize {
+		out := make([]byte, ArgumentHeaderSize+len(data))
+		out[0] = ArgumentMagic1
+		out[1] = ArgumentMagic2
+		out[2] = t
+		binary.BigEndian.PutUint16(out[3:5], uint16(len(data)))
+		copy(out[ArgumentHeaderSize:], data)
+		return out, nil
+	}
+	if uint64(len(data)) > ArgumentMaxExtDataSize {
 		return nil, ErrAgDataTooLarge
 	}
-	out := make([]byte, ArgumentHeaderSize+len(data))
+	out := make([]byte, ArgumentExtHeaderSize+len(data))
 	out[0] = ArgumentMagic1
 	out[1] = ArgumentMagic2
-	out[2] = t
-	binary.BigEndian.PutUint16(out[3:5], uint16(len(data)))
-	copy(out[ArgumentHeaderSize:], data)
+	out[2] = t | ArgumentTypeExtended
+	binary.BigEndian.PutUint32(out[3:7], uint32(len(data)))
+	copy(out[ArgumentExtHeaderSize:], data)
 	return out, nil
 }
 
@@ -391,6 +473,12 @@ func get_value(b []byte) (any, error) {
 		out := make([]byte, len(v))
 		copy(out, v)
 		return out, nil
+
+	case ArgumentTypeSlice, ArgumentTypeMap, ArgumentTypeStruct, ArgumentTypeCustom:
+		return get_composite(t, v)
+
+	case ArgumentTypeTime, ArgumentTypeDuration, ArgumentTypeBigInt, ArgumentTypeBigFloat:
+		return get_ext(t, v)
 	}
 	return nil, ErrAgUnknownType
 }
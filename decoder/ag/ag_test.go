@@ -1,13 +1,14 @@
 package ag
 
 import (
+	"bytes"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -96,6 +97,16 @@ func typeName(t uint8) string {
 		return "map"
 	case ArgumentTypeStruct:
 		return "struct"
+	case ArgumentTypeCustom:
+		return "custom"
+	case ArgumentTypeTime:
+		return "time"
+	case ArgumentTypeDuration:
+		return "duration"
+	case ArgumentTypeBigInt:
+		return "bigint"
+	case ArgumentTypeBigFloat:
+		return "bigfloat"
 	}
 	return "unknown(" + strconv.FormatUint(uint64(t), 10) + ")"
 }
@@ -200,8 +211,8 @@ func TestEncode_TypeExhaustive(t *testing.T) {
 		{"String", sa.String, ArgumentTypeString, len(sa.String), len(sa.String)},
 		{"Byte", sa.Byte, ArgumentTypeUint8, 1, 8},          // byte = uint8 别名
 		{"Rune", sa.Rune, ArgumentTypeInt32, 1, 8},          // rune = int32 别名
-		{"struct", sa.B, ArgumentTypeString, 2, 1 << 16},    // struct 降级为 JSON String
-		{"Slice", sa.Slice, ArgumentTypeString, 2, 1 << 16}, // 复合 → JSON
+		{"struct", sa.B, ArgumentTypeString, 2, 1 << 16},    // struct 默认为 JSON String（旧版格式）
+		{"Slice", sa.Slice, ArgumentTypeString, 2, 1 << 16}, // 复合 → JSON，EncodeExt 才是嵌套帧
 		{"Slice16", sa.Slice16, ArgumentTypeString, 2, 1 << 16},
 		{"Slice32", sa.Slice32, ArgumentTypeString, 2, 1 << 16},
 		{"Slice64", sa.Slice64, ArgumentTypeString, 2, 1 << 16},
@@ -388,67 +399,98 @@ func TestEncode_TypeExhaustive(t *testing.T) {
 		}
 	})
 
-	// 8. Struct → JSON String 降级：JSON 可反解回等价 struct
-	t.Run("Struct_JsonFallback", func(t *testing.T) {
-		raw, err := Encode(sa.B)
+	// 8. Struct → 嵌套帧（EncodeExt）：DecodeInto 还原为等价 struct
+	t.Run("Struct_Native", func(t *testing.T) {
+		raw, err := EncodeExt(sa.B)
 		if err != nil {
 			t.Fatal(err)
 		}
-		assertFrame(t, "struct", raw, ArgumentTypeString, int(binary.BigEndian.Uint16(raw[3:5])))
-		jsonStr := string(raw[ArgumentHeaderSize:])
+		assertFrame(t, "struct", raw, ArgumentTypeStruct, int(binary.BigEndian.Uint16(raw[3:5])))
+		name, _ := EncodeExt("c")
+		if !bytes.HasPrefix(raw[ArgumentHeaderSize:], name) {
+			t.Fatalf("struct: first field frame=% x, want json name c", raw[ArgumentHeaderSize:])
+		}
 		var out innerB
-		if err := json.Unmarshal([]byte(jsonStr), &out); err != nil {
-			t.Fatalf("struct json unmarshal: %v  raw=%q", err, jsonStr)
+		if err := DecodeInto(raw, &out); err != nil {
+			t.Fatalf("struct decode: %v", err)
 		}
 		if out.C != sa.B.C {
 			t.Fatalf("struct B.C=%q, want %q", out.C, sa.B.C)
 		}
 	})
 
-	// 9. Map / Slice / []string / []float → JSON String 降级
-	t.Run("Composite_JsonFallback", func(t *testing.T) {
+	// 9. Map / Slice / []string → 嵌套帧（EncodeExt），DecodeInto 还原
+	t.Run("Composite_Native", func(t *testing.T) {
 		// a) []int
-		raw, _ := Encode(sa.Slice)
+		raw, _ := EncodeExt(sa.Slice)
 		var s1 []int
-		if err := json.Unmarshal(raw[ArgumentHeaderSize:], &s1); err != nil {
-			t.Fatalf("[]int json err %v", err)
+		if err := DecodeInto(raw, &s1); err != nil {
+			t.Fatalf("[]int err %v", err)
 		}
 		if !reflect.DeepEqual(s1, sa.Slice) {
 			t.Fatalf("[]int: got=%v want=%v", s1, sa.Slice)
 		}
 
-		// b) map[string]int —— key 可能乱序，Unmarshal 后再比较
-		rawM, _ := Encode(sa.Map)
+		// b) map[string]int —— 按键排序编码，结果稳定
+		rawM, _ := EncodeExt(sa.Map)
+		rawM2, _ := EncodeExt(map[string]int{"c": 3, "b": 2, "a": 1})
+		if !bytes.Equal(rawM, rawM2) {
+			t.Fatalf("map encode not stable: % x vs % x", rawM, rawM2)
+		}
 		var m1 map[string]int
-		if err := json.Unmarshal(rawM[ArgumentHeaderSize:], &m1); err != nil {
-			t.Fatalf("map json err %v", err)
+		if err := DecodeInto(rawM, &m1); err != nil {
+			t.Fatalf("map err %v", err)
 		}
 		if !reflect.DeepEqual(m1, sa.Map) {
 			t.Fatalf("map: got=%v want=%v", m1, sa.Map)
 		}
 
 		// c) []string
-		rawS, _ := Encode(sa.Arraya)
+		rawS, _ := EncodeExt(sa.Arraya)
 		var sA []string
-		if err := json.Unmarshal(rawS[ArgumentHeaderSize:], &sA); err != nil {
-			t.Fatalf("[]string json err %v", err)
+		if err := DecodeInto(rawS, &sA); err != nil {
+			t.Fatalf("[]string err %v", err)
 		}
 		if !reflect.DeepEqual(sA, sa.Arraya) {
 			t.Fatalf("[]string: got=%v want=%v", sA, sa.Arraya)
 		}
 	})
 
-	// 10. Encode 错误分支：超长 Value（>65535）
-	t.Run("ErrAgDataTooLarge", func(t *testing.T) {
-		big := make([]byte, ArgumentMaxDataSize+1)
-		_, err := Encode(big)
-		if !errors.Is(err, ErrAgDataTooLarge) {
-			t.Fatalf("big []byte err=%v, want ErrAgDataTooLarge", err)
-		}
-		boundary := make([]byte, ArgumentMaxDataSize)
-		if _, err := Encode(boundary); err != nil {
+	// 10. 超长 Value（>65535）写扩展帧：TYPE 置 0x80、LEN 4 字节
+	t.Run("Extended", func(t *testing.T) {
+		short, err := Encode(make([]byte, ArgumentMaxDataSize-1))
+		if err != nil {
 			t.Fatalf("boundary 65535 err=%v", err)
 		}
+		assertFrame(t, "boundary 65535", short, ArgumentTypeBytes, ArgumentMaxDataSize-1)
+
+		for _, n := range []int{ArgumentMaxDataSize, 3 << 20} {
+			big := make([]byte, n)
+			big[n-1] = 0x7F
+			raw, err := Encode(big)
+			if err != nil {
+				t.Fatalf("big []byte(%d) err=%v", n, err)
+			}
+			if raw[2] != ArgumentTypeBytes|ArgumentTypeExtended {
+				t.Fatalf("TYPE=0x%02x, want extended bytes", raw[2])
+			}
+			if l := int(binary.BigEndian.Uint32(raw[3:7])); l != n || len(raw) != ArgumentExtHeaderSize+n {
+				t.Fatalf("LENGTH=%d raw=%d, want %d", l, len(raw), n)
+			}
+			if !IsArgument(raw) || Validate(raw) != nil {
+				t.Fatalf("extended frame not recognised: %v", Validate(raw))
+			}
+			if !bytes.Equal(Data(raw), big) {
+				t.Fatalf("Data mismatch")
+			}
+			if d, err := Decoder(raw); err != nil || !bytes.Equal(d, big) {
+				t.Fatalf("Decoder mismatch: %v", err)
+			}
+			var out []byte
+			if err := DecodeInto(raw, &out); err != nil || !bytes.Equal(out, big) {
+				t.Fatalf("DecodeInto mismatch: %v", err)
+			}
+		}
 	})
 }
 
@@ -620,26 +662,25 @@ func TestDecode_RoundTrip(t *testing.T) {
 		}
 	})
 
-	// 5. JSON 降级类型 roundtrip：Decode 返回 string(…)，值等价于 JSON 文本
-	t.Run("Composite_DecodeReturnsJsonString", func(t *testing.T) {
-		type pair struct {
+	// 5. 复合类型 Decode：Slice → []any，Map/Struct → map[string]any，元素保留 Go 类型
+	t.Run("Composite_DecodeGeneric", func(t *testing.T) {
+		pairs := []struct {
 			name string
 			in   any
-		}
-		pairs := []pair{
-			{"[]int", sa.Slice},
-			{"[]int16", sa.Slice16},
-			{"[]int32", sa.Slice32},
-			{"[]int64", sa.Slice64},
-			{"map[string]int", sa.Map},
-			{"[]string", sa.Arraya},
-			{"innerB struct", sa.B},
-			{"[]float32", sa.Float32s},
-			{"[]float64", sa.Float64s},
+			want any
+		}{
+			{"[]int", sa.Slice, []any{-1, 2, 3, 4, 5}},
+			{"[]int16", sa.Slice16, []any{int16(1), int16(-2), int16(3), int16(4), int16(5)}},
+			{"[]int64", sa.Slice64, []any{int64(1), int64(2), int64(3), int64(-4), int64(5)}},
+			{"map[string]int", sa.Map, map[string]any{"a": 1, "b": 2, "c": 3}},
+			{"[]string", sa.Arraya, []any{"a中广", "b节qqq112", "c1231ff"}},
+			{"innerB struct", sa.B, map[string]any{"c": sa.B.C}},
+			{"[]float32", sa.Float32s, []any{float32(1.1), float32(2.2), float32(3.3)}},
+			{"map[int]bool", map[int]bool{1: true}, map[any]any{1: true}},
 		}
 		for _, p := range pairs {
 			t.Run(p.name, func(t *testing.T) {
-				raw, err := Encode(p.in)
+				raw, err := EncodeExt(p.in)
 				if err != nil {
 					t.Fatalf("Encode %s err %v", p.name, err)
 				}
@@ -647,42 +688,47 @@ func TestDecode_RoundTrip(t *testing.T) {
 				if err != nil {
 					t.Fatalf("Decode %s err %v", p.name, err)
 				}
-				s, ok := out.(string)
-				if !ok {
-					t.Fatalf("%s Decode type=%T, want string(json)", p.name, out)
-				}
-				// 再把 s Unmarshal 回 in 的类型，与原输入相等
-				if reflect.DeepEqual(p.in, sa.Map) {
-					var m map[string]int
-					if err := json.Unmarshal([]byte(s), &m); err != nil {
-						t.Fatalf("map json: %v", err)
-					}
-					if !reflect.DeepEqual(m, sa.Map) {
-						t.Fatalf("map: got=%v want=%v", m, sa.Map)
-					}
-				} else if reflect.DeepEqual(p.in, sa.B) {
-					var o innerB
-					if err := json.Unmarshal([]byte(s), &o); err != nil {
-						t.Fatalf("struct json: %v", err)
-					}
-					if o.C != sa.B.C {
-						t.Fatalf("struct C=%q want %q", o.C, sa.B.C)
-					}
-				} else {
-					// 其它切片：直接 Unmarshal 到 any，再 DeepEqual
-					var rawOut any
-					if err := json.Unmarshal([]byte(s), &rawOut); err != nil {
-						t.Fatalf("unmarshal json err %v  json=%q", err, s)
-					}
-					// 与 in 的 JSON 编码再解码结果比较（避免 []int vs []float64 类型差异）
-					wantBytes, _ := json.Marshal(p.in)
-					var wantNorm any
-					_ = json.Unmarshal(wantBytes, &wantNorm)
-					if !reflect.DeepEqual(rawOut, wantNorm) {
-						t.Fatalf("%s json norm mismatch:\ngot =%#v\nwant=%#v", p.name, rawOut, wantNorm)
-					}
+				if !reflect.DeepEqual(out, p.want) {
+					t.Fatalf("%s: got=%#v want=%#v", p.name, out, p.want)
 				}
 			})
 		}
 	})
 }
+
+// 扩展帧：嵌套在复合帧中、截断与长度不符
+func TestExtended(t *testing.T) {
+	big := strings.Repeat("x", ArgumentMaxDataSize+10)
+	in := map[string]any{"big": big, "list": []string{big, "s"}}
+	raw, err := Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[2]&ArgumentTypeExtended == 0 {
+		t.Fatalf("outer frame should be extended, TYPE=0x%02x", raw[2])
+	}
+	var out map[string]any
+	if err := DecodeInto(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["big"] != big || !reflect.DeepEqual(out["list"], []any{big, "s"}) {
+		t.Fatalf("nested extended frames mismatch")
+	}
+
+	// 小值仍写普通帧，与旧版字节完全一致
+	small, _ := Encode("s")
+	if !bytes.Equal(small, []byte{ArgumentMagic1, ArgumentMagic2, ArgumentTypeString, 0, 1, 's'}) {
+		t.Fatalf("short frame changed: % x", small)
+	}
+
+	ext, _ := Encode([]byte(big))
+	if IsArgument(ext[:ArgumentHeaderSize]) || Validate(ext[:6]) != ErrAgTooShort {
+		t.Fatalf("truncated extended header accepted")
+	}
+	if IsArgument(ext[:len(ext)-1]) || Validate(ext[:len(ext)-1]) != ErrAgLengthMismatch {
+		t.Fatalf("truncated extended value accepted")
+	}
+	if _, err := Decode(ext[:len(ext)-1]); !errors.Is(err, ErrAgInvalidHeader) {
+		t.Fatalf("Decode truncated err = %v", err)
+	}
+}
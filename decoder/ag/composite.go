@@ -0,0 +1,631 @@
+package ag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/w6xian/sloth/v3/internal/utils"
+)
+
+/**
+ * 复合类型帧：VALUE 为嵌套的 AG 帧序列，自描述、可任意嵌套
+ *
+ * Slice   元素帧 e0 e1 ...            （[]byte 仍为 Bytes 帧，nil slice 为 Nil 帧）
+ * Map     键帧 值帧 键帧 值帧 ...      （按键帧字节排序，编码结果稳定）
+ * Struct  字段名(String 帧) 值帧 ...   （字段名取 json tag，规则同 encoding/json：
+ *                                       "-" 忽略、omitempty 零值省略、匿名结构体字段展开）
+ * Custom  JSON 文本                   （实现 json.Marshaler 的类型，以及 chan/func 等无法原生编码的类型）
+ *
+ * 旧版本把 Slice/Map/Struct 帧的 VALUE 写成 JSON，解码时按首字节区分：空或以 magic 开头为嵌套帧，否则为 JSON。
+ */
+
+var typeOfJsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// MaxDepth 反射编码的最大嵌套层数，超过返回 ErrAgTooDeep
+const MaxDepth = 1000
+
+// cycleCheckDepth 嵌套超过该层数后才记录路径上的引用（与 encoding/json 相同的取舍，浅层不付出额外开销）
+const cycleCheckDepth = 64
+
+var (
+	ErrAgCycle   = errors.New("ag: encountered a cycle")
+	ErrAgTooDeep = fmt.Errorf("ag: exceeded max depth %d", MaxDepth)
+)
+
+// encoder 一次反射编码的状态：当前嵌套深度，以及路径上的指针/map/slice，再次遇到即为循环引用
+type encoder struct {
+	depth int
+	path  map[ref_key]struct{}
+}
+
+type ref_key struct {
+	ptr unsafe.Pointer
+	typ reflect.Type
+	len int
+}
+
+// enter 记录路径上的引用，已在路径上时返回 ErrAgCycle；返回的 leave 在编码完该值后调用
+func (e *encoder) enter(rv reflect.Value, n int) (func(), error) {
+	if e.depth <= cycleCheckDepth {
+		return func() {}, nil
+	}
+	k := ref_key{rv.UnsafePointer(), rv.Type(), n}
+	if e.path == nil {
+		e.path = make(map[ref_key]struct{})
+	}
+	if _, ok := e.path[k]; ok {
+		return nil, fmt.Errorf("%w via %s", ErrAgCycle, rv.Type())
+	}
+	e.path[k] = struct{}{}
+	return func() { delete(e.path, k) }, nil
+}
+
+// kind_tags 标量 Kind 对应的类型标签，具名类型（type Level int）按底层类型编码
+var kind_tags = map[reflect.Kind]uint8{
+	reflect.Bool:       ArgumentTypeBool,
+	reflect.Int:        ArgumentTypeInt,
+	reflect.Int8:       ArgumentTypeInt8,
+	reflect.Int16:      ArgumentTypeInt16,
+	reflect.Int32:      ArgumentTypeInt32,
+	reflect.Int64:      ArgumentTypeInt64,
+	reflect.Uint:       ArgumentTypeUint,
+	reflect.Uint8:      ArgumentTypeUint8,
+	reflect.Uint16:     ArgumentTypeUint16,
+	reflect.Uint32:     ArgumentTypeUint32,
+	reflect.Uint64:     ArgumentTypeUint64,
+	reflect.Uintptr:    ArgumentTypeUintptr,
+	reflect.Float32:    ArgumentTypeFloat32,
+	reflect.Float64:    ArgumentTypeFloat64,
+	reflect.Complex64:  ArgumentTypeComplex64,
+	reflect.Complex128: ArgumentTypeComplex128,
+	reflect.String:     ArgumentTypeString,
+}
+
+// encode_value 反射编码：指针/接口解引用（nil 为 Nil 帧），复合类型递归编码为嵌套帧；
+// 与 encoding/json 一致，循环引用返回 ErrAgCycle
+func encode_value(rv reflect.Value) ([]byte, error) {
+	return new(encoder).value(rv)
+}
+
+func (e *encoder) value(rv reflect.Value) ([]byte, error) {
+	if e.depth++; e.depth > MaxDepth {
+		return nil, ErrAgTooDeep
+	}
+	defer func() { e.depth-- }()
+	for {
+		if !rv.IsValid() {
+			return encode_ag(ArgumentTypeNil, nil)
+		}
+		if rv.Kind() != reflect.Pointer && rv.Kind() != reflect.Interface {
+			break
+		}
+		if rv.IsNil() {
+			return encode_ag(ArgumentTypeNil, nil)
+		}
+		if rv.Type().Implements(typeOfJsonMarshaler) && !is_ext(rv.Type().Elem()) {
+			return encode_custom(rv)
+		}
+		if rv.Kind() == reflect.Pointer {
+			leave, err := e.enter(rv, 0)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		rv = rv.Elem()
+	}
+	if is_ext(rv.Type()) {
+		return encode_ext(rv)
+	}
+	if rv.Type().Implements(typeOfJsonMarshaler) || (rv.CanAddr() && rv.Addr().Type().Implements(typeOfJsonMarshaler)) {
+		return encode_custom(rv)
+	}
+	if t, ok := kind_tags[rv.Kind()]; ok {
+		return encode_ag(t, scalar_bytes(rv))
+	}
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			return encode_ag(ArgumentTypeNil, nil)
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return encode_ag(ArgumentTypeBytes, rv.Bytes())
+		}
+		leave, err := e.enter(rv, rv.Len())
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return e.list(rv)
+	case reflect.Array:
+		// 与 encoding/json 一致，[N]byte 按数组编码
+		return e.list(rv)
+	case reflect.Map:
+		if rv.IsNil() {
+			return encode_ag(ArgumentTypeNil, nil)
+		}
+		leave, err := e.enter(rv, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return e.map_(rv)
+	case reflect.Struct:
+		return e.struct_(rv)
+	}
+	// chan/func 等：与旧版一致，JSON 兜底
+	return encode_ag(ArgumentTypeCustom, utils.Serialize(rv.Interface()))
+}
+
+func encode_custom(rv reflect.Value) ([]byte, error) {
+	if rv.CanAddr() {
+		rv = rv.Addr()
+	}
+	b, err := json.Marshal(rv.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return encode_ag(ArgumentTypeCustom, b)
+}
+
+// scalar_bytes 标量的 VALUE 段，与 Encode 的原语编码一致
+func scalar_bytes(rv reflect.Value) []byte {
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return []byte{1}
+		}
+		return []byte{0}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int_to_byte(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uint_to_byte(rv.Uint())
+	case reflect.Float32:
+		return binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(rv.Float())))
+	case reflect.Float64:
+		return binary.LittleEndian.AppendUint64(nil, math.Float64bits(rv.Float()))
+	case reflect.Complex64:
+		c := rv.Complex()
+		buf := binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(real(c))))
+		return binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(imag(c))))
+	case reflect.Complex128:
+		c := rv.Complex()
+		buf := binary.LittleEndian.AppendUint64(nil, math.Float64bits(real(c)))
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(imag(c)))
+	}
+	return []byte(rv.String())
+}
+
+func (e *encoder) list(rv reflect.Value) ([]byte, error) {
+	var buf []byte
+	for i := 0; i < rv.Len(); i++ {
+		b, err := e.value(rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return encode_ag(ArgumentTypeSlice, buf)
+}
+
+func (e *encoder) map_(rv reflect.Value) ([]byte, error) {
+	type entry struct{ k, v []byte }
+	entries := make([]entry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		k, err := e.value(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		v, err := e.value(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{k, v})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return bytes.Compare(a.k, b.k) })
+	var buf []byte
+	for _, e := range entries {
+		buf = append(buf, e.k...)
+		buf = append(buf, e.v...)
+	}
+	return encode_ag(ArgumentTypeMap, buf)
+}
+
+func (e *encoder) struct_(rv reflect.Value) ([]byte, error) {
+	var buf []byte
+	for _, f := range struct_fields(rv.Type()) {
+		fv, ok := field_value(rv, f.index)
+		if !ok || (f.omitempty && is_empty(fv)) {
+			continue
+		}
+		name, _ := encode_ag(ArgumentTypeString, []byte(f.name))
+		v, err := e.value(fv)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, name...)
+		buf = append(buf, v...)
+	}
+	return encode_ag(ArgumentTypeStruct, buf)
+}
+
+// ag_field 可编码的结构体字段
+type ag_field struct {
+	name      string
+	index     []int
+	omitempty bool
+}
+
+var fieldsCache sync.Map // reflect.Type -> []ag_field
+
+// struct_fields 导出字段（按 json tag 命名），匿名结构体字段展开，外层同名字段优先
+func struct_fields(t reflect.Type) []ag_field {
+	if f, ok := fieldsCache.Load(t); ok {
+		return f.([]ag_field)
+	}
+	fields := collect_fields(t, map[reflect.Type]bool{})
+	fieldsCache.Store(t, fields)
+	return fields
+}
+
+// collect_fields visiting 为正在展开的类型：与 encoding/json 一致，匿名字段的类型已在展开中时跳过，
+// 互相嵌入（A 嵌入 *B、B 嵌入 *A）或嵌入自身都能终止。中间结果依赖 visiting，不写入缓存
+func collect_fields(t reflect.Type, visiting map[reflect.Type]bool) []ag_field {
+	visiting[t] = true
+	defer delete(visiting, t)
+	var fields []ag_field
+	seen := map[string]bool{}
+	var embedded []ag_field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if visiting[ft] {
+				continue
+			}
+			if !sf.IsExported() && sf.Type.Kind() == reflect.Pointer {
+				// 与 encoding/json 一致：无法为未导出的匿名指针分配内存
+				continue
+			}
+			for _, inner := range collect_fields(ft, visiting) {
+				inner.index = append([]int{i}, inner.index...)
+				embedded = append(embedded, inner)
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		seen[name] = true
+		fields = append(fields, ag_field{name: name, index: []int{i}, omitempty: strings.Contains(opts, "omitempty")})
+	}
+	for _, f := range embedded {
+		if !seen[f.name] {
+			seen[f.name] = true
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// field_value 按下标路径取字段，途经的匿名指针为 nil 时返回 false
+func field_value(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// field_alloc 同 field_value，解码时为途经的 nil 匿名指针分配内存
+func field_alloc(rv reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv
+}
+
+// is_empty 与 encoding/json 的 omitempty 判断一致
+func is_empty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// is_legacy_json 旧版 Slice/Map/Struct 帧的 VALUE 为 JSON 文本
+func is_legacy_json(data []byte) bool {
+	return len(data) > 0 && data[0] != ArgumentMagic1
+}
+
+// split_frames 把复合帧的 VALUE 拆成嵌套帧
+func split_frames(data []byte) ([][]byte, error) {
+	var frames [][]byte
+	for len(data) > 0 {
+		if len(data) < ArgumentHeaderSize {
+			return nil, ErrAgTooShort
+		}
+		if data[0] != ArgumentMagic1 || data[1] != ArgumentMagic2 {
+			return nil, ErrAgBadMagic
+		}
+		hdr, length, ok := frame_size(data)
+		if !ok {
+			return nil, ErrAgTooShort
+		}
+		if len(data)-hdr < length {
+			return nil, ErrAgLengthMismatch
+		}
+		n := hdr + length
+		frames = append(frames, data[:n:n])
+		data = data[n:]
+	}
+	return frames, nil
+}
+
+// payload 复合帧的 VALUE 段（不拷贝）
+func payload(b []byte) []byte {
+	hdr, _, _ := frame_size(b)
+	return b[hdr:]
+}
+
+// get_composite Decode 复合帧：Slice 为 []any，Struct 为 map[string]any，
+// Map 的键全为 string 时为 map[string]any，否则为 map[any]any；Custom 按 JSON 解析
+func get_composite(t uint8, data []byte) (any, error) {
+	if t == ArgumentTypeCustom || is_legacy_json(data) {
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	frames, err := split_frames(data)
+	if err != nil {
+		return nil, err
+	}
+	if t == ArgumentTypeSlice {
+		out := make([]any, len(frames))
+		for i, f := range frames {
+			if out[i], err = get_value(f); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	if len(frames)%2 != 0 {
+		return nil, ErrAgLengthMismatch
+	}
+	keys := make([]any, 0, len(frames)/2)
+	vals := make([]any, 0, len(frames)/2)
+	allString := true
+	for i := 0; i < len(frames); i += 2 {
+		k, err := get_value(frames[i])
+		if err != nil {
+			return nil, err
+		}
+		v, err := get_value(frames[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := k.(string); !ok {
+			allString = false
+			if k != nil && !reflect.TypeOf(k).Comparable() {
+				return nil, fmt.Errorf("%w: map key %T", ErrAgTypeMismatch, k)
+			}
+		}
+		keys = append(keys, k)
+		vals = append(vals, v)
+	}
+	if allString {
+		out := make(map[string]any, len(keys))
+		for i, k := range keys {
+			out[k.(string)] = vals[i]
+		}
+		return out, nil
+	}
+	out := make(map[any]any, len(keys))
+	for i, k := range keys {
+		out[k] = vals[i]
+	}
+	return out, nil
+}
+
+// to_json Decode 的结果转为 JSON 可编码的值：map[any]any 的键按 fmt 文本化
+func to_json(v any) any {
+	switch x := v.(type) {
+	case []any:
+		for i := range x {
+			x[i] = to_json(x[i])
+		}
+	case map[string]any:
+		for k := range x {
+			x[k] = to_json(x[k])
+		}
+	case map[any]any:
+		out := make(map[string]any, len(x))
+		for k, val := range x {
+			out[fmt.Sprint(k)] = to_json(val)
+		}
+		return out
+	}
+	return v
+}
+
+// decode_frame 把一帧解码到 dst（可寻址）
+func decode_frame(b []byte, dst reflect.Value) error {
+	t := frame_type(b)
+	if t == ArgumentTypeNil {
+		dst.SetZero()
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decode_frame(b, dst.Elem())
+	case reflect.Interface:
+		if dst.NumMethod() == 0 {
+			val, err := get_value(b)
+			if err != nil {
+				return err
+			}
+			if val == nil {
+				dst.SetZero()
+			} else {
+				dst.Set(reflect.ValueOf(val))
+			}
+			return nil
+		}
+	}
+	switch t {
+	case ArgumentTypeTime, ArgumentTypeDuration, ArgumentTypeBigInt, ArgumentTypeBigFloat:
+		val, err := get_value(b)
+		if err != nil {
+			return err
+		}
+		return set_ext(dst, val)
+	case ArgumentTypeString, ArgumentTypeBytes:
+		return decode_raw(get_data(b), dst)
+	case ArgumentTypeCustom:
+		return json.Unmarshal(payload(b), dst.Addr().Interface())
+	case ArgumentTypeSlice, ArgumentTypeMap, ArgumentTypeStruct:
+		data := payload(b)
+		if is_legacy_json(data) {
+			return json.Unmarshal(data, dst.Addr().Interface())
+		}
+		frames, err := split_frames(data)
+		if err != nil {
+			return err
+		}
+		if t == ArgumentTypeSlice {
+			return decode_list(frames, dst)
+		}
+		return decode_pairs(frames, dst)
+	}
+	val, err := get_value(b)
+	if err != nil {
+		return err
+	}
+	return set_value(dst, val)
+}
+
+func decode_list(frames [][]byte, dst reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Slice:
+		out := reflect.MakeSlice(dst.Type(), len(frames), len(frames))
+		for i, f := range frames {
+			if err := decode_frame(f, out.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		dst.Set(out)
+		return nil
+	case reflect.Array:
+		dst.SetZero()
+		for i, f := range frames {
+			if i >= dst.Len() {
+				break
+			}
+			if err := decode_frame(f, dst.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: cannot decode slice into %s", ErrAgTypeMismatch, dst.Type())
+}
+
+// decode_pairs Map/Struct 帧：目标可以是 map 或结构体（字段名按 json tag 匹配，其次忽略大小写）
+func decode_pairs(frames [][]byte, dst reflect.Value) error {
+	if len(frames)%2 != 0 {
+		return ErrAgLengthMismatch
+	}
+	switch dst.Kind() {
+	case reflect.Map:
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(frames)/2))
+		}
+		kt, vt := dst.Type().Key(), dst.Type().Elem()
+		for i := 0; i < len(frames); i += 2 {
+			k := reflect.New(kt).Elem()
+			if err := decode_frame(frames[i], k); err != nil {
+				return fmt.Errorf("key: %w", err)
+			}
+			v := reflect.New(vt).Elem()
+			if err := decode_frame(frames[i+1], v); err != nil {
+				return fmt.Errorf("[%v]: %w", k.Interface(), err)
+			}
+			dst.SetMapIndex(k, v)
+		}
+		return nil
+	case reflect.Struct:
+		fields := struct_fields(dst.Type())
+		for i := 0; i < len(frames); i += 2 {
+			if frame_type(frames[i]) != ArgumentTypeString {
+				return fmt.Errorf("%w: struct field name must be string", ErrAgTypeMismatch)
+			}
+			name := string(payload(frames[i]))
+			f := find_field(fields, name)
+			if f == nil {
+				continue
+			}
+			if err := decode_frame(frames[i+1], field_alloc(dst, f.index)); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: cannot decode map into %s", ErrAgTypeMismatch, dst.Type())
+}
+
+func find_field(fields []ag_field, name string) *ag_field {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
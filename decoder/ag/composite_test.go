@@ -0,0 +1,305 @@
+package ag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Level int
+
+type cBase struct {
+	Id int64 `json:"id"`
+}
+
+type cItem struct {
+	cBase
+	Name  string            `json:"name"`
+	Level Level             `json:"level"`
+	Skip  string            `json:"-"`
+	Note  string            `json:"note,omitempty"`
+	Next  *cItem            `json:"next"`
+	Attrs map[string]string `json:"attrs"`
+	Code  [2]byte           `json:"code"`
+	Pos   [2]int16          `json:"pos"`
+	Any   any               `json:"any"`
+	At    time.Time         `json:"at"`
+	inner int
+}
+
+func TestComposite_FullStruct(t *testing.T) {
+	sa := sampleA()
+	raw, err := EncodeExt(&sa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out fullA
+	if err := DecodeInto(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	// JSON 不支持的 complex 字段同样原样还原
+	if !reflect.DeepEqual(out, sa) {
+		t.Fatalf("got  %+v\nwant %+v", out, sa)
+	}
+}
+
+func TestComposite_Nested(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	in := cItem{
+		cBase: cBase{Id: 7},
+		Name:  "a",
+		Level: 3,
+		Skip:  "skip",
+		Next:  &cItem{Name: "b", Attrs: map[string]string{"k": "v"}},
+		Code:  [2]byte{1, 2},
+		Pos:   [2]int16{-1, 1},
+		Any:   []any{"x", int64(1)},
+		At:    at,
+		inner: 1,
+	}
+	raw, err := EncodeExt(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out cItem
+	if err := DecodeInto(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := in
+	want.Skip, want.inner = "", 0
+	want.Next.At = time.Time{}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("got  %+v\nwant %+v", out, want)
+	}
+
+	// 字段名与 encoding/json 一致：匿名字段展开、"-" 忽略、omitempty 省略
+	m, err := Decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := m.(map[string]any)
+	for _, name := range []string{"id", "name", "level", "next", "attrs", "code", "pos", "any", "at"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("missing field %s in %v", name, fields)
+		}
+	}
+	for _, name := range []string{"Skip", "note", "inner", "cBase"} {
+		if _, ok := fields[name]; ok {
+			t.Errorf("unexpected field %s", name)
+		}
+	}
+	// time.Time 为 Time 帧（见 ext.go）
+	if got, ok := fields["at"].(time.Time); !ok || !got.Equal(at) {
+		t.Errorf("at = %#v", fields["at"])
+	}
+}
+
+func TestComposite_Loose(t *testing.T) {
+	// Map 帧可以解码到结构体，字段名忽略大小写；未知字段跳过
+	raw, _ := EncodeExt(map[string]any{"NAME": "x", "id": 5, "unknown": true})
+	var it cItem
+	if err := DecodeInto(raw, &it); err != nil || it.Name != "x" || it.Id != 5 {
+		t.Fatalf("map->struct: %+v, %v", it, err)
+	}
+	// Struct 帧解码到 map
+	raw, _ = EncodeExt(cBase{Id: 9})
+	var m map[string]int
+	if err := DecodeInto(raw, &m); err != nil || m["id"] != 9 {
+		t.Fatalf("struct->map: %v, %v", m, err)
+	}
+	// 字符串键解码到整数键
+	raw, _ = EncodeExt(map[string]string{"1": "a"})
+	var mi map[int]string
+	if err := DecodeInto(raw, &mi); err != nil || mi[1] != "a" {
+		t.Fatalf("map key: %v, %v", mi, err)
+	}
+	// 空 slice 与 nil slice
+	raw, _ = EncodeExt([]int{})
+	var s []int
+	if err := DecodeInto(raw, &s); err != nil || s == nil || len(s) != 0 {
+		t.Fatalf("empty slice: %#v, %v", s, err)
+	}
+	raw, _ = EncodeExt([]int(nil))
+	if err := DecodeInto(raw, &s); err != nil || s != nil {
+		t.Fatalf("nil slice: %#v, %v", s, err)
+	}
+}
+
+func TestComposite_Errors(t *testing.T) {
+	raw, _ := EncodeExt([]string{"a"})
+	var m map[string]int
+	if err := DecodeInto(raw, &m); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Fatalf("slice->map err = %v", err)
+	}
+	raw, _ = EncodeExt(map[string]bool{"a": true})
+	var s []int
+	if err := DecodeInto(raw, &s); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Fatalf("map->slice err = %v", err)
+	}
+	raw, _ = EncodeExt([]any{"x"})
+	var ints []int
+	if err := DecodeInto(raw, &ints); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Fatalf("element err = %v", err)
+	}
+	// 截断的嵌套帧
+	bad, _ := encode_ag(ArgumentTypeSlice, []byte{ArgumentMagic1, ArgumentMagic2, ArgumentTypeInt, 0, 2, 1})
+	if _, err := Decode(bad); !errors.Is(err, ErrAgLengthMismatch) {
+		t.Fatalf("truncated err = %v", err)
+	}
+}
+
+type cycleNode struct {
+	Name string     `json:"name"`
+	Next *cycleNode `json:"next,omitempty"`
+}
+
+func TestComposite_Cycle(t *testing.T) {
+	n := &cycleNode{Name: "a"}
+	n.Next = n
+	m := map[string]any{}
+	m["self"] = m
+	s := []any{nil}
+	s[0] = s
+	for name, v := range map[string]any{"pointer": n, "map": m, "slice": s} {
+		if _, err := EncodeExt(v); !errors.Is(err, ErrAgCycle) {
+			t.Errorf("%s: err = %v, want ErrAgCycle", name, err)
+		}
+	}
+
+	// 共享的引用与超过 cycleCheckDepth 的长链不是循环
+	shared := &cycleNode{Name: "shared"}
+	if _, err := EncodeExt([]*cycleNode{shared, shared}); err != nil {
+		t.Fatalf("shared: %v", err)
+	}
+	chain := func(n int) *cycleNode {
+		head := &cycleNode{}
+		for i := 1; i < n; i++ {
+			head = &cycleNode{Name: fmt.Sprint(i), Next: head}
+		}
+		return head
+	}
+	raw, err := EncodeExt(chain(cycleCheckDepth * 3))
+	if err != nil {
+		t.Fatalf("chain: %v", err)
+	}
+	var back cycleNode
+	if err := DecodeInto(raw, &back); err != nil || back.Name != fmt.Sprint(cycleCheckDepth*3-1) {
+		t.Fatalf("chain decode: %v %q", err, back.Name)
+	}
+	if _, err := EncodeExt(chain(MaxDepth)); !errors.Is(err, ErrAgTooDeep) {
+		t.Fatalf("deep err = %v, want ErrAgTooDeep", err)
+	}
+}
+
+type EmbedA struct {
+	Id int `json:"id"`
+	*EmbedB
+}
+
+type EmbedB struct {
+	Name string `json:"name"`
+	*EmbedA
+}
+
+// 互相嵌入的结构体：与 encoding/json 一致，已在展开中的类型不再展开
+func TestComposite_MutualEmbed(t *testing.T) {
+	names := func(fields []ag_field) []string {
+		var out []string
+		for _, f := range fields {
+			out = append(out, f.name)
+		}
+		return out
+	}
+	if got := names(struct_fields(reflect.TypeOf(EmbedA{}))); !reflect.DeepEqual(got, []string{"id", "name"}) {
+		t.Fatalf("EmbedA fields = %v", got)
+	}
+	if got := names(struct_fields(reflect.TypeOf(EmbedB{}))); !reflect.DeepEqual(got, []string{"name", "id"}) {
+		t.Fatalf("EmbedB fields = %v", got)
+	}
+	in := EmbedA{Id: 1, EmbedB: &EmbedB{Name: "b"}}
+	raw, err := EncodeExt(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out EmbedA
+	if err := DecodeInto(raw, &out); err != nil || out.Id != 1 || out.EmbedB == nil || out.Name != "b" {
+		t.Fatalf("decode: %+v, %v", out, err)
+	}
+	want, _ := json.Marshal(in)
+	got, _ := json.Marshal(out)
+	if string(got) != string(want) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+// 旧版复合帧的 VALUE 为 JSON，仍可解码
+func TestComposite_LegacyJSON(t *testing.T) {
+	legacy, _ := encode_ag(ArgumentTypeStruct, []byte(`{"id":3,"name":"old"}`))
+	var it cItem
+	if err := DecodeInto(legacy, &it); err != nil || it.Id != 3 || it.Name != "old" {
+		t.Fatalf("DecodeInto: %+v, %v", it, err)
+	}
+	v, err := Decode(legacy)
+	if err != nil || !reflect.DeepEqual(v, map[string]any{"id": 3.0, "name": "old"}) {
+		t.Fatalf("Decode: %#v, %v", v, err)
+	}
+	if d, _ := Decoder(legacy); string(d) != `{"id":3,"name":"old"}` {
+		t.Fatalf("Decoder: %s", d)
+	}
+}
+
+// Encode 默认为旧版 JSON 形式（旧版对端可解析），EncodeExt 为嵌套帧，DecodeInto 两者都能还原
+func TestComposite_EncodePaths(t *testing.T) {
+	in := cItem{cBase: cBase{Id: 7}, Name: "n", Attrs: map[string]string{"k": "v"}}
+	want, _ := json.Marshal(in)
+
+	legacy, err := Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame_type(legacy) != ArgumentTypeString || string(get_data(legacy)) != string(want) {
+		t.Fatalf("Encode: type %d, %s", frame_type(legacy), get_data(legacy))
+	}
+	ext, err := EncodeExt(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame_type(ext) != ArgumentTypeStruct || json.Valid(get_data(ext)) {
+		t.Fatalf("EncodeExt: type %d, %s", frame_type(ext), get_data(ext))
+	}
+	for name, raw := range map[string][]byte{"legacy": legacy, "ext": ext} {
+		var out cItem
+		if err := DecodeInto(raw, &out); err != nil || out.Id != 7 || out.Name != "n" || out.Attrs["k"] != "v" {
+			t.Fatalf("%s: %+v, %v", name, out, err)
+		}
+	}
+	// EncodeTyped 同样默认 JSON
+	if typed, _ := EncodeTyped(in); frame_type(typed) != ArgumentTypeStruct || string(get_data(typed)) != string(want) {
+		t.Fatalf("EncodeTyped: %s", get_data(typed))
+	}
+}
+
+// Decoder 把复合帧转为 JSON：服务端按 JSON 解码结构体参数的旧路径不变
+func TestComposite_Decoder(t *testing.T) {
+	in := cItem{cBase: cBase{Id: 1}, Name: "n", Attrs: map[string]string{"k": "v"}, Code: [2]byte{1, 2}}
+	raw, _ := EncodeExt(&in)
+	data, err := Decoder(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out cItem
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	if out.Id != 1 || out.Name != "n" || out.Attrs["k"] != "v" {
+		t.Fatalf("got %+v from %s", out, data)
+	}
+	// 非字符串键按文本输出
+	raw, _ = EncodeExt(map[int][]int64{2: {1, 2}})
+	if data, _ := Decoder(raw); string(data) != `{"2":[1,2]}` {
+		t.Fatalf("map[int]: %s", data)
+	}
+}
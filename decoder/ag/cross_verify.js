@@ -0,0 +1,100 @@
+// 与 Go 版 ag 交叉校验扩展标量帧（Time / Duration / BigInt / BigFloat / Nil）
+// 用法：node decoder/ag/cross_verify.js
+const path = require('path');
+const child_process = require('child_process');
+
+require(path.join(__dirname, '..', '..', 'examples', 'ws', 'web', 'sloth_rpc_v3.js'));
+const AG = globalThis.AG;
+
+function toHex(u8) {
+    return Buffer.from(u8.buffer, u8.byteOffset, u8.byteLength).toString('hex');
+}
+function fromHex(h) {
+    const b = Buffer.from(h, 'hex');
+    return new Uint8Array(b.buffer, b.byteOffset, b.byteLength);
+}
+
+// 与 cross_verify_main.go 的 canon 一致
+function canon(v) {
+    if (v === null || v === undefined) return null;
+    if (v instanceof Date) return v.toISOString();
+    if (typeof v === 'bigint') return v.toString();
+    if (Array.isArray(v)) return v.map(canon);
+    if (v instanceof Map) v = Object.fromEntries(v);
+    if (typeof v === 'object') {
+        const out = {};
+        for (const k of Object.keys(v).sort()) out[k] = canon(v[k]);
+        return out;
+    }
+    return v;
+}
+function text(v) {
+    return JSON.stringify(canon(v));
+}
+
+function goRun(args, input) {
+    try {
+        const out = child_process.execSync('go run cross_verify_main.go ' + args, {
+            cwd: __dirname, input: input, stdio: ['pipe', 'pipe', 'pipe'],
+        });
+        return JSON.parse(out.toString().trim());
+    } catch (e) {
+        console.error('FAIL: go run cross_verify_main.go ' + args + ':', e.stderr ? e.stderr.toString() : e.message);
+        process.exit(1);
+    }
+}
+
+let failed = 0;
+
+// ---------- Part 1: Go encode -> JS decode ----------
+for (const c of goRun('', '')) {
+    let got;
+    try {
+        got = text(AG.DecodeArg(fromHex(c.hex)));
+    } catch (e) {
+        console.error(`[GoEncode->JSDecode] FAIL ${c.name}: Decode threw: ${e.message}`);
+        failed++;
+        continue;
+    }
+    if (got !== c.text) {
+        console.error(`[GoEncode->JSDecode] FAIL ${c.name}: js=${got} go=${c.text}`);
+        failed++;
+    } else {
+        console.log(`[GoEncode->JSDecode] OK   ${c.name} ${got}`);
+    }
+}
+
+// ---------- Part 2: JS encode -> Go decode ----------
+const jsCases = [
+    ['time_date', new Date(Date.UTC(2024, 4, 6, 7, 8, 9, 123))],
+    ['time_epoch', new Date(0)],
+    ['duration', AG.AsDuration(BigInt(5400001000000))],
+    ['duration_neg', AG.AsDuration(-1000000000)],
+    ['bigint_auto', BigInt(1) << BigInt(100)],
+    ['bigint_neg', AG.AsBigInt('-123456789012345678901234567890')],
+    ['bigint_zero', AG.AsBigInt(0)],
+    ['bigfloat', AG.AsBigFloat('3.14159265358979323846', 100)],
+    ['nil', null],
+    ['map_ext', new Map([['at', new Date(1700000000000)], ['ttl', AG.AsDuration(1000000000)], ['n', AG.AsBigInt(-7)], ['none', null]])],
+];
+const jsOut = jsCases.map(([name, v]) => {
+    const raw = AG.EncodeArgExt(v);
+    return { name, hex: toHex(raw), text: text(AG.DecodeArg(raw)) };
+});
+const goDecoded = goRun('decode', JSON.stringify(jsOut.map(c => ({ name: c.name, hex: c.hex }))));
+for (let i = 0; i < jsOut.length; i++) {
+    const want = jsOut[i].text;
+    const got = goDecoded[i].text;
+    if (got !== want) {
+        console.error(`[JSEncode->GoDecode] FAIL ${jsOut[i].name}: go=${got} js=${want}`);
+        failed++;
+    } else {
+        console.log(`[JSEncode->GoDecode] OK   ${jsOut[i].name} ${got}`);
+    }
+}
+
+if (failed) {
+    console.error(`\n${failed} case(s) FAILED`);
+    process.exit(1);
+}
+console.log('\nALL OK');
@@ -0,0 +1,121 @@
+//go:build ignore
+
+// 与 examples/ws/web/ag.js 交叉校验扩展标量帧，由 cross_verify.js 调用：
+//
+//	go run cross_verify_main.go          输出 Go 编码的用例 [{name,hex,text}]
+//	go run cross_verify_main.go decode   从 stdin 读 [{name,hex}]，输出 Go 解码结果 [{name,text}]
+//
+// text 为两端共同的规范形式：时间为毫秒精度的 UTC ISO 串，Duration/BigInt 为十进制串，
+// BigFloat 为 Text('g', -1)，nil 为 null，map/结构体为按键排序的 JSON 对象。
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/w6xian/sloth/v3/decoder/ag"
+)
+
+type testCase struct {
+	Name string `json:"name"`
+	Hex  string `json:"hex"`
+	Text string `json:"text,omitempty"`
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "decode" {
+		decode()
+		return
+	}
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	pi, _, _ := big.ParseFloat("3.14159265358979323846", 10, 100, big.ToNearestEven)
+	cases := []struct {
+		name string
+		v    any
+	}{
+		{"time_utc", time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.UTC)},
+		{"time_fixed_zone", time.Date(2024, 5, 6, 7, 8, 9, 456789000, time.FixedZone("CST", 8*3600))},
+		{"time_zero_unix", time.Unix(0, 0).UTC()},
+		{"duration", 90*time.Minute + time.Millisecond},
+		{"duration_neg", -time.Second},
+		{"bigint_pos", new(big.Int).Lsh(big.NewInt(1), 100)},
+		{"bigint_neg", huge},
+		{"bigint_zero", big.NewInt(0)},
+		{"bigfloat", pi},
+		{"nil_pointer", (*time.Time)(nil)},
+		{"map_ext", map[string]any{"at": time.Unix(1700000000, 0).UTC(), "ttl": time.Second, "n": big.NewInt(-7), "none": (*big.Int)(nil)}},
+	}
+	out := make([]testCase, 0, len(cases))
+	for _, c := range cases {
+		raw, err := ag.EncodeExt(c.v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERR %s: %v\n", c.name, err)
+			os.Exit(1)
+		}
+		v, err := ag.Decode(raw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERR %s: %v\n", c.name, err)
+			os.Exit(1)
+		}
+		out = append(out, testCase{Name: c.name, Hex: hex.EncodeToString(raw), Text: text(v)})
+	}
+	json.NewEncoder(os.Stdout).Encode(out)
+}
+
+func decode() {
+	var in []testCase
+	if err := json.NewDecoder(os.Stdin).Decode(&in); err != nil {
+		fmt.Fprintln(os.Stderr, "ERR stdin:", err)
+		os.Exit(1)
+	}
+	for i, c := range in {
+		raw, err := hex.DecodeString(c.Hex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERR %s: %v\n", c.Name, err)
+			os.Exit(1)
+		}
+		v, err := ag.Decode(raw)
+		if err != nil {
+			in[i].Text = "error: " + err.Error()
+			continue
+		}
+		in[i].Text = text(v)
+	}
+	json.NewEncoder(os.Stdout).Encode(in)
+}
+
+func text(v any) string {
+	b, _ := json.Marshal(canon(v))
+	return string(b)
+}
+
+// canon 把 Decode 结果转为规范形式，json.Marshal 按键排序输出
+func canon(v any) any {
+	switch x := v.(type) {
+	case time.Time:
+		return x.UTC().Format("2006-01-02T15:04:05.000Z")
+	case time.Duration:
+		return fmt.Sprint(int64(x))
+	case *big.Int:
+		return x.String()
+	case *big.Float:
+		return x.Text('g', -1)
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			m[k] = canon(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(x))
+		for i, e := range x {
+			s[i] = canon(e)
+		}
+		return s
+	}
+	return v
+}
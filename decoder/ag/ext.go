@@ -0,0 +1,176 @@
+package ag
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+/**
+ * 扩展标量帧：常用但经 JSON 会丢信息的标准库类型
+ *
+ * Time      秒(int64 BE, 8) 纳秒(uint32 BE, 4) UTC 偏移秒(int32 BE, 4) 时区名(其余字节)
+ *           时区名为 IANA 名（Asia/Shanghai）时按名加载，加载失败或偏移不符时为同名固定时区；
+ *           time.Local 写时区缩写（如 CST），因为接收方的 Local 未必相同
+ * Duration  纳秒数，压缩方式同 Int64
+ * BigInt    符号(1 byte，0 非负 / 1 负) 绝对值(big endian)
+ * BigFloat  精度(uint32 BE, 4) 十进制文本（Text('g', -1)，含 ±Inf）
+ *
+ * 指针为 nil 时写 Nil 帧，非 nil 指针即使指向零值也写值帧：对端据此区分"没有值"与零值。
+ */
+
+var (
+	typeOfTime     = reflect.TypeOf(time.Time{})
+	typeOfDuration = reflect.TypeOf(time.Duration(0))
+	typeOfBigInt   = reflect.TypeOf(big.Int{})
+	typeOfBigFloat = reflect.TypeOf(big.Float{})
+)
+
+// is_ext 扩展标量类型（不含指针）
+func is_ext(t reflect.Type) bool {
+	return t == typeOfTime || t == typeOfDuration || t == typeOfBigInt || t == typeOfBigFloat
+}
+
+// encode_ext rv 的类型满足 is_ext
+func encode_ext(rv reflect.Value) ([]byte, error) {
+	switch rv.Type() {
+	case typeOfTime:
+		return encode_ag(ArgumentTypeTime, time_bytes(rv.Interface().(time.Time)))
+	case typeOfDuration:
+		return encode_ag(ArgumentTypeDuration, int_to_byte(rv.Int()))
+	case typeOfBigInt:
+		x := rv.Interface().(big.Int)
+		return encode_ag(ArgumentTypeBigInt, bigint_bytes(&x))
+	}
+	x := rv.Interface().(big.Float)
+	return encode_ag(ArgumentTypeBigFloat, bigfloat_bytes(&x))
+}
+
+// get_ext Decode 扩展标量：time.Time / time.Duration / *big.Int / *big.Float
+func get_ext(t uint8, v []byte) (any, error) {
+	switch t {
+	case ArgumentTypeTime:
+		return bytes_time(v)
+	case ArgumentTypeDuration:
+		return time.Duration(to_int64(v)), nil
+	case ArgumentTypeBigInt:
+		return bytes_bigint(v)
+	}
+	return bytes_bigfloat(v)
+}
+
+// set_ext 把 get_ext 的结果赋给 dst（非指针）：同类型直接赋值，字符串目标取文本，
+// Duration 与能放下的 BigInt 可转为整数，BigFloat 可转为浮点数
+func set_ext(dst reflect.Value, val any) error {
+	src := reflect.Indirect(reflect.ValueOf(val))
+	if src.Type() == dst.Type() {
+		dst.Set(src)
+		return nil
+	}
+	if dst.Kind() == reflect.String {
+		dst.SetString(ext_text(val))
+		return nil
+	}
+	switch x := val.(type) {
+	case time.Duration:
+		return set_value(dst, int64(x))
+	case *big.Int:
+		if x.IsInt64() {
+			return set_value(dst, x.Int64())
+		}
+		if x.IsUint64() {
+			return set_value(dst, x.Uint64())
+		}
+	case *big.Float:
+		if kind_class(dst.Kind()) == 1 {
+			f, _ := x.Float64()
+			return set_value(dst, f)
+		}
+	}
+	return fmt.Errorf("%w: cannot decode %T into %s", ErrAgTypeMismatch, val, dst.Type())
+}
+
+func ext_text(val any) string {
+	switch x := val.(type) {
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case *big.Float:
+		return x.Text('g', -1)
+	}
+	return fmt.Sprint(val)
+}
+
+func time_bytes(t time.Time) []byte {
+	name, offset := t.Zone()
+	if loc := t.Location(); loc != time.Local {
+		name = loc.String()
+	}
+	buf := make([]byte, 16, 16+len(name))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(t.Unix()))
+	binary.BigEndian.PutUint32(buf[8:12], uint32(t.Nanosecond()))
+	binary.BigEndian.PutUint32(buf[12:16], uint32(int32(offset)))
+	return append(buf, name...)
+}
+
+func bytes_time(v []byte) (time.Time, error) {
+	if len(v) < 16 {
+		return time.Time{}, ErrAgLengthMismatch
+	}
+	sec := int64(binary.BigEndian.Uint64(v[0:8]))
+	nsec := int64(binary.BigEndian.Uint32(v[8:12]))
+	offset := int(int32(binary.BigEndian.Uint32(v[12:16])))
+	t := time.Unix(sec, nsec)
+	return t.In(time_location(string(v[16:]), offset, t)), nil
+}
+
+// time_location 按时区名还原 Location，名字无法加载或该时刻的偏移不符时用固定时区
+func time_location(name string, offset int, t time.Time) *time.Location {
+	if offset == 0 && (name == "" || name == "UTC") {
+		return time.UTC
+	}
+	if name != "" && name != "Local" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			if _, off := t.In(loc).Zone(); off == offset {
+				return loc
+			}
+		}
+	}
+	return time.FixedZone(name, offset)
+}
+
+func bigint_bytes(x *big.Int) []byte {
+	sign := byte(0)
+	if x.Sign() < 0 {
+		sign = 1
+	}
+	return append([]byte{sign}, x.Bytes()...)
+}
+
+func bytes_bigint(v []byte) (*big.Int, error) {
+	if len(v) == 0 {
+		return nil, ErrAgLengthMismatch
+	}
+	x := new(big.Int).SetBytes(v[1:])
+	if v[0] == 1 {
+		x.Neg(x)
+	}
+	return x, nil
+}
+
+func bigfloat_bytes(x *big.Float) []byte {
+	buf := binary.BigEndian.AppendUint32(nil, uint32(x.Prec()))
+	return append(buf, x.Text('g', -1)...)
+}
+
+func bytes_bigfloat(v []byte) (*big.Float, error) {
+	if len(v) < 4 {
+		return nil, ErrAgLengthMismatch
+	}
+	x := new(big.Float).SetPrec(uint(binary.BigEndian.Uint32(v[0:4])))
+	if _, ok := x.SetString(string(v[4:])); !ok {
+		return nil, fmt.Errorf("%w: invalid big.Float %q", ErrAgTypeMismatch, v[4:])
+	}
+	return x, nil
+}
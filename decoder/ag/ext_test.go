@@ -0,0 +1,232 @@
+package ag
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type extItem struct {
+	At    time.Time     `json:"at"`
+	TTL   time.Duration `json:"ttl"`
+	N     *big.Int      `json:"n"`
+	F     *big.Float    `json:"f"`
+	Since *time.Time    `json:"since"`
+	Count *int          `json:"count"`
+}
+
+func TestExt_Time(t *testing.T) {
+	cases := []time.Time{
+		{},
+		time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC),
+		time.Date(1969, 12, 31, 23, 59, 59, 1, time.FixedZone("CST", 8*3600)),
+		time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("", -3*3600-1800)),
+	}
+	if loc, err := time.LoadLocation("America/New_York"); err == nil {
+		cases = append(cases, time.Date(2024, 7, 1, 12, 0, 0, 0, loc), time.Date(2024, 1, 1, 12, 0, 0, 0, loc))
+	}
+	for _, in := range cases {
+		raw, err := EncodeExt(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw[2] != ArgumentTypeTime {
+			t.Fatalf("%v: TYPE=%s", in, typeName(raw[2]))
+		}
+		var out time.Time
+		if err := DecodeInto(raw, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !out.Equal(in) || out.Location().String() != in.Location().String() {
+			t.Errorf("got %v (%s), want %v (%s)", out, out.Location(), in, in.Location())
+		}
+		if _, off := out.Zone(); off != func() int { _, o := in.Zone(); return o }() {
+			t.Errorf("%v: offset %d", in, off)
+		}
+	}
+	if got, _ := Decode(mustEncode(t, time.Time{})); got != (time.Time{}) {
+		t.Errorf("zero time = %#v", got)
+	}
+
+	// Local 写时区缩写与偏移
+	local := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	var out time.Time
+	if err := DecodeInto(mustEncode(t, local), &out); err != nil || !out.Equal(local) {
+		t.Fatalf("local: %v, %v", out, err)
+	}
+	name, off := local.Zone()
+	if n, o := out.Zone(); n != name || o != off {
+		t.Errorf("local zone = %s %d, want %s %d", n, o, name, off)
+	}
+}
+
+func TestExt_Numbers(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	for _, in := range []*big.Int{big.NewInt(0), big.NewInt(-1), big.NewInt(1 << 62), huge} {
+		raw := mustEncode(t, in)
+		var out *big.Int
+		if err := DecodeInto(raw, &out); err != nil || out.Cmp(in) != 0 {
+			t.Errorf("big.Int %s: got %v, %v", in, out, err)
+		}
+		if v, _ := Decode(raw); v.(*big.Int).Cmp(in) != 0 {
+			t.Errorf("Decode big.Int %s = %v", in, v)
+		}
+	}
+	// 非指针 big.Int 同样为 BigInt 帧
+	if raw := mustEncode(t, *big.NewInt(7)); raw[2] != ArgumentTypeBigInt {
+		t.Errorf("big.Int value TYPE=%s", typeName(raw[2]))
+	}
+
+	pi, _, _ := big.ParseFloat("3.14159265358979323846264338327950288419716939937510", 10, 200, big.ToNearestEven)
+	inf := new(big.Float).SetInf(true)
+	for _, in := range []*big.Float{pi, big.NewFloat(-0.5), inf} {
+		var out big.Float
+		if err := DecodeInto(mustEncode(t, in), &out); err != nil || out.Cmp(in) != 0 || out.Prec() != in.Prec() {
+			t.Errorf("big.Float %s: got %s (prec %d), %v", in.Text('g', -1), out.Text('g', -1), out.Prec(), err)
+		}
+	}
+
+	for _, d := range []time.Duration{0, -time.Nanosecond, 90*time.Minute + 1, time.Duration(1<<63 - 1)} {
+		raw := mustEncode(t, d)
+		if raw[2] != ArgumentTypeDuration {
+			t.Fatalf("TYPE=%s", typeName(raw[2]))
+		}
+		var out time.Duration
+		if err := DecodeInto(raw, &out); err != nil || out != d {
+			t.Errorf("duration %v: got %v, %v", d, out, err)
+		}
+		if v, _ := Decode(raw); v != d {
+			t.Errorf("Decode duration = %#v", v)
+		}
+	}
+}
+
+func TestExt_Convert(t *testing.T) {
+	var n int64
+	if err := DecodeInto(mustEncode(t, 3*time.Second), &n); err != nil || n != int64(3*time.Second) {
+		t.Errorf("duration->int64: %d, %v", n, err)
+	}
+	var s string
+	if err := DecodeInto(mustEncode(t, 3*time.Second), &s); err != nil || s != "3s" {
+		t.Errorf("duration->string: %q, %v", s, err)
+	}
+	if err := DecodeInto(mustEncode(t, big.NewInt(-42)), &n); err != nil || n != -42 {
+		t.Errorf("bigint->int64: %d, %v", n, err)
+	}
+	huge := new(big.Int).Lsh(big.NewInt(1), 100)
+	if err := DecodeInto(mustEncode(t, huge), &n); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Errorf("huge bigint->int64 err = %v", err)
+	}
+	if err := DecodeInto(mustEncode(t, huge), &s); err != nil || s != huge.String() {
+		t.Errorf("bigint->string: %q, %v", s, err)
+	}
+	var f float64
+	if err := DecodeInto(mustEncode(t, big.NewFloat(1.25)), &f); err != nil || f != 1.25 {
+		t.Errorf("bigfloat->float64: %v, %v", f, err)
+	}
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("CST", 8*3600))
+	if err := DecodeInto(mustEncode(t, at), &s); err != nil || s != "2024-05-06T07:08:09+08:00" {
+		t.Errorf("time->string: %q, %v", s, err)
+	}
+	if err := DecodeInto(mustEncode(t, at), &n); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Errorf("time->int64 err = %v", err)
+	}
+
+	// 旧客户端的文本参数
+	var tt time.Time
+	if err := DecodeInto(mustEncode(t, "2024-05-06T07:08:09+08:00"), &tt); err != nil || !tt.Equal(at) {
+		t.Errorf("string->time: %v, %v", tt, err)
+	}
+	var d time.Duration
+	if err := DecodeInto(mustEncode(t, "1.5s"), &d); err != nil || d != 1500*time.Millisecond {
+		t.Errorf("string->duration: %v, %v", d, err)
+	}
+	var bi big.Int
+	if err := DecodeInto([]byte("123456789012345678901234567890"), &bi); err != nil || bi.String() != "123456789012345678901234567890" {
+		t.Errorf("raw->big.Int: %v, %v", &bi, err)
+	}
+}
+
+// nil 指针与指向零值的指针在线上可区分
+func TestExt_Nullable(t *testing.T) {
+	zero := 0
+	epoch := time.Unix(0, 0).UTC()
+	in := extItem{
+		At:    time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		TTL:   time.Minute,
+		N:     big.NewInt(0),
+		Since: &epoch,
+		Count: &zero,
+	}
+	raw := mustEncode(t, in)
+	var out extItem
+	if err := DecodeInto(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.At.Equal(in.At) || out.TTL != in.TTL || out.N == nil || out.N.Sign() != 0 || out.F != nil {
+		t.Fatalf("got %+v", out)
+	}
+	if out.Since == nil || !out.Since.Equal(epoch) || out.Count == nil || *out.Count != 0 {
+		t.Fatalf("zero pointers lost: %+v", out)
+	}
+
+	// 已有值的字段收到 Nil 帧时置为 nil
+	in.Count, in.N = nil, nil
+	if err := DecodeInto(mustEncode(t, in), &out); err != nil || out.Count != nil || out.N != nil {
+		t.Fatalf("nil pointers: %+v, %v", out, err)
+	}
+
+	// Decoder：Nil 帧为 nil，空值为非 nil 空切片
+	if d, _ := Decoder(mustEncode(t, (*int)(nil))); d != nil {
+		t.Errorf("Decoder(nil) = %#v", d)
+	}
+	if d, _ := Decoder(mustEncode(t, "")); d == nil || len(d) != 0 {
+		t.Errorf(`Decoder("") = %#v`, d)
+	}
+}
+
+// Decoder：Time/BigInt/BigFloat 保留整帧，Duration 与 Int64 一样补齐为 8 字节
+func TestExt_Decoder(t *testing.T) {
+	for _, v := range []any{time.Now(), big.NewInt(1), big.NewFloat(1)} {
+		raw := mustEncode(t, v)
+		if d, err := Decoder(raw); err != nil || !bytes.Equal(d, raw) {
+			t.Errorf("Decoder(%T) = % x, %v", v, d, err)
+		}
+	}
+	d, _ := Decoder(mustEncode(t, time.Duration(-2)))
+	want, _ := Decoder(mustEncode(t, int64(-2)))
+	if !bytes.Equal(d, want) || len(d) != 8 {
+		t.Errorf("Decoder(duration) = % x, want % x", d, want)
+	}
+	// 复合帧转 JSON 时扩展类型按 encoding/json 输出
+	js, _ := Decoder(mustEncode(t, map[string]any{"n": big.NewInt(5), "ttl": time.Second}))
+	if string(js) != `{"n":5,"ttl":1000000000}` {
+		t.Errorf("Decoder(map) = %s", js)
+	}
+}
+
+func TestExt_Errors(t *testing.T) {
+	short, _ := encode_ag(ArgumentTypeTime, make([]byte, 15))
+	if _, err := Decode(short); !errors.Is(err, ErrAgLengthMismatch) {
+		t.Errorf("short time err = %v", err)
+	}
+	empty, _ := encode_ag(ArgumentTypeBigInt, nil)
+	if _, err := Decode(empty); !errors.Is(err, ErrAgLengthMismatch) {
+		t.Errorf("empty bigint err = %v", err)
+	}
+	bad, _ := encode_ag(ArgumentTypeBigFloat, append([]byte{0, 0, 0, 64}, "x1"...))
+	if _, err := Decode(bad); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Errorf("bad bigfloat err = %v", err)
+	}
+}
+
+func mustEncode(t *testing.T, v any) []byte {
+	t.Helper()
+	raw, err := EncodeExt(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
@@ -0,0 +1,178 @@
+package ag
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/w6xian/sloth/v3/internal/utils"
+)
+
+var (
+	ErrAgInvalidTarget = errors.New("ag: decode target must be a non-nil pointer")
+	ErrAgTypeMismatch  = errors.New("ag: type mismatch")
+)
+
+// EncodeTyped 与 Encode 相同，但复合类型保留真实的类型标签：
+//   - 指针先解引用，nil 指针编码为 Nil 帧；
+//   - Slice/Map/Struct 分别以 ArgumentTypeSlice/Map/Struct 标记，payload 为 JSON；
+//   - 其余类型与 Encode 完全一致。
+//
+// 用于 RPC 返回值：调用方据此标签可以把结果准确还原为 Go 值（见 DecodeInto）。
+// 对端协商了 ag_ext 时用 EncodeTypedExt。
+func EncodeTyped(arg any) ([]byte, error) {
+	if arg == nil {
+		return encode_ag(ArgumentTypeNil, nil)
+	}
+	rv := reflect.ValueOf(arg)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return encode_ag(ArgumentTypeNil, nil)
+		}
+		rv = rv.Elem()
+	}
+	arg = rv.Interface()
+	t := typeof(arg)
+	switch t {
+	case ArgumentTypeSlice, ArgumentTypeMap, ArgumentTypeStruct:
+		b, err := json.Marshal(arg)
+		if err != nil {
+			return nil, err
+		}
+		return encode_ag(t, b)
+	}
+	return Encode(arg)
+}
+
+// EncodeTypedExt 即 EncodeExt：嵌套帧本身带类型标签
+func EncodeTypedExt(arg any) ([]byte, error) {
+	return EncodeExt(arg)
+}
+
+// DecodeInto 把一帧 AG 解码到 v 指向的 Go 值中，v 必须是非 nil 指针。
+//
+// 规则：
+//   - Nil 帧把目标置零；
+//   - 标量帧按目标类型做数值转换（溢出按 Go 截断语义），类型不兼容返回 ErrAgTypeMismatch；
+//   - String/Bytes 帧：目标为 string/[]byte 直接赋值，标量目标按文本解析，其余按 JSON 解析；
+//   - Slice 帧解码到 slice/array，Map/Struct 帧解码到 map 或结构体（字段按 json 名匹配），逐层递归；
+//   - Custom 帧以及旧版 JSON 形式的复合帧按 JSON 解析；
+//   - Time/Duration/BigInt/BigFloat 帧解码到同类型，字符串目标取文本，Duration/BigInt/BigFloat 可转为数值；
+//   - 非 AG 帧按 utils.AnyToBytes 的输出格式（文本/原始字节/JSON）兼容解析，
+//     实现 encoding.TextUnmarshaler 的目标（time.Time、big.Int 等）先按文本解析，time.Duration 接受 "1.5s"。
+func DecodeInto(b []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return ErrAgInvalidTarget
+	}
+	if !IsArgument(b) {
+		return decode_raw(b, rv.Elem())
+	}
+	return decode_frame(b, rv.Elem())
+}
+
+// set_value 把标量 val 赋给 dst，必要时做数值类型转换
+func set_value(dst reflect.Value, val any) error {
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return set_value(dst.Elem(), val)
+	}
+	src := reflect.ValueOf(val)
+	if dst.Kind() == reflect.Interface && src.Type().Implements(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+	if dst.Kind() == reflect.String {
+		dst.SetString(utils.MustAnyToStr(val))
+		return nil
+	}
+	if kind_class(src.Kind()) != 0 && kind_class(src.Kind()) == kind_class(dst.Kind()) {
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("%w: cannot decode %s into %s", ErrAgTypeMismatch, src.Type(), dst.Type())
+}
+
+// kind_class 数值类之间允许互相转换：1=整数/浮点，2=复数，3=布尔
+func kind_class(k reflect.Kind) int {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 1
+	case reflect.Complex64, reflect.Complex128:
+		return 2
+	case reflect.Bool:
+		return 3
+	}
+	return 0
+}
+
+// decode_raw 解析无类型标签的 payload（AnyToBytes 输出或 String/Bytes 帧的 Value）
+func decode_raw(data []byte, dst reflect.Value) error {
+	if dst.Type() == typeOfDuration {
+		if d, err := time.ParseDuration(string(data)); err == nil {
+			dst.SetInt(int64(d))
+			return nil
+		}
+	}
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return decode_raw(data, dst.Elem())
+	case reflect.String:
+		dst.SetString(string(data))
+		return nil
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			out := make([]byte, len(data))
+			copy(out, data)
+			dst.SetBytes(out)
+			return nil
+		}
+	case reflect.Bool:
+		b, err := strconv.ParseBool(string(data))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrAgTypeMismatch, err)
+		}
+		dst.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(string(data), 10, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrAgTypeMismatch, err)
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(string(data), 10, dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrAgTypeMismatch, err)
+		}
+		dst.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(data), dst.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrAgTypeMismatch, err)
+		}
+		dst.SetFloat(f)
+		return nil
+	}
+	if len(data) == 0 {
+		dst.SetZero()
+		return nil
+	}
+	if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok && u.UnmarshalText(data) == nil {
+		return nil
+	}
+	return json.Unmarshal(data, dst.Addr().Interface())
+}
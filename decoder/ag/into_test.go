@@ -0,0 +1,191 @@
+package ag
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// ---------- EncodeTyped + DecodeInto 往返 ----------
+
+func TestEncodeTyped_Tags(t *testing.T) {
+	sa := sampleA()
+	var nilPtr *fullA
+	cases := []struct {
+		name string
+		in   any
+		tag  uint8
+	}{
+		{"nil", nil, ArgumentTypeNil},
+		{"nilPtr", nilPtr, ArgumentTypeNil},
+		{"int", 42, ArgumentTypeInt},
+		{"string", "hi", ArgumentTypeString},
+		{"bytes", []byte{1, 2}, ArgumentTypeBytes},
+		{"struct", sa.B, ArgumentTypeStruct},
+		{"structPtr", &sa.B, ArgumentTypeStruct},
+		{"slice", sa.Slice, ArgumentTypeSlice},
+		{"map", sa.Map, ArgumentTypeMap},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			raw, err := EncodeTyped(c.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if raw[2] != c.tag {
+				t.Fatalf("TYPE=%s, want %s", typeName(raw[2]), typeName(c.tag))
+			}
+		})
+	}
+}
+
+func TestDecodeInto_RoundTrip(t *testing.T) {
+	sa := sampleA()
+
+	t.Run("struct", func(t *testing.T) {
+		// complex 字段 JSON 不支持，取其余字段
+		type reply struct {
+			Int64  int64
+			Uint64 uint64
+			String string
+			B      innerB
+			Slice  []int
+			Map    map[string]int
+		}
+		in := reply{sa.Int64, sa.Uint64, sa.String, sa.B, sa.Slice, sa.Map}
+		raw, err := EncodeTyped(&in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out reply
+		if err := DecodeInto(raw, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, in) {
+			t.Fatalf("got %+v, want %+v", out, in)
+		}
+	})
+
+	t.Run("structPtr", func(t *testing.T) {
+		raw, _ := EncodeTyped(sa.B)
+		var out *innerB
+		if err := DecodeInto(raw, &out); err != nil {
+			t.Fatal(err)
+		}
+		if out == nil || out.C != sa.B.C {
+			t.Fatalf("got %+v, want %+v", out, sa.B)
+		}
+	})
+
+	t.Run("slice", func(t *testing.T) {
+		raw, _ := EncodeTyped(sa.Arraya)
+		var out []string
+		if err := DecodeInto(raw, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, sa.Arraya) {
+			t.Fatalf("got %v, want %v", out, sa.Arraya)
+		}
+	})
+
+	t.Run("map", func(t *testing.T) {
+		raw, _ := EncodeTyped(sa.Map)
+		var out map[string]int
+		if err := DecodeInto(raw, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, sa.Map) {
+			t.Fatalf("got %v, want %v", out, sa.Map)
+		}
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		raw, _ := EncodeTyped(sa.Arrayb)
+		var out []byte
+		if err := DecodeInto(raw, &out); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, sa.Arrayb) {
+			t.Fatalf("got % x, want % x", out, sa.Arrayb)
+		}
+	})
+
+	t.Run("scalars", func(t *testing.T) {
+		raw, _ := EncodeTyped(sa.Int64)
+		var i64 int64
+		if err := DecodeInto(raw, &i64); err != nil || i64 != sa.Int64 {
+			t.Fatalf("int64 = %d, %v, want %d", i64, err, sa.Int64)
+		}
+		// 数值类之间允许转换
+		var i int
+		if err := DecodeInto(raw, &i); err != nil || i != int(sa.Int64) {
+			t.Fatalf("int = %d, %v, want %d", i, err, sa.Int64)
+		}
+		raw, _ = EncodeTyped(sa.Float64)
+		var f float64
+		if err := DecodeInto(raw, &f); err != nil || f != sa.Float64 {
+			t.Fatalf("float64 = %v, %v, want %v", f, err, sa.Float64)
+		}
+		raw, _ = EncodeTyped(sa.Bool)
+		var bp *bool
+		if err := DecodeInto(raw, &bp); err != nil || bp == nil || *bp != sa.Bool {
+			t.Fatalf("*bool = %v, %v, want %v", bp, err, sa.Bool)
+		}
+		raw, _ = EncodeTyped(sa.String)
+		var s string
+		if err := DecodeInto(raw, &s); err != nil || s != sa.String {
+			t.Fatalf("string = %q, %v, want %q", s, err, sa.String)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		raw, _ := EncodeTyped(nil)
+		out := &innerB{C: "x"}
+		if err := DecodeInto(raw, &out); err != nil || out != nil {
+			t.Fatalf("got %v, %v, want nil", out, err)
+		}
+	})
+
+	t.Run("any", func(t *testing.T) {
+		raw, _ := EncodeTyped(uint16(7))
+		var out any
+		if err := DecodeInto(raw, &out); err != nil || out != uint16(7) {
+			t.Fatalf("got %#v, %v, want uint16(7)", out, err)
+		}
+	})
+}
+
+// 非 AG 帧：兼容 utils.AnyToBytes 的输出（旧服务端不识别 reply_type）
+func TestDecodeInto_Raw(t *testing.T) {
+	var i int32
+	if err := DecodeInto([]byte("-32"), &i); err != nil || i != -32 {
+		t.Fatalf("int32 = %d, %v, want -32", i, err)
+	}
+	var b bool
+	if err := DecodeInto([]byte("true"), &b); err != nil || !b {
+		t.Fatalf("bool = %v, %v, want true", b, err)
+	}
+	var s string
+	if err := DecodeInto([]byte("plain"), &s); err != nil || s != "plain" {
+		t.Fatalf("string = %q, %v, want plain", s, err)
+	}
+	var m map[string]string
+	if err := DecodeInto([]byte(`{"a":"b"}`), &m); err != nil || m["a"] != "b" {
+		t.Fatalf("map = %v, %v", m, err)
+	}
+}
+
+func TestDecodeInto_Errors(t *testing.T) {
+	raw, _ := EncodeTyped(1)
+	var i int
+	if err := DecodeInto(raw, i); !errors.Is(err, ErrAgInvalidTarget) {
+		t.Fatalf("non-pointer err = %v, want ErrAgInvalidTarget", err)
+	}
+	raw, _ = EncodeTyped(true)
+	if err := DecodeInto(raw, &i); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Fatalf("bool->int err = %v, want ErrAgTypeMismatch", err)
+	}
+	if err := DecodeInto([]byte("abc"), &i); !errors.Is(err, ErrAgTypeMismatch) {
+		t.Fatalf("raw abc->int err = %v, want ErrAgTypeMismatch", err)
+	}
+}
@@ -0,0 +1,115 @@
+package frame
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Compressed 整条消息经 raw deflate（RFC 1951）压缩后再分片，每个分片都带此标记
+const Compressed byte = 0x10
+
+// DefaultCompressThreshold 超过该字节数的消息才尝试压缩
+const DefaultCompressThreshold = 4 << 10
+
+var ErrDecompress = errors.New("frame: decompress failed")
+
+// CompressStats 发送侧压缩决策统计
+type CompressStats struct {
+	// Compressed 压缩后发送的消息数
+	Compressed int64 `json:"compressed"`
+	// Skipped 未达阈值、原样发送的消息数
+	Skipped int64 `json:"skipped"`
+	// Incompressible 压缩后不变小、原样发送的消息数
+	Incompressible int64 `json:"incompressible"`
+	// RawBytes 压缩前的消息字节数
+	RawBytes int64 `json:"raw_bytes"`
+	// WireBytes 实际发送的消息字节数（压缩后或原样）
+	WireBytes int64 `json:"wire_bytes"`
+}
+
+// Compressor 按阈值决定是否压缩消息并记录统计；每个连接一个
+type Compressor struct {
+	threshold int
+	level     int
+	pool      sync.Pool
+
+	compressed     atomic.Int64
+	skipped        atomic.Int64
+	incompressible atomic.Int64
+	rawBytes       atomic.Int64
+	wireBytes      atomic.Int64
+}
+
+// NewCompressor threshold<=0 时从不压缩；level 取 flate.BestSpeed..flate.BestCompression，
+// 非法值按 flate.DefaultCompression
+func NewCompressor(threshold, level int) *Compressor {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.DefaultCompression
+	}
+	c := &Compressor{threshold: threshold, level: level}
+	c.pool.New = func() any {
+		w, _ := flate.NewWriter(nil, c.level)
+		return w
+	}
+	return c
+}
+
+// Compress 超过阈值且压缩后更小时返回压缩数据与 true，否则原样返回 data 与 false
+func (c *Compressor) Compress(data []byte) ([]byte, bool) {
+	c.rawBytes.Add(int64(len(data)))
+	if c.threshold <= 0 || len(data) <= c.threshold {
+		c.skipped.Add(1)
+		c.wireBytes.Add(int64(len(data)))
+		return data, false
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) / 2)
+	w := c.pool.Get().(*flate.Writer)
+	w.Reset(&buf)
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	c.pool.Put(w)
+	if err != nil || buf.Len() >= len(data) {
+		c.incompressible.Add(1)
+		c.wireBytes.Add(int64(len(data)))
+		return data, false
+	}
+	c.compressed.Add(1)
+	c.wireBytes.Add(int64(buf.Len()))
+	return buf.Bytes(), true
+}
+
+func (c *Compressor) Stats() CompressStats {
+	return CompressStats{
+		Compressed:     c.compressed.Load(),
+		Skipped:        c.skipped.Load(),
+		Incompressible: c.incompressible.Load(),
+		RawBytes:       c.rawBytes.Load(),
+		WireBytes:      c.wireBytes.Load(),
+	}
+}
+
+// Decompress 解压 Compressed 消息，结果超过 maxSize（>0 时）返回 ErrMessageTooLarge
+func Decompress(data []byte, maxSize int) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	var src io.Reader = r
+	if maxSize > 0 {
+		src = io.LimitReader(r, int64(maxSize)+1)
+	}
+	out, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecompress, err)
+	}
+	if maxSize > 0 && len(out) > maxSize {
+		return nil, fmt.Errorf("%w: decompressed > %d", ErrMessageTooLarge, maxSize)
+	}
+	return out, nil
+}
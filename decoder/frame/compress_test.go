@@ -0,0 +1,70 @@
+package frame
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// 超过阈值的消息压缩后分片，接收端重组时解压；统计记录每次决策
+func TestCompressor(t *testing.T) {
+	c := NewCompressor(1024, -100)
+	small := []byte("hello")
+	if d, ok := c.Compress(small); ok || !bytes.Equal(d, small) {
+		t.Fatalf("small message compressed")
+	}
+	noise := make([]byte, 4096)
+	for i := range noise {
+		noise[i] = byte(i*2654435761>>13 ^ i)
+	}
+	if _, ok := c.Compress(noise); ok {
+		t.Fatal("incompressible message compressed")
+	}
+	msg := bytes.Repeat([]byte(`{"id":1,"name":"room"},`), 2000)
+	z, ok := c.Compress(msg)
+	if !ok || len(z) >= len(msg)/4 {
+		t.Fatalf("compressed %d -> %d, %v", len(msg), len(z), ok)
+	}
+	st := c.Stats()
+	want := CompressStats{Compressed: 1, Skipped: 1, Incompressible: 1,
+		RawBytes:  int64(len(small) + len(noise) + len(msg)),
+		WireBytes: int64(len(small) + len(noise) + len(z))}
+	if st != want {
+		t.Fatalf("stats %+v, want %+v", st, want)
+	}
+
+	for _, size := range []int{64, 1024} {
+		slices, err := Split("zz", z, size, BinaryMessage, CompressedSlices())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewReassembler()
+		var got []byte
+		for _, s := range slices {
+			d, err := Decode(s.Encode())
+			if err != nil || d.P&Compressed == 0 {
+				t.Fatalf("decode P=%#x %v", d.P, err)
+			}
+			if m, err := r.Push(d); err != nil {
+				t.Fatal(err)
+			} else if m != nil {
+				got = m
+			}
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("reassembled %d bytes", len(got))
+		}
+		if st := r.Stats(); st.Decompressed != 1 || st.Bytes != int64(len(msg)) {
+			t.Fatalf("stats %+v", st)
+		}
+	}
+
+	// 解压结果同样受消息大小限制
+	r := NewReassembler(WithMaxMessageSize(1000))
+	if _, err := r.Push(&DataSlice{P: TextMessage | Compressed, N: "zb", T: 1, D: z}); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("bomb err = %v", err)
+	}
+	if _, err := r.Push(&DataSlice{P: TextMessage | Compressed, N: "zc", T: 1, D: []byte("junk")}); !errors.Is(err, ErrDecompress) {
+		t.Fatalf("junk err = %v", err)
+	}
+}
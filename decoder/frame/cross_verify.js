@@ -3,8 +3,12 @@ const os = require('os');
 const path = require('path');
 const child_process = require('child_process');
 
-const slicePath = path.join(__dirname, 'slice.js');
-const { DataSlice, Encode, Decode, GetCrC, CheckCRC, CRC, TextMessage, BinaryMessage, LongMessage, newOption, CheckCRC: CheckCRCOpt } = require(slicePath);
+// slice.js 依赖 tools.js 的 getCRC（浏览器里为全局函数），先加载打包后的 SDK 提供它；
+// 打包后的 Encode/Decode 是 fn.js 的同名函数，分片编解码直接用 slice.js 导出的
+const webDir = path.join(__dirname, '..', '..', 'examples', 'ws', 'web');
+require(path.join(webDir, 'sloth_rpc_v3.js'));
+globalThis.GetCrC = globalThis.getCRC;
+const { DataSlice, Encode, Decode, GetCrC, CheckCRC, CRC, TextMessage, BinaryMessage, WideSlice } = require(path.join(webDir, 'slice.js'));
 
 function toHex(u8) {
     return Buffer.from(u8.buffer, u8.byteOffset, u8.byteLength).toString('hex');
@@ -40,8 +44,8 @@ for (const c of goJson) {
     const mismatches = [];
     if ((s.P & 0xFF) !== c.p) mismatches.push(`P: js=${s.P} go=${c.p}`);
     if (s.N !== c.n) mismatches.push(`N: js=${JSON.stringify(s.N)} go=${JSON.stringify(c.n)}`);
-    if ((s.T & 0xFF) !== c.t) mismatches.push(`T: js=${s.T} go=${c.t}`);
-    if ((s.I & 0xFF) !== c.i) mismatches.push(`I: js=${s.I} go=${c.i}`);
+    if ((s.T >>> 0) !== c.t) mismatches.push(`T: js=${s.T} go=${c.t}`);
+    if ((s.I >>> 0) !== c.i) mismatches.push(`I: js=${s.I} go=${c.i}`);
     if ((s.S >>> 0) !== (c.s >>> 0)) mismatches.push(`S: js=${s.S} go=${c.s}`);
     if (s.D.length !== c.dLen) mismatches.push(`D.len: js=${s.D.length} go=${c.dLen}`);
     const dh = toHex(s.D.subarray(0, Math.min(8, s.D.length)));
@@ -135,6 +139,9 @@ const jsCases = [
     { name: 'js_longNamePrefix', p: TextMessage, n: 'xyz999', t: 10, i: 5, s: 2, d: new Uint8Array([0xDE, 0xAD]) },
     { name: 'js_shortBoundary_65535', p: BinaryMessage, n: 'AB', t: 1, i: 0, s: 0xFFFF, d: new Uint8Array(0xFFFF) },
     { name: 'js_longMessage_65536', p: BinaryMessage, n: 'AB', t: 1, i: 0, s: 0x10000, d: new Uint8Array(0x10000) },
+    { name: 'js_wide_auto', p: BinaryMessage, n: 'wd', t: 300, i: 299, s: 3, d: 'abc' },
+    { name: 'js_wide_flag_CRC', p: BinaryMessage | WideSlice, n: 'wf', t: 2, i: 1, s: 3, d: 'abc', crcOpt: true },
+    { name: 'js_wide_long', p: BinaryMessage, n: 'wl', t: 70000, i: 65536, s: 0x10000, d: new Uint8Array(0x10000) },
 ];
 
 for (const c of jsCases) {
@@ -171,8 +178,8 @@ for (const c of jsCases) {
     const mismatches = [];
     if ((selfDec.P & 0xFF) !== +pStr) mismatches.push(`P: js=${selfDec.P} go=${pStr}`);
     if (selfDec.N !== nStr) mismatches.push(`N: js=${JSON.stringify(selfDec.N)} go=${JSON.stringify(nStr)}`);
-    if ((selfDec.T & 0xFF) !== +tStr) mismatches.push(`T: js=${selfDec.T} go=${tStr}`);
-    if ((selfDec.I & 0xFF) !== +iStr) mismatches.push(`I: js=${selfDec.I} go=${iStr}`);
+    if ((selfDec.T >>> 0) !== +tStr) mismatches.push(`T: js=${selfDec.T} go=${tStr}`);
+    if ((selfDec.I >>> 0) !== +iStr) mismatches.push(`I: js=${selfDec.I} go=${iStr}`);
     if ((selfDec.S >>> 0) !== +sStr) mismatches.push(`S: js=${selfDec.S} go=${sStr}`);
     if (selfDec.D.length !== +dlenStr) mismatches.push(`DLEN: js=${selfDec.D.length} go=${dlenStr}`);
     const jdh = toHex(selfDec.D.subarray(0, Math.min(8, selfDec.D.length)));
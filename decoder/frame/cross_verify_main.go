@@ -26,6 +26,9 @@ func main() {
 		{"longNamePrefix_noCRC", &frame.DataSlice{P: frame.TextMessage, N: "xyz999", T: 10, I: 5, S: 2, D: []byte{0xDE, 0xAD}}, nil},
 		{"shortBoundary_65535", &frame.DataSlice{P: frame.BinaryMessage, N: "AB", T: 1, I: 0, S: 0xFFFF, D: make([]byte, 0xFFFF)}, nil},
 		{"longMessage_65536_noCRC", &frame.DataSlice{P: frame.BinaryMessage, N: "AB", T: 1, I: 0, S: 0x10000, D: make([]byte, 0x10000)}, nil},
+		{"wide_auto", &frame.DataSlice{P: frame.BinaryMessage, N: "wd", T: 300, I: 299, S: 3, D: []byte("abc")}, nil},
+		{"wide_flag_CRC", &frame.DataSlice{P: frame.BinaryMessage | frame.WideSlice, N: "wf", T: 2, I: 1, S: 3, D: []byte("abc")}, []frame.FrameOption{frame.CheckCRC()}},
+		{"wide_long", &frame.DataSlice{P: frame.BinaryMessage, N: "wl", T: 70000, I: 65536, S: 0x10000, D: make([]byte, 0x10000)}, nil},
 	}
 	fmt.Fprintln(os.Stderr, "===== Go Encode -> JSON cases =====")
 	fmt.Print("[")
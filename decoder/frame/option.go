@@ -8,9 +8,25 @@ func CheckCRC() FrameOption {
 	}
 }
 
+// NarrowSlices 对端不支持 WideSlice 时使用：分片数超过 255 返回 ErrTooManySlices
+func NarrowSlices() FrameOption {
+	return func(opt *Option) {
+		opt.Narrow = true
+	}
+}
+
+// CompressedSlices 消息已经 Compressor 压缩，分片带 Compressed 标记，接收端重组后解压
+func CompressedSlices() FrameOption {
+	return func(opt *Option) {
+		opt.Compressed = true
+	}
+}
+
 type Option struct {
 	CheckCRC   bool
 	LengthSize byte
+	Narrow     bool
+	Compressed bool
 }
 
 func newOption(opts ...FrameOption) Option {
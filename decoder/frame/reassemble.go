@@ -0,0 +1,229 @@
+package frame
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSliceInvalid    = errors.New("frame: invalid slice")
+	ErrMessageTooLarge = errors.New("frame: message too large")
+)
+
+const (
+	DefaultMaxMessageSize = 16 << 20
+	DefaultMaxPending     = 64
+	DefaultMessageTimeout = 30 * time.Second
+)
+
+// ReassembleStats 重组统计
+type ReassembleStats struct {
+	// Messages 重组完成的消息数（含单片消息）
+	Messages int64 `json:"messages"`
+	// Slices 收到的分片数
+	Slices int64 `json:"slices"`
+	// Bytes 重组完成的消息字节数（已压缩的按解压后计）
+	Bytes int64 `json:"bytes"`
+	// Pending 当前未收齐的消息数
+	Pending int `json:"pending"`
+	// PendingBytes 未收齐消息已缓存的字节数
+	PendingBytes int64 `json:"pending_bytes"`
+	// Duplicates 重复的分片（已丢弃）
+	Duplicates int64 `json:"duplicates"`
+	// Expired 超时被淘汰的未完成消息
+	Expired int64 `json:"expired"`
+	// Dropped 因超限或分片不一致被丢弃的消息
+	Dropped int64 `json:"dropped"`
+	// Decompressed 带 Compressed 标记、重组后解压的消息数
+	Decompressed int64 `json:"decompressed"`
+}
+
+type ReassembleOption func(r *Reassembler)
+
+// WithMaxMessageSize 单条消息重组后的最大字节数，<=0 不限制
+func WithMaxMessageSize(n int) ReassembleOption {
+	return func(r *Reassembler) {
+		r.maxSize = n
+	}
+}
+
+// WithMaxPending 同时未收齐的消息数上限，超出时淘汰最早的一条
+func WithMaxPending(n int) ReassembleOption {
+	return func(r *Reassembler) {
+		r.maxPending = n
+	}
+}
+
+// WithMessageTimeout 一条消息从首片到收齐的最长时间，<=0 不过期
+func WithMessageTimeout(d time.Duration) ReassembleOption {
+	return func(r *Reassembler) {
+		r.timeout = d
+	}
+}
+
+type partial struct {
+	total   int
+	chunks  map[int][]byte
+	size    int
+	created time.Time
+}
+
+// Reassembler 按片名重组分片，不同消息的分片可以交错到达；每个连接一个
+type Reassembler struct {
+	mu         sync.Mutex
+	maxSize    int
+	maxPending int
+	timeout    time.Duration
+	pending    map[string]*partial
+	stats      ReassembleStats
+	now        func() time.Time
+}
+
+func NewReassembler(opts ...ReassembleOption) *Reassembler {
+	r := &Reassembler{
+		maxSize:    DefaultMaxMessageSize,
+		maxPending: DefaultMaxPending,
+		timeout:    DefaultMessageTimeout,
+		pending:    make(map[string]*partial),
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Push 收入一个分片，消息收齐时返回完整数据（空消息为非 nil 空切片），否则返回 nil；
+// 出错时该片名下已缓存的分片一并丢弃
+func (r *Reassembler) Push(s *DataSlice) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.stats.Slices++
+	r.expire(now)
+
+	total, idx := int(s.T), int(s.I)
+	if total <= 1 {
+		if idx > 0 {
+			return nil, fmt.Errorf("%w: index %d of %d", ErrSliceInvalid, idx, total)
+		}
+		if r.maxSize > 0 && len(s.D) > r.maxSize {
+			r.stats.Dropped++
+			return nil, fmt.Errorf("%w: %d > %d", ErrMessageTooLarge, len(s.D), r.maxSize)
+		}
+		if s.D == nil {
+			s.D = []byte{}
+		}
+		return r.complete(s.P, s.D)
+	}
+	if idx >= total {
+		r.drop(s.N)
+		return nil, fmt.Errorf("%w: index %d of %d", ErrSliceInvalid, idx, total)
+	}
+
+	p, ok := r.pending[s.N]
+	if !ok {
+		if r.maxSize > 0 && int64(s.S) > int64(r.maxSize) {
+			r.stats.Dropped++
+			return nil, fmt.Errorf("%w: %d > %d", ErrMessageTooLarge, s.S, r.maxSize)
+		}
+		if r.maxPending > 0 && len(r.pending) >= r.maxPending {
+			r.evict_oldest()
+		}
+		// 分片数来自对端，按实际收到的分片分配
+		p = &partial{total: total, chunks: make(map[int][]byte), created: now}
+		r.pending[s.N] = p
+	} else if p.total != total {
+		r.drop(s.N)
+		return nil, fmt.Errorf("%w: total %d, want %d", ErrSliceInvalid, total, p.total)
+	}
+	if _, dup := p.chunks[idx]; dup {
+		r.stats.Duplicates++
+		return nil, nil
+	}
+	if r.maxSize > 0 && p.size+len(s.D) > r.maxSize {
+		r.drop(s.N)
+		return nil, fmt.Errorf("%w: %d > %d", ErrMessageTooLarge, p.size+len(s.D), r.maxSize)
+	}
+	p.chunks[idx] = append([]byte{}, s.D...)
+	p.size += len(s.D)
+	r.stats.PendingBytes += int64(len(s.D))
+	if len(p.chunks) < p.total {
+		return nil, nil
+	}
+
+	delete(r.pending, s.N)
+	r.stats.PendingBytes -= int64(p.size)
+	data := make([]byte, 0, p.size)
+	for i := 0; i < p.total; i++ {
+		data = append(data, p.chunks[i]...)
+	}
+	return r.complete(s.P, data)
+}
+
+// complete 统计收齐的消息，带 Compressed 标记时解压（解压结果同样受 maxSize 限制）
+func (r *Reassembler) complete(flags byte, data []byte) ([]byte, error) {
+	if flags&Compressed != 0 {
+		out, err := Decompress(data, r.maxSize)
+		if err != nil {
+			r.stats.Dropped++
+			return nil, err
+		}
+		r.stats.Decompressed++
+		data = out
+	}
+	r.stats.Messages++
+	r.stats.Bytes += int64(len(data))
+	return data, nil
+}
+
+// Reset 丢弃全部未完成消息（连接断开时调用）
+func (r *Reassembler) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n := range r.pending {
+		r.drop(n)
+	}
+}
+
+func (r *Reassembler) Stats() ReassembleStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.stats
+	st.Pending = len(r.pending)
+	return st
+}
+
+func (r *Reassembler) expire(now time.Time) {
+	if r.timeout <= 0 {
+		return
+	}
+	for n, p := range r.pending {
+		if now.Sub(p.created) > r.timeout {
+			r.stats.PendingBytes -= int64(p.size)
+			r.stats.Expired++
+			delete(r.pending, n)
+		}
+	}
+}
+
+func (r *Reassembler) evict_oldest() {
+	var oldest *partial
+	name := ""
+	for n, p := range r.pending {
+		if oldest == nil || p.created.Before(oldest.created) {
+			oldest, name = p, n
+		}
+	}
+	r.drop(name)
+}
+
+func (r *Reassembler) drop(n string) {
+	if p, ok := r.pending[n]; ok {
+		r.stats.PendingBytes -= int64(p.size)
+		r.stats.Dropped++
+		delete(r.pending, n)
+	}
+}
@@ -0,0 +1,110 @@
+package frame
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustSplit(t *testing.T, n string, data []byte) []*DataSlice {
+	t.Helper()
+	s, err := Split(n, data, 1024, TextMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// 两条消息的分片交错到达，各自按片名重组
+func TestReassembler_Interleaved(t *testing.T) {
+	a := bytes.Repeat([]byte("a"), 3000)
+	b := bytes.Repeat([]byte("b"), 2100)
+	sa, sb := mustSplit(t, "0a", a), mustSplit(t, "0b", b)
+	r := NewReassembler()
+	order := []*DataSlice{sa[0], sb[1], sa[2], sb[0], sa[1], sb[2]}
+	var got [][]byte
+	for _, s := range order {
+		m, err := r.Push(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != nil {
+			got = append(got, m)
+		}
+	}
+	if len(got) != 2 || !bytes.Equal(got[0], a) || !bytes.Equal(got[1], b) {
+		t.Fatalf("got %d messages", len(got))
+	}
+	st := r.Stats()
+	if st.Messages != 2 || st.Slices != 6 || st.Pending != 0 || st.PendingBytes != 0 || st.Bytes != int64(len(a)+len(b)) {
+		t.Fatalf("stats %+v", st)
+	}
+
+	// 单片消息直接返回，空消息为非 nil
+	if m, err := r.Push(&DataSlice{N: "0c", T: 1}); err != nil || m == nil || len(m) != 0 {
+		t.Fatalf("empty: %#v, %v", m, err)
+	}
+	// 重复分片丢弃
+	r.Push(sa[0])
+	if m, _ := r.Push(sa[0]); m != nil || r.Stats().Duplicates != 1 {
+		t.Fatalf("duplicate: %v %+v", m, r.Stats())
+	}
+}
+
+func TestReassembler_Limits(t *testing.T) {
+	s := mustSplit(t, "xx", make([]byte, 4000))
+	r := NewReassembler(WithMaxMessageSize(3000))
+	if _, err := r.Push(s[0]); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("declared size err = %v", err)
+	}
+	// 声明的大小不可信时按累计字节判断
+	s[0].S, s[1].S, s[2].S = 100, 100, 100
+	r.Push(s[0])
+	r.Push(s[1])
+	if _, err := r.Push(s[2]); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("accumulated size err = %v", err)
+	}
+	if st := r.Stats(); st.Pending != 0 || st.PendingBytes != 0 || st.Dropped != 2 {
+		t.Fatalf("stats %+v", st)
+	}
+
+	if _, err := r.Push(&DataSlice{N: "yy", T: 2, I: 2}); !errors.Is(err, ErrSliceInvalid) {
+		t.Fatalf("index err = %v", err)
+	}
+	r.Push(&DataSlice{N: "zz", T: 2, I: 0, D: []byte{1}})
+	if _, err := r.Push(&DataSlice{N: "zz", T: 3, I: 1, D: []byte{2}}); !errors.Is(err, ErrSliceInvalid) {
+		t.Fatalf("total err = %v", err)
+	}
+
+	// 未完成消息数超限淘汰最早的一条
+	r = NewReassembler(WithMaxPending(2))
+	for _, n := range []string{"p1", "p2", "p3"} {
+		r.Push(&DataSlice{N: n, T: 2, D: []byte(n)})
+	}
+	if m, _ := r.Push(&DataSlice{N: "p1", T: 2, I: 1}); m != nil {
+		t.Fatalf("evicted message completed: %s", m)
+	}
+	if m, _ := r.Push(&DataSlice{N: "p3", T: 2, I: 1, D: []byte("!")}); string(m) != "p3!" {
+		t.Fatalf("p3 = %q", m)
+	}
+}
+
+func TestReassembler_Expire(t *testing.T) {
+	now := time.Unix(0, 0)
+	r := NewReassembler(WithMessageTimeout(time.Second))
+	r.now = func() time.Time { return now }
+	r.Push(&DataSlice{N: "aa", T: 2, D: []byte("x")})
+	now = now.Add(2 * time.Second)
+	if m, _ := r.Push(&DataSlice{N: "aa", T: 2, I: 1, D: []byte("y")}); m != nil {
+		t.Fatalf("stale message completed: %s", m)
+	}
+	st := r.Stats()
+	if st.Expired != 1 || st.Pending != 1 || st.PendingBytes != 1 {
+		t.Fatalf("stats %+v", st)
+	}
+	r.Reset()
+	if st := r.Stats(); st.Pending != 0 || st.PendingBytes != 0 {
+		t.Fatalf("reset stats %+v", st)
+	}
+}
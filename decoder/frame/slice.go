@@ -3,6 +3,7 @@ package frame
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/w6xian/sloth/v3/internal/utils"
@@ -13,15 +14,23 @@ const BinaryMessage byte = 0x02
 const LongMessage byte = 0x80
 const CRC byte = 0x40
 
+// WideSlice 分片总数与索引各占 4 字节（默认各 1 字节，最多 255 片）
+const WideSlice byte = 0x20
+
+// MaxNarrowSlices 未设置 WideSlice 时的最大分片数
+const MaxNarrowSlices = 0xFF
+
+var ErrTooManySlices = errors.New("frame: too many slices")
+
 type DataSlice struct {
-	// P 消息类型 6位有效 高2位为扩展位 0x80 2字节长度 0x40 校验crc
+	// P 消息类型 低5位有效 0x80 4字节长度 0x40 校验crc 0x20 4字节分片数/索引 0x10 消息已压缩
 	P byte `json:"p"`
 	// Name 分片名称（用于标识是哪个信息）
 	N string `json:"n"` // len 2
 	// Total 分片总数
-	T byte `json:"t"`
+	T uint32 `json:"t"`
 	// Index 当前分片索引
-	I byte `json:"i"`
+	I uint32 `json:"i"`
 	// Size 消息体总大小
 	S uint32 `json:"s"`
 	// Data 分片数据
@@ -36,29 +45,42 @@ func (s *DataSlice) MuskCheck() byte {
 	return s.P & 0x40
 }
 
+// IsWide 是否需要 4 字节的分片数/索引
+func (s *DataSlice) IsWide() bool {
+	return s.P&WideSlice != 0 || s.T > MaxNarrowSlices || s.I > MaxNarrowSlices
+}
+
 func (s *DataSlice) Encode() []byte {
 	return Encode(s)
 }
 
-func get_header_size(lLen byte, checkCRC bool) byte {
+func get_header_size(lLen byte, checkCRC bool, wide bool) byte {
 	c := byte(0x02)
 	if !checkCRC {
 		c = 0
 	}
-	return lLen + 1 + 2 + 1 + 1 + c
+	n := byte(1)
+	if wide {
+		n = 4
+	}
+	return lLen + 1 + 2 + n + n + c
 }
 
 func Encode(s *DataSlice, opts ...FrameOption) []byte {
 	opt := newOption(opts...)
 	// 1byte type
 	// 2byte name
-	// 1byte slices
-	// 1byte index
+	// 1/4byte slices
+	// 1/4byte index
 	// 2/4byte size
 	// 0/2byte crc
 	// nbyte data
 	tag := s.P & 0x3F
 	checkCRC := s.P&CRC == CRC
+	wide := s.IsWide()
+	if wide {
+		tag |= WideSlice
+	}
 	l := len(s.D)
 	// 根据长度大小判断是否需要扩展tag
 	if l <= 0xFFFF {
@@ -72,8 +94,8 @@ func Encode(s *DataSlice, opts ...FrameOption) []byte {
 		tag |= CRC
 	}
 	// 根据长度大小判断是否需要扩展tag
-	// 1+2+1+1+2/4+[2]+len(s.D)
-	headerSize := get_header_size(opt.LengthSize, checkCRC)
+	// 1+2+1/4+1/4+2/4+[2]+len(s.D)
+	headerSize := get_header_size(opt.LengthSize, checkCRC, wide)
 
 	buf := make([]byte, int(headerSize)+len(s.D))
 	buf[0] = tag
@@ -92,21 +114,24 @@ func Encode(s *DataSlice, opts ...FrameOption) []byte {
 	}
 	buf[1] = name[0]
 	buf[2] = name[1]
-	buf[3] = byte(s.T)
-	buf[4] = byte(s.I)
-	lb := make([]byte, opt.LengthSize)
+	off := 5
+	if wide {
+		binary.BigEndian.PutUint32(buf[3:7], s.T)
+		binary.BigEndian.PutUint32(buf[7:11], s.I)
+		off = 11
+	} else {
+		buf[3] = byte(s.T)
+		buf[4] = byte(s.I)
+	}
 	if opt.LengthSize == 2 {
-		binary.BigEndian.PutUint16(lb, uint16(len(s.D)))
+		binary.BigEndian.PutUint16(buf[off:], uint16(len(s.D)))
 	} else {
-		binary.BigEndian.PutUint32(lb, uint32(len(s.D)))
+		binary.BigEndian.PutUint32(buf[off:], uint32(len(s.D)))
 	}
 	if checkCRC {
-		copy(buf[5:headerSize-2], lb)
 		crc := utils.GetCrC(s.D)
 		buf[headerSize-2] = crc[0]
 		buf[headerSize-1] = crc[1]
-	} else {
-		copy(buf[5:headerSize], lb)
 	}
 	copy(buf[headerSize:], s.D)
 	return buf
@@ -114,8 +139,7 @@ func Encode(s *DataSlice, opts ...FrameOption) []byte {
 
 // DecodeSlice 从二进制数据中解码分片
 func Decode(b []byte) (*DataSlice, error) {
-	headerSize := get_header_size(2, false)
-	if len(b) < int(headerSize) {
+	if len(b) < int(get_header_size(2, false, false)) {
 		return nil, fmt.Errorf("invalid slice data length")
 	}
 	tag := b[0]
@@ -128,20 +152,30 @@ func Decode(b []byte) (*DataSlice, error) {
 	if tag&CRC == CRC {
 		opt.CheckCRC = true
 	}
-
-	l := uint32(binary.BigEndian.Uint16(b[5:7]))
-	if opt.LengthSize == 4 {
-		l = binary.BigEndian.Uint32(b[5:9])
-	}
-	headerSize = get_header_size(opt.LengthSize, opt.CheckCRC)
-	if len(b) < int(headerSize)+int(l) {
+	wide := tag&WideSlice != 0
+	headerSize := get_header_size(opt.LengthSize, opt.CheckCRC, wide)
+	if len(b) < int(headerSize) {
 		return nil, fmt.Errorf("invalid slice data length")
 	}
 	s := &DataSlice{
 		P: tag,
 		N: string(b[1:3]),
-		T: b[3],
-		I: b[4],
+	}
+	off := 5
+	if wide {
+		s.T = binary.BigEndian.Uint32(b[3:7])
+		s.I = binary.BigEndian.Uint32(b[7:11])
+		off = 11
+	} else {
+		s.T = uint32(b[3])
+		s.I = uint32(b[4])
+	}
+	l := uint32(binary.BigEndian.Uint16(b[off:]))
+	if opt.LengthSize == 4 {
+		l = binary.BigEndian.Uint32(b[off:])
+	}
+	if uint64(len(b)) < uint64(headerSize)+uint64(l) {
+		return nil, fmt.Errorf("invalid slice data length")
 	}
 	s.S = l
 
@@ -0,0 +1,81 @@
+package frame
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// 超过 255 片：WideSlice 帧，二进制与 JSON 两种形式都能还原
+func TestSplit_Wide(t *testing.T) {
+	msg := make([]byte, 300*1024+7)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	slices, err := Split("wd", msg, 512, BinaryMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slices) != 301 || slices[300].T != 301 || slices[300].I != 300 {
+		t.Fatalf("%d slices, last T=%d I=%d", len(slices), slices[300].T, slices[300].I)
+	}
+	for _, mt := range []byte{BinaryMessage, TextMessage} {
+		r := NewReassembler()
+		var got []byte
+		for _, s := range slices {
+			raw := s.Encode()
+			if mt == TextMessage {
+				raw = s.Bytes()
+			}
+			if mt == BinaryMessage && raw[0]&WideSlice == 0 {
+				t.Fatalf("tag %#x without WideSlice", raw[0])
+			}
+			d, err := FromType(raw, mt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.T != s.T || d.I != s.I || d.P&WideSlice == 0 {
+				t.Fatalf("decoded P=%#x T=%d I=%d", d.P, d.T, d.I)
+			}
+			if m, err := r.Push(d); err != nil {
+				t.Fatal(err)
+			} else if m != nil {
+				got = m
+			}
+		}
+		if !bytes.Equal(got, msg) {
+			t.Fatalf("type %d: reassembled %d bytes", mt, len(got))
+		}
+	}
+
+	if _, err := Split("nw", msg, 1024, BinaryMessage, NarrowSlices()); !errors.Is(err, ErrTooManySlices) {
+		t.Fatalf("narrow err = %v", err)
+	}
+}
+
+// 不超过 255 片时与旧版格式逐字节一致；大分片使用 4 字节长度
+func TestEncode_Narrow(t *testing.T) {
+	s := &DataSlice{P: BinaryMessage, N: "ab", T: 3, I: 1, D: []byte("hi")}
+	want := []byte{BinaryMessage, 'a', 'b', 3, 1, 0, 2, 'h', 'i'}
+	if raw := s.Encode(); !bytes.Equal(raw, want) {
+		t.Fatalf("got % x, want % x", raw, want)
+	}
+
+	slices, err := Split("lg", make([]byte, 200000), 100000, BinaryMessage, NarrowSlices())
+	if err != nil || len(slices) != 2 {
+		t.Fatalf("%d slices, %v", len(slices), err)
+	}
+	raw := slices[0].Encode()
+	if raw[0]&LongMessage == 0 || raw[0]&WideSlice != 0 {
+		t.Fatalf("tag %#x", raw[0])
+	}
+	d, err := Decode(raw)
+	if err != nil || len(d.D) != 100000 {
+		t.Fatalf("decode: %v", err)
+	}
+
+	// 截断的 WideSlice 头
+	if _, err := Decode([]byte{BinaryMessage | WideSlice, 'a', 'b', 0, 0, 1, 0, 0}); err == nil {
+		t.Fatal("truncated wide header accepted")
+	}
+}
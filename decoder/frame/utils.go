@@ -3,21 +3,34 @@ package frame
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 )
 
-// getSliceArray 将数据按指定大小分片(1024-65535)
-func Split(n string, message []byte, sliceSize int, messageType byte) ([]*DataSlice, error) {
+// Split 将数据按指定大小分片(不小于1024)，超过 255 片时使用 WideSlice 帧
+func Split(n string, message []byte, sliceSize int, messageType byte, opts ...FrameOption) ([]*DataSlice, error) {
+	opt := newOption(opts...)
 	// 这里可能有汉字
 	// msg := []rune(string(message))
 	msg := message
 	totalSize := len(msg)
+	if uint64(totalSize) > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: message size %d exceeds 4 GiB", ErrTooManySlices, totalSize)
+	}
 	sliceSize = max(sliceSize, 1024)
-	sliceSize = min(sliceSize, 0xFFFF)
 	totalSlice := totalSize / sliceSize
 	if totalSize%sliceSize != 0 {
 		totalSlice++
 	}
-	// 转换为字符串，判断真实长度
+	if opt.Narrow && totalSlice > MaxNarrowSlices {
+		return nil, fmt.Errorf("%w: %d > %d", ErrTooManySlices, totalSlice, MaxNarrowSlices)
+	}
+	p := messageType
+	if totalSlice > MaxNarrowSlices {
+		p |= WideSlice
+	}
+	if opt.Compressed {
+		p |= Compressed
+	}
 
 	slices := make([]*DataSlice, 0, totalSlice)
 	for i := 0; i < totalSlice; i++ {
@@ -26,10 +39,10 @@ func Split(n string, message []byte, sliceSize int, messageType byte) ([]*DataSl
 		end = min(end, totalSize)
 
 		slices = append(slices, &DataSlice{
-			P: byte(messageType),
+			P: p,
 			N: n,
-			T: byte(totalSlice),
-			I: byte(i),
+			T: uint32(totalSlice),
+			I: uint32(i),
 			S: uint32(totalSize),
 			D: msg[start:end],
 		})
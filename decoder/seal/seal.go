@@ -0,0 +1,231 @@
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/w6xian/sloth/v3/decoder/fn"
+)
+
+/**
+ * fn 帧体端到端加密
+ *
+ * 握手：双方各生成一对 X25519 密钥，公钥经 WebSocket 握手头交换（见 wsocket.KeyHeader），
+ * 共享密钥 || 预共享密钥（PSK，可为空）经 HKDF-SHA256（salt = 客户端公钥 || 服务端公钥）导出两把 AES-256 密钥，
+ * 客户端→服务端与服务端→客户端各用一把，另导出一个可公开的确认值（Confirm）供对端核对。
+ *
+ * 公钥交换本身不带认证：不配置 PSK 时只能防被动窃听，握手头经过的代理可以替换双方公钥做中间人。
+ * 配置 PSK 后，不知道 PSK 的中间人导出的密钥与两端都对不上，确认值核对失败（ErrKeyMismatch），帧也无法解密。
+ *
+ * 帧：fn 帧头（Magic/Action/ID/Length）保持明文，Data 换成 序号 || AES-GCM 密文（含 16 字节 tag），
+ * Length 随之改为新的 Data 长度。
+ *
+ *  SEQ     8 byte   每个方向从 1 递增的帧序号（big endian），由 Session 分配，与 Action/ID 无关
+ *  NONCE  12 byte   0x00(4) SEQ(8)，同一把密钥下永不重复
+ *  AAD    11 byte   fn 帧头前 11 字节（Magic/Action/ID），篡改 Action/ID 即解密失败
+ *
+ * 接收侧按 SEQ 维护滑动窗口（最高序号 + ReplayWindow 位图）：窗口内重复的、
+ * 以及落在窗口之下的帧一律返回 ErrReplay，窗口内的乱序帧照常接受。
+ */
+
+const (
+	// KeySize X25519 公钥字节数
+	KeySize = 32
+	// ReplayWindow 接收窗口宽度（帧数），比最高序号小这么多以上的帧直接拒绝
+	ReplayWindow = 4096
+
+	aadSize = 11
+	seqSize = 8
+)
+
+var (
+	ErrBadKey = errors.New("seal: bad peer key")
+	ErrOpen   = errors.New("seal: message authentication failed")
+	ErrReplay = errors.New("seal: replayed frame")
+	// ErrKeyMismatch 双方导出的密钥不同：PSK 不一致，或公钥被中间人替换
+	ErrKeyMismatch = errors.New("seal: session key mismatch")
+)
+
+// GenerateKey 生成一次性的 X25519 密钥，每个连接一个
+func GenerateKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// Session 一个连接上的加解密状态，并发安全
+type Session struct {
+	send cipher.AEAD
+	recv cipher.AEAD
+	seq  atomic.Uint64
+	seen window
+
+	confirm []byte
+}
+
+// NewSession 由本端私钥与对端公钥导出会话；client 标明本端是否为发起握手的客户端，
+// psk 为双方预先共享的密钥，为空时不认证对端公钥
+func NewSession(priv *ecdh.PrivateKey, peer []byte, client bool, psk []byte) (*Session, error) {
+	pub, err := ecdh.X25519().NewPublicKey(peer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadKey, err)
+	}
+	secret, err := priv.ECDH(pub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadKey, err)
+	}
+	ikm := append(secret, psk...)
+	salt := make([]byte, 0, 2*KeySize)
+	if client {
+		salt = append(append(salt, priv.PublicKey().Bytes()...), peer...)
+	} else {
+		salt = append(append(salt, peer...), priv.PublicKey().Bytes()...)
+	}
+	c2s, err := new_aead(ikm, salt, "sloth v3 c2s")
+	if err != nil {
+		return nil, err
+	}
+	s2c, err := new_aead(ikm, salt, "sloth v3 s2c")
+	if err != nil {
+		return nil, err
+	}
+	confirm, err := hkdf.Key(sha256.New, ikm, salt, "sloth v3 confirm", 32)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{send: c2s, recv: s2c, confirm: confirm}
+	if !client {
+		s.send, s.recv = s2c, c2s
+	}
+	return s, nil
+}
+
+// Confirm 密钥确认值，双方密钥相同时相等；由会话密钥单向导出，可以明文发给对端
+func (s *Session) Confirm() []byte {
+	return s.confirm
+}
+
+// Verify 核对对端发来的确认值
+func (s *Session) Verify(confirm []byte) error {
+	if !hmac.Equal(s.confirm, confirm) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// Seal 把 fn 帧的 Data 换成 序号 || 密文
+func (s *Session) Seal(frame []byte) ([]byte, error) {
+	_, _, data, err := fn.Decode(frame)
+	if err != nil {
+		return nil, err
+	}
+	seq := s.seq.Add(1)
+	out := make([]byte, fn.FnHeaderSize+seqSize, fn.FnHeaderSize+seqSize+len(data)+s.send.Overhead())
+	copy(out, frame[:aadSize])
+	binary.BigEndian.PutUint64(out[fn.FnHeaderSize:], seq)
+	out = s.send.Seal(out, nonce(seq), data, frame[:aadSize])
+	binary.BigEndian.PutUint32(out[aadSize:fn.FnHeaderSize], uint32(len(out)-fn.FnHeaderSize))
+	return out, nil
+}
+
+// Open 解密 Seal 产生的 fn 帧，返回明文 fn 帧
+func (s *Session) Open(frame []byte) ([]byte, error) {
+	action, id, data, err := fn.Decode(frame)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < seqSize {
+		return nil, ErrOpen
+	}
+	seq := binary.BigEndian.Uint64(data)
+	if !s.seen.check(seq) {
+		return nil, fmt.Errorf("%w: action %d id %d seq %d", ErrReplay, action, id, seq)
+	}
+	out := make([]byte, fn.FnHeaderSize, fn.FnHeaderSize+len(data))
+	copy(out, frame[:aadSize])
+	out, err = s.recv.Open(out, nonce(seq), data[seqSize:], frame[:aadSize])
+	if err != nil {
+		return nil, ErrOpen
+	}
+	// 认证通过后才记入窗口，伪造的帧不能推动窗口
+	if !s.seen.add(seq) {
+		return nil, fmt.Errorf("%w: action %d id %d seq %d", ErrReplay, action, id, seq)
+	}
+	binary.BigEndian.PutUint32(out[aadSize:fn.FnHeaderSize], uint32(len(out)-fn.FnHeaderSize))
+	return out, nil
+}
+
+func new_aead(secret, salt []byte, info string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, secret, salt, info, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(seq uint64) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n[4:], seq)
+	return n
+}
+
+// window 接收方向的滑动窗口：top 为已接受的最高序号，bits 以 seq%ReplayWindow 为下标
+// 记录 (top-ReplayWindow, top] 内已接受的序号
+type window struct {
+	mu   sync.Mutex
+	top  uint64
+	bits [ReplayWindow / 64]uint64
+}
+
+// check 序号可能被接受（未见过且不在窗口之下），不修改窗口
+func (w *window) check(seq uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.fresh(seq)
+}
+
+// add 接受序号并推动窗口，重复或过旧时返回 false
+func (w *window) add(seq uint64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.fresh(seq) {
+		return false
+	}
+	if seq > w.top {
+		if seq-w.top >= ReplayWindow {
+			w.bits = [ReplayWindow / 64]uint64{}
+		} else {
+			for i := w.top + 1; i < seq; i++ {
+				w.bits[i%ReplayWindow/64] &^= 1 << (i % 64)
+			}
+		}
+		w.top = seq
+	}
+	w.bits[seq%ReplayWindow/64] |= 1 << (seq % 64)
+	return true
+}
+
+func (w *window) fresh(seq uint64) bool {
+	if seq == 0 {
+		return false
+	}
+	if seq > w.top {
+		return true
+	}
+	if w.top-seq >= ReplayWindow {
+		return false
+	}
+	return w.bits[seq%ReplayWindow/64]&(1<<(seq%64)) == 0
+}
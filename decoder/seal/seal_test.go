@@ -0,0 +1,181 @@
+package seal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/w6xian/sloth/v3/decoder/fn"
+)
+
+func pair(t *testing.T) (*Session, *Session) {
+	return pair_psk(t, nil, nil)
+}
+
+func pair_psk(t *testing.T, cpsk, spsk []byte) (*Session, *Session) {
+	t.Helper()
+	ck, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewSession(ck, sk.PublicKey().Bytes(), true, cpsk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSession(sk, ck.PublicKey().Bytes(), false, spsk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, s
+}
+
+func TestSession(t *testing.T) {
+	c, s := pair(t)
+	plain, _ := fn.Encode(1, 42, []byte(`{"method":"v1.Test"}`))
+	sealed, err := c.Seal(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fn.IsFn(sealed) || fn.Id(sealed) != 42 || bytes.Contains(sealed, []byte("v1.Test")) {
+		t.Fatalf("sealed % x", sealed)
+	}
+	got, err := s.Open(sealed)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("open: %v % x", err, got)
+	}
+	if _, err := s.Open(sealed); !errors.Is(err, ErrReplay) {
+		t.Fatalf("replay err = %v", err)
+	}
+	// 同一 (Action, ID) 再次发送时换用新的序号，nonce 不重复
+	again, err := c.Seal(plain)
+	if err != nil || bytes.Equal(again, sealed) {
+		t.Fatalf("reseal: %v", err)
+	}
+	if _, err := s.Open(again); err != nil {
+		t.Fatalf("reseal open: %v", err)
+	}
+	// 同一 ID 的回复走另一方向、另一把密钥
+	reply, _ := fn.Encode(2, 42, nil)
+	if sr, err := s.Seal(reply); err != nil {
+		t.Fatal(err)
+	} else if got, err := c.Open(sr); err != nil || !bytes.Equal(got, reply) {
+		t.Fatalf("reply: %v", err)
+	}
+
+	// 篡改 ID、密文，或用本方向的密钥解自己发的帧
+	other, _ := fn.Encode(1, 43, []byte("x"))
+	sealed, _ = c.Seal(other)
+	bad := append([]byte{}, sealed...)
+	bad[10]++
+	if _, err := s.Open(bad); !errors.Is(err, ErrOpen) {
+		t.Fatalf("tampered id err = %v", err)
+	}
+	bad = append([]byte{}, sealed...)
+	bad[len(bad)-1]++
+	if _, err := s.Open(bad); !errors.Is(err, ErrOpen) {
+		t.Fatalf("tampered data err = %v", err)
+	}
+	if _, err := c.Open(sealed); !errors.Is(err, ErrOpen) {
+		t.Fatalf("wrong direction err = %v", err)
+	}
+	if _, err := s.Open(sealed); err != nil {
+		t.Fatalf("forged frames must not block the real one: %v", err)
+	}
+
+	k, _ := GenerateKey()
+	if _, err := NewSession(k, []byte{1, 2, 3}, true, nil); !errors.Is(err, ErrBadKey) {
+		t.Fatalf("bad key err = %v", err)
+	}
+}
+
+func TestReplayWindow(t *testing.T) {
+	c, s := pair(t)
+	seal := func(id uint64) []byte {
+		f, _ := fn.Encode(1, id, []byte("x"))
+		sealed, err := c.Seal(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sealed
+	}
+	first := seal(1)
+	if _, err := s.Open(first); err != nil {
+		t.Fatal(err)
+	}
+	// 窗口内乱序到达照常接受，重复的拒绝
+	late := seal(2)
+	early := seal(3)
+	if _, err := s.Open(early); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(late); err != nil {
+		t.Fatalf("out of order: %v", err)
+	}
+	if _, err := s.Open(late); !errors.Is(err, ErrReplay) {
+		t.Fatalf("replay in window err = %v", err)
+	}
+	// 超过 ReplayWindow 帧之后再重放，帧已落在窗口之下
+	held := seal(4)
+	for i := 0; i < ReplayWindow+1; i++ {
+		if _, err := s.Open(seal(uint64(5 + i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range [][]byte{first, early, held} {
+		if _, err := s.Open(f); !errors.Is(err, ErrReplay) {
+			t.Fatalf("replay below window err = %v", err)
+		}
+	}
+	// 伪造的大序号不能推动窗口
+	forged := seal(9999)
+	binary.BigEndian.PutUint64(forged[fn.FnHeaderSize:], 1<<40)
+	if _, err := s.Open(forged); !errors.Is(err, ErrOpen) {
+		t.Fatalf("forged seq err = %v", err)
+	}
+	if _, err := s.Open(seal(10000)); err != nil {
+		t.Fatalf("after forged seq: %v", err)
+	}
+}
+
+func TestPresharedKey(t *testing.T) {
+	psk := []byte("shared secret")
+	c, s := pair_psk(t, psk, psk)
+	if err := c.Verify(s.Confirm()); err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := fn.Encode(1, 1, []byte("x"))
+	sealed, _ := c.Seal(plain)
+	if _, err := s.Open(sealed); err != nil {
+		t.Fatal(err)
+	}
+
+	// PSK 不一致（或中间人不知道 PSK）时确认值与帧都对不上
+	c, s = pair_psk(t, psk, []byte("other"))
+	if err := c.Verify(s.Confirm()); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("verify err = %v", err)
+	}
+	sealed, _ = c.Seal(plain)
+	if _, err := s.Open(sealed); !errors.Is(err, ErrOpen) {
+		t.Fatalf("open err = %v", err)
+	}
+
+	// 中间人替换双方公钥：与客户端、服务端各自协商出的会话，都与对端的确认值不同
+	ck, _ := GenerateKey()
+	sk, _ := GenerateKey()
+	mk, _ := GenerateKey()
+	cm, _ := NewSession(ck, mk.PublicKey().Bytes(), true, psk)
+	mc, _ := NewSession(mk, ck.PublicKey().Bytes(), false, []byte("guess"))
+	if err := cm.Verify(mc.Confirm()); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("mitm verify err = %v", err)
+	}
+	ms, _ := NewSession(mk, sk.PublicKey().Bytes(), true, nil)
+	sm, _ := NewSession(sk, mk.PublicKey().Bytes(), false, psk)
+	if err := ms.Verify(sm.Confirm()); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("mitm verify err = %v", err)
+	}
+}
@@ -4,15 +4,21 @@ import (
 	"github.com/w6xian/sloth/v3/internal/utils/id"
 )
 
+// NextId 进程内单调递增的调用 ID（snowflake 布局），不传参数时用 SetNodeId 配置的节点（默认 1），
+// 传入 n 时用节点 n
 func NextId(n ...int64) uint64 {
-
 	if len(n) == 0 {
-		n = append(n, 1)
+		return uint64(id.Next())
 	}
 	return uint64(id.NextId(n[0]))
 }
+
+// SetNodeId 设置 NextId 的节点号（0..1023），集群中每个进程配置不同的值即 ID 全局唯一
+func SetNodeId(node int64) error {
+	return id.SetNode(node)
+}
 func DecodeArgs(args [][]byte, decoder func([]byte) ([]byte, error)) [][]byte {
-	a := [][]byte{}
+	a := make([][]byte, 0, len(args))
 	for _, v := range args {
 		b, err := decoder(v)
 		if err != nil {
@@ -25,7 +31,7 @@ func DecodeArgs(args [][]byte, decoder func([]byte) ([]byte, error)) [][]byte {
 }
 
 func EncodeArgs(args []any, encoder func(any) ([]byte, error)) ([][]byte, error) {
-	a := [][]byte{}
+	a := make([][]byte, 0, len(args))
 	for _, v := range args {
 		b, err := encoder(v)
 		if err != nil {
@@ -0,0 +1,128 @@
+package sloth
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// SysService 内置服务名，sys.Describe 返回当前连接注册的全部服务
+const SysService = "sys"
+
+// 连接能力
+const (
+	CapabilityCall      = "call"      // 请求/应答式 RPC
+	CapabilityNotify    = "notify"    // 无返回值的方法（仅返回 error）
+	CapabilityPush      = "push"      // 单向推送（Push/Send）
+	CapabilityRoom      = "room"      // 房间推送/房间 RPC（服务端）
+	CapabilityBroadcast = "broadcast" // 全服广播/全服 RPC（服务端）
+)
+
+// ApiDesc 服务自描述
+type ApiDesc struct {
+	ServerId     string        `json:"server_id"`
+	Version      string        `json:"version"`
+	Capabilities []string      `json:"capabilities"`
+	Services     []ServiceDesc `json:"services"`
+}
+
+// ServiceDesc 单个服务的描述
+type ServiceDesc struct {
+	Name       string       `json:"name"`                 // 注册名，如 v1；带版本时为 name@version
+	Type       string       `json:"type"`                 // Go 类型全名
+	Meta       string       `json:"meta"`                 // Register 时传入的 metadata
+	Version    string       `json:"version,omitempty"`    // WithVersion
+	Default    bool         `json:"default"`              // 未指定版本时使用
+	Deprecated string       `json:"deprecated,omitempty"` // WithDeprecated
+	Methods    []MethodDesc `json:"methods"`
+}
+
+// MethodDesc 单个方法的描述，Args 不含 ctx
+type MethodDesc struct {
+	Name    string    `json:"name"`
+	Define  string    `json:"define"`
+	Args    []ArgDesc `json:"args"`
+	Returns []string  `json:"returns"`
+	Notify  bool      `json:"notify"`
+}
+
+type ArgDesc struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Describe 返回当前连接注册的全部服务及方法签名，服务与方法按名称排序
+func (c *Connect) Describe() *ApiDesc {
+	desc := &ApiDesc{
+		ServerId:     c.ServerId,
+		Version:      Version,
+		Capabilities: c.capabilities(),
+	}
+	for _, r := range c.services() {
+		desc.Services = append(desc.Services, describeService(r))
+	}
+	return desc
+}
+
+// DescribeService 返回单个服务的描述，name 为 name@version 或服务名（默认版本）
+func (c *Connect) DescribeService(name string) (*ServiceDesc, bool) {
+	r, ok := c.service(name)
+	if !ok {
+		return nil, false
+	}
+	sd := describeService(r)
+	return &sd, true
+}
+
+func (c *Connect) capabilities() []string {
+	caps := []string{CapabilityCall, CapabilityNotify, CapabilityPush}
+	if c.client != nil && c.client.Serve != nil {
+		caps = append(caps, CapabilityRoom, CapabilityBroadcast)
+	}
+	return caps
+}
+
+func describeService(r registered) ServiceDesc {
+	fns := r.Funcs
+	sd := ServiceDesc{
+		Name:       r.Key,
+		Type:       fns.N,
+		Meta:       fns.D,
+		Version:    fns.Version,
+		Default:    r.Default,
+		Deprecated: fns.Deprecated,
+		Methods:    make([]MethodDesc, 0, len(fns.A)),
+	}
+	for _, fs := range fns.A {
+		md := MethodDesc{
+			Name:    fs.Name,
+			Define:  fs.Define,
+			Args:    make([]ArgDesc, 0, len(fs.Args)),
+			Returns: fs.Returns,
+			Notify:  len(fs.Returns) == 1,
+		}
+		for _, a := range fs.Args {
+			md.Args = append(md.Args, ArgDesc{Name: a.Name, Type: a.Type})
+		}
+		sd.Methods = append(sd.Methods, md)
+	}
+	slices.SortFunc(sd.Methods, func(a, b MethodDesc) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return sd
+}
+
+// sysService 内置服务，随 Connect 自动注册为 sys
+type sysService struct {
+	c *Connect
+}
+
+// Describe 远端调用 sys.Describe 获取服务自描述
+func (s *sysService) Describe(ctx context.Context) (*ApiDesc, error) {
+	return s.c.Describe(), nil
+}
+
+// Contract 远端调用 sys.Contract 获取服务契约（JSON Schema）
+func (s *sysService) Contract(ctx context.Context) (*Contract, error) {
+	return s.c.Contract(), nil
+}
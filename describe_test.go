@@ -0,0 +1,104 @@
+package sloth
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/w6xian/sloth/v3/types/trpc"
+)
+
+type Item struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type cartSvc struct{}
+
+func (s *cartSvc) Add(ctx context.Context, item *Item, n int) (int, error) { return n, nil }
+func (s *cartSvc) Clear(ctx context.Context) error                         { return nil }
+
+type userSvc struct{}
+
+func (s *userSvc) Get(ctx context.Context, id int64) (*Item, error) { return &Item{Id: id}, nil }
+func (s *userSvc) Tags(ctx context.Context, tags ...string) error   { return nil }
+func (s *userSvc) hidden(ctx context.Context) error                 { return nil }
+func (s *userSvc) NoCtx(id int64) error                             { return nil }
+
+func TestDescribe(t *testing.T) {
+	c := ServerConn(DefaultServer())
+	if err := c.Register("cart", &cartSvc{}, "购物车"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Register("user", &userSvc{}, "用户", WithVersion("v2"), WithDeprecated("use v3")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 本地与远端 sys.Describe 得到同样的描述
+	out, err := c.CallFunc(context.Background(), nil, nil, &trpc.RpcCaller{Method: "sys.Describe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remote ApiDesc
+	if err := json.Unmarshal(out, &remote); err != nil {
+		t.Fatalf("sys.Describe = %s: %v", out, err)
+	}
+	local := c.Describe()
+	a, _ := json.Marshal(local)
+	b, _ := json.Marshal(&remote)
+	if string(a) != string(b) {
+		t.Fatalf("local %s\nremote %s", a, b)
+	}
+	for _, cap := range []string{CapabilityCall, CapabilityNotify, CapabilityPush} {
+		if !slices.Contains(local.Capabilities, cap) {
+			t.Errorf("capabilities %v missing %s", local.Capabilities, cap)
+		}
+	}
+
+	services := map[string]ServiceDesc{}
+	for _, sd := range local.Services {
+		services[sd.Name] = sd
+	}
+	if _, ok := services[SysService]; !ok {
+		t.Errorf("sys not described: %v", local.Services)
+	}
+
+	cart, ok := services["cart"]
+	if !ok || cart.Meta != "购物车" || cart.Type != "github.com/w6xian/sloth/v3.cartSvc" || !cart.Default || cart.Version != "" || cart.Deprecated != "" {
+		t.Fatalf("cart = %+v", cart)
+	}
+	user, ok := services["user@v2"]
+	if !ok || user.Meta != "用户" || user.Version != "v2" || !user.Default || user.Deprecated != "use v3" {
+		t.Fatalf("user = %+v", user)
+	}
+
+	check := func(sd ServiceDesc, want []MethodDesc) {
+		t.Helper()
+		if len(sd.Methods) != len(want) {
+			t.Fatalf("%s methods = %+v", sd.Name, sd.Methods)
+		}
+		for i, w := range want {
+			m := sd.Methods[i]
+			if m.Name != w.Name || m.Notify != w.Notify || !slices.Equal(m.Args, w.Args) || !slices.Equal(m.Returns, w.Returns) {
+				t.Errorf("%s.%s = %+v, want %+v", sd.Name, w.Name, m, w)
+			}
+		}
+	}
+	// 方法按名称排序，未导出与不带 ctx 的方法不出现
+	check(cart, []MethodDesc{
+		{Name: "Add", Args: []ArgDesc{{"arg0", "*sloth.Item"}, {"arg1", "int"}}, Returns: []string{"int", "error"}},
+		{Name: "Clear", Args: []ArgDesc{}, Returns: []string{"error"}, Notify: true},
+	})
+	check(user, []MethodDesc{
+		{Name: "Get", Args: []ArgDesc{{"arg0", "int64"}}, Returns: []string{"*sloth.Item", "error"}},
+		{Name: "Tags", Args: []ArgDesc{{"arg0", "...string"}}, Returns: []string{"error"}, Notify: true},
+	})
+
+	if sd, ok := c.DescribeService("user"); !ok || sd.Name != "user@v2" || sd.Meta != "用户" {
+		t.Errorf("DescribeService(user) = %+v, %v", sd, ok)
+	}
+	if _, ok := c.DescribeService("user@v1"); ok {
+		t.Error("DescribeService(user@v1) ok")
+	}
+}
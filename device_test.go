@@ -0,0 +1,103 @@
+package sloth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/w6xian/sloth/v3/bucket"
+	"github.com/w6xian/sloth/v3/message"
+	"github.com/w6xian/sloth/v3/types"
+)
+
+// deviceServer 只有一个 bucket 的服务端
+type deviceServer struct {
+	types.IServer
+	b *bucket.Bucket
+}
+
+func (s *deviceServer) Bucket(userId int64) *bucket.Bucket { return s.b }
+
+// deviceChannel 按 call 的返回应答 Call，记录调用时的 deadline
+type deviceChannel struct {
+	bucket.IChannel
+	call     func(ctx context.Context) ([]byte, error)
+	deadline chan time.Time
+}
+
+func newDeviceChannel(call func(ctx context.Context) ([]byte, error)) *deviceChannel {
+	return &deviceChannel{call: call, deadline: make(chan time.Time, 1)}
+}
+
+func (c *deviceChannel) Call(ctx context.Context, header message.Header, mtd string, args ...[]byte) ([]byte, error) {
+	d, _ := ctx.Deadline()
+	c.deadline <- d
+	return c.call(ctx)
+}
+func (c *deviceChannel) Room(r ...*bucket.Room) *bucket.Room { return nil }
+func (c *deviceChannel) UserId(u ...int64) int64             { return 0 }
+func (c *deviceChannel) Token(t ...string) string            { return "" }
+
+func TestCallDevices(t *testing.T) {
+	b := bucket.NewBucket()
+	cli := DefaultServer()
+	cli.Serve = &deviceServer{b: b}
+
+	if _, err := cli.CallDevices(context.Background(), 1, "v1.Ping"); err == nil || err.Error() != "channel not found" {
+		t.Fatalf("offline err = %v", err)
+	}
+
+	ok := newDeviceChannel(func(ctx context.Context) ([]byte, error) { return []byte("pong"), nil })
+	fail := newDeviceChannel(func(ctx context.Context) ([]byte, error) { return nil, errors.New("boom") })
+	// 不应答的设备只能等到自己的超时
+	hang := newDeviceChannel(func(ctx context.Context) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	for id, ch := range map[string]*deviceChannel{"phone": ok, "pad": fail, "web": hang} {
+		if err := b.PutDevice(1, id, bucket.NoRoom, "", ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 调用方没有 deadline 时，每个设备各自带 defaultCallTimeout
+	hang.call = func(ctx context.Context) ([]byte, error) { return nil, context.DeadlineExceeded }
+	start := time.Now()
+	resp, err := cli.CallDevices(context.Background(), 1, "v1.Ping")
+	for _, ch := range []*deviceChannel{ok, fail, hang} {
+		d := <-ch.deadline
+		if d.IsZero() || d.Before(start.Add(defaultCallTimeout)) || d.After(time.Now().Add(defaultCallTimeout)) {
+			t.Errorf("deadline = %v, want now+%v", d, defaultCallTimeout)
+		}
+	}
+	if len(resp) != 1 || string(resp["phone"]) != "pong" {
+		t.Fatalf("resp = %q", resp)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), `device "pad": boom`) || !strings.Contains(err.Error(), `device "web"`) {
+		t.Fatalf("err = %v", err)
+	}
+
+	// 慢设备超时不拖住其他设备的结果
+	hang.call = func(ctx context.Context) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	resp, err = cli.CallDevices(ctx, 1, "v1.Ping")
+	if len(resp) != 1 || string(resp["phone"]) != "pong" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("resp = %q, err = %v", resp, err)
+	}
+	for _, ch := range []*deviceChannel{ok, fail, hang} {
+		<-ch.deadline
+	}
+
+	if b, err := cli.CallDevice(context.Background(), 1, "phone", "v1.Ping"); err != nil || string(b) != "pong" {
+		t.Fatalf("CallDevice = %q, %v", b, err)
+	}
+	if _, err := cli.CallDevice(context.Background(), 1, "tv", "v1.Ping"); err == nil {
+		t.Fatal("CallDevice(tv): err = nil")
+	}
+}
@@ -0,0 +1,31 @@
+package sloth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/w6xian/sloth/v3/internal/ref"
+)
+
+// ErrBadArguments 服务方无法把参数解码为方法声明的类型（长度不符、JSON 格式错误、strict 下的未知字段等）
+var ErrBadArguments = ref.ErrBadArguments
+
+// ArgError 参数解码错误，携带参数下标、期望的 Go 类型与原因
+//
+//	bad arguments: arg1 (*main.Req): json: unknown field "c"
+type ArgError = ref.ArgError
+
+// IsBadArguments 判断错误是否为参数解码错误；
+// 服务方本地调用可直接 errors.Is，远端返回的错误只保留文本，按前缀判断
+func IsBadArguments(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrBadArguments) || strings.HasPrefix(err.Error(), ErrBadArguments.Error()+":")
+}
+
+// FieldError 参数结构体上单个字段的 validate 校验失败
+type FieldError = ref.FieldError
+
+// ValidationErrors 一个参数上全部失败的字段，作为 ArgError 的原因返回，可用 errors.As 取出
+type ValidationErrors = ref.ValidationErrors
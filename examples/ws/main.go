@@ -13,6 +13,7 @@ import (
 	"github.com/w6xian/sloth/v3/message"
 	"github.com/w6xian/sloth/v3/option"
 	"github.com/w6xian/sloth/v3/slots"
+	"github.com/w6xian/sloth/v3/tsgen"
 	"github.com/w6xian/sloth/v3/types"
 	"github.com/w6xian/sloth/v3/types/auth"
 	"github.com/w6xian/tlv"
@@ -46,6 +47,9 @@ func main() {
 	r := mux.NewRouter()
 	// Register services
 	drpc.Register("v1", &HelloService{}, "metadata")
+	// 浏览器端类型化存根，始终与当前注册的服务一致
+	http.Handle("/sloth_api.js", tsgen.Handler(drpc))
+	http.Handle("/sloth_api.ts", tsgen.Handler(drpc))
 	drpc.Listen(ctx, "ws", "localhost:8990",
 		option.WithRouter(r, "/ws"),
 		option.WithOrigin("*", "localhost:8000"),
@@ -3,16 +3,26 @@
  *
  * 帧格式：
  *   MAGIC  :p   2 byte   0x3A 0x70  (ASCII ":p")
- *   TYPE   t    1 byte   ArgumentType* 枚举
- *   LEN    l    2 byte   big endian，Value 字节数 (0~65535)
+ *   TYPE   t    1 byte   ArgumentType* 枚举，最高位为扩展长度标志 ArgumentTypeExtended
+ *   LEN    l    2 byte   big endian，Value 字节数 (0~65535)；扩展帧为 4 byte
  *   VALUE  d    l byte   payload，长度 = l
  *
- * 总帧最小 5 字节，最大 5 + 65535 = 65540 字节。
+ * 总帧最小 5 字节。Value 不超过 65535 字节时写普通帧，超过时写扩展帧
+ * （TYPE 置 0x80、LEN 4 字节、帧头 7 字节），与 Go 版一致。
  *
  * 说明：
  *   - 整数编码采用 Big Endian + 简易压缩（去除前导 0，负数保留 FF 符号位）
  *   - Float32/Float64/Complex64/Complex128 采用 Little Endian（与 Go 版一致）
- *   - 复合类型（Array/Object/非 Uint8Array）降级为 JSON 字符串以 String 帧传输
+ *   - 复合类型的 VALUE 为嵌套的 AG 帧序列（与 Go 版一致）：
+ *       Slice   元素帧 e0 e1 ...              Array
+ *       Map     键帧 值帧 ...（按键帧字节排序） Map 实例
+ *       Struct  字段名(String 帧) 值帧 ...     普通对象（值为 undefined 的属性省略）
+ *     旧版本的复合帧 VALUE 为 JSON 文本，解码时按首字节区分
+ *   - 扩展标量（与 Go 版 ext.go 一致）：
+ *       Time      秒(int64 BE) 纳秒(uint32 BE) UTC 偏移秒(int32 BE) 时区名   ←→ Date（毫秒精度，时区不保留）
+ *       Duration  纳秒数，同 Int64                                          ←→ AsDuration / BigInt
+ *       BigInt    符号(1 byte) 绝对值(BE)                                   ←→ 超出 64 位的 BigInt / AsBigInt
+ *       BigFloat  精度(uint32 BE) 十进制文本                                ←→ AsBigFloat / string
  *   - 64 位整数使用 BigInt 保证精度；若运行环境不支持 BigInt，将退化为 Number
  */
 
@@ -26,11 +36,14 @@ const ArgumentMagic1 = 0x3A;
 const ArgumentMagic2 = 0x70;
 /** 帧头大小 = Magic(2) + Type(1) + Length(2) = 5 */
 const ArgumentHeaderSize = 2 + 1 + 2;
-/** Value 段最大字节数上限（>此值时报 ErrAgDataTooLarge）。
- *  注意：LEN 字段是 16 位无符号，实际最大可表达 65535；当 data 长度恰好 65536 时
- *  不会触发错误但会使 LEN 溢位（与 Go 版行为完全一致）。
- */
+/** 普通帧 Value 长度上限（不含），达到时改写扩展帧 */
 const ArgumentMaxDataSize = 1 << 16;
+/** 扩展帧帧头 = Magic(2) + Type(1) + Length(4) = 7 */
+const ArgumentExtHeaderSize = 2 + 1 + 4;
+/** 扩展帧 Value 最大字节数（>此值时报 ErrAgDataTooLarge） */
+const ArgumentMaxExtDataSize = 0xFFFFFFFF;
+/** TYPE 字节的扩展长度标志 */
+const ArgumentTypeExtended = 0x80;
 
 /**
  * 基本类型枚举（与 Go 原语一一对应，0x01~0x1F 为基础标量；0x20~0x3F 为复合/扩展）
@@ -66,6 +79,11 @@ const ArgumentType = {
   Map: 21,
   Struct: 22,
   Custom: 23,
+
+  Time: 24,
+  Duration: 25,
+  BigInt: 26,
+  BigFloat: 27,
 };
 
 /** 兼容 Go 常量命名的别名 */
@@ -92,6 +110,10 @@ const ArgumentTypeSlice = ArgumentType.Slice;
 const ArgumentTypeMap = ArgumentType.Map;
 const ArgumentTypeStruct = ArgumentType.Struct;
 const ArgumentTypeCustom = ArgumentType.Custom;
+const ArgumentTypeTime = ArgumentType.Time;
+const ArgumentTypeDuration = ArgumentType.Duration;
+const ArgumentTypeBigInt = ArgumentType.BigInt;
+const ArgumentTypeBigFloat = ArgumentType.BigFloat;
 
 /* ============================================================
  *  错误对象
@@ -100,7 +122,7 @@ const ArgumentTypeCustom = ArgumentType.Custom;
 const ErrAgTooShort = new Error('ag: payload too short for header');
 const ErrAgBadMagic = new Error('ag: bad magic header, expect :p');
 const ErrAgLengthMismatch = new Error('ag: payload length mismatch');
-const ErrAgDataTooLarge = new Error(`ag: data length exceeds ${ArgumentMaxDataSize}`);
+const ErrAgDataTooLarge = new Error(`ag: data length exceeds ${ArgumentMaxExtDataSize}`);
 const ErrAgUnknownType = new Error('ag: unknown type tag');
 const ErrAgInvalidHeader = new Error('ag: invalid header');
 
@@ -114,26 +136,46 @@ const ErrAgInvalidHeader = new Error('ag: invalid header');
  * @param {number} t 类型标签 (ArgumentType*)
  * @param {Uint8Array|null} data Value 段
  * @returns {Uint8Array}
- * @throws {ErrAgDataTooLarge} 数据超过 65535 字节
+ * @throws {ErrAgDataTooLarge} 数据超过 ArgumentMaxExtDataSize 字节
  */
 function encode_ag(t, data) {
   const payload = data || new Uint8Array(0);
-  if (payload.length > ArgumentMaxDataSize) {
+  if (payload.length > ArgumentMaxExtDataSize) {
     throw ErrAgDataTooLarge;
   }
-  const out = new Uint8Array(ArgumentHeaderSize + payload.length);
+  const ext = payload.length >= ArgumentMaxDataSize;
+  const hdr = ext ? ArgumentExtHeaderSize : ArgumentHeaderSize;
+  const out = new Uint8Array(hdr + payload.length);
   out[0] = ArgumentMagic1;
   out[1] = ArgumentMagic2;
-  out[2] = t & 0xFF;
-  // 写入 2 字节 BigEndian 长度
-  out[3] = (payload.length >> 8) & 0xFF;
-  out[4] = payload.length & 0xFF;
+  out[2] = ext ? (t | ArgumentTypeExtended) & 0xFF : t & 0xFF;
+  // 写入 BigEndian 长度：普通帧 2 字节，扩展帧 4 字节
+  if (ext) {
+    new DataView(out.buffer).setUint32(3, payload.length, false);
+  } else {
+    out[3] = (payload.length >> 8) & 0xFF;
+    out[4] = payload.length & 0xFF;
+  }
   if (payload.length > 0) {
-    out.set(payload, ArgumentHeaderSize);
+    out.set(payload, hdr);
   }
   return out;
 }
 
+/**
+ * 解析帧头长度与 Value 长度；buf 以 magic 开头且不短于普通帧头
+ * @param {Uint8Array} buf
+ * @returns {{hdr: number, length: number}|null} 扩展帧头不完整时返回 null
+ */
+function frame_size(buf) {
+  if ((buf[2] & ArgumentTypeExtended) === 0) {
+    return { hdr: ArgumentHeaderSize, length: (buf[3] << 8) | buf[4] };
+  }
+  if (buf.length < ArgumentExtHeaderSize) return null;
+  const length = new DataView(buf.buffer, buf.byteOffset, buf.byteLength).getUint32(3, false);
+  return { hdr: ArgumentExtHeaderSize, length };
+}
+
 /**
  * O(1) 校验帧完整性（magic + length 匹配）
  * @param {Uint8Array|ArrayBuffer|Array<number>} b
@@ -143,8 +185,8 @@ function IsArgument(b) {
   const buf = _asU8(b);
   if (!buf || buf.length < ArgumentHeaderSize) return false;
   if (buf[0] !== ArgumentMagic1 || buf[1] !== ArgumentMagic2) return false;
-  const length = (buf[3] << 8) | buf[4];
-  return buf.length === ArgumentHeaderSize + length;
+  const fs = frame_size(buf);
+  return fs !== null && buf.length === fs.hdr + fs.length;
 }
 
 /**
@@ -156,9 +198,9 @@ function Validate(b) {
   const buf = _asU8(b);
   if (!buf || buf.length < ArgumentHeaderSize) return ErrAgTooShort;
   if (buf[0] !== ArgumentMagic1 || buf[1] !== ArgumentMagic2) return ErrAgBadMagic;
-  const length = (buf[3] << 8) | buf[4];
-  if (length > ArgumentMaxDataSize) return ErrAgDataTooLarge;
-  if (buf.length !== ArgumentHeaderSize + length) return ErrAgLengthMismatch;
+  const fs = frame_size(buf);
+  if (fs === null) return ErrAgTooShort;
+  if (buf.length !== fs.hdr + fs.length) return ErrAgLengthMismatch;
   return null;
 }
 
@@ -171,7 +213,7 @@ function ag_get_frame(b) {
   const err = Validate(b);
   if (err) throw err;
   const buf = _asU8(b);
-  const t = buf[2];
+  const t = buf[2] & ~ArgumentTypeExtended;
   const v =  ag_get_data(buf);
   return { t, v };
 }
@@ -189,11 +231,11 @@ function ag_get_frame(b) {
  */
 function ag_get_data(b) {
   const buf = _asU8(b);
-  const length = (buf[3] << 8) | buf[4];
+  const { hdr, length } = frame_size(buf);
   if (length === 0) return null;
   const raw = new Uint8Array(length);
-  raw.set(buf.subarray(ArgumentHeaderSize, ArgumentHeaderSize + length), 0);
-  const t = buf[2];
+  raw.set(buf.subarray(hdr, hdr + length), 0);
+  const t = buf[2] & ~ArgumentTypeExtended;
   switch (t) {
     case ArgumentTypeUint8:
     case ArgumentTypeInt8:
@@ -209,6 +251,7 @@ function ag_get_data(b) {
     case ArgumentTypeInt:
     case ArgumentTypeUint:
     case ArgumentTypeUintptr:
+    case ArgumentTypeDuration:
       return zeroExtend8byte(raw);
   }
   return raw;
@@ -251,9 +294,11 @@ function Decoder(b) {
  *   - null/undefined → Nil
  *   - string         → String
  *   - Uint8Array     → Bytes
- *   - Array          → Slice（实际编码会降级 JSON → String）
- *   - plain Object   → Struct/Map（实际编码会降级 JSON → String）
- *   - BigInt         → Int64 / Uint64（根据符号）
+ *   - Array          → Slice
+ *   - Map            → Map
+ *   - plain Object   → Struct
+ *   - BigInt         → Int64 / Uint64（根据符号），超出 64 位为 BigInt
+ *   - Date           → Time
  * @param {any} arg
  * @returns {number} ArgumentType*
  */
@@ -262,6 +307,7 @@ function typeofTag(arg) {
   if (typeof arg === 'boolean') return ArgumentTypeBool;
   if (typeof arg === 'string') return ArgumentTypeString;
   if (typeof arg === 'bigint') {
+    if (arg < -(BigInt(1) << BigInt(63)) || arg >= (BigInt(1) << BigInt(64))) return ArgumentTypeBigInt;
     return arg < 0 ? ArgumentTypeInt64 : ArgumentTypeUint64;
   }
   if (typeof arg === 'number') {
@@ -273,7 +319,9 @@ function typeofTag(arg) {
     return ArgumentTypeFloat64;
   }
   if (arg instanceof Uint8Array) return ArgumentTypeBytes;
+  if (arg instanceof Date) return ArgumentTypeTime;
   if (Array.isArray(arg)) return ArgumentTypeSlice;
+  if (arg instanceof Map) return ArgumentTypeMap;
   if (arg && typeof arg === 'object') {
     // 判断是否复数对象 {real,imag}
     if ('real' in arg && 'imag' in arg &&
@@ -290,10 +338,36 @@ function typeofTag(arg) {
  *  编码：EncodeArg / Encoder / Json
  * ============================================================ */
 
+/**
+ * 对端协商了 ag_ext 时由 EncodeArgExt 临时置 true；默认按旧版格式编码，
+ * 复合类型为 JSON 的 String 帧，Date/大整数等扩展标量为 JSON 文本，旧版服务端都能解析
+ */
+let _agExt = false;
+
+/**
+ * 旧版格式：复合类型与 Date 为 JSON 的 String 帧，其余扩展标量为 Custom 帧
+ * @param {number} t
+ * @param {any} val
+ * @returns {Uint8Array}
+ */
+function encode_legacy(t, val) {
+  switch (t) {
+    case ArgumentTypeSlice:
+    case ArgumentTypeMap:
+    case ArgumentTypeStruct:
+    case ArgumentTypeTime: {
+      const s = jsonMarshalFallback(val instanceof Map ? Object.fromEntries(val) : val);
+      return encode_ag(ArgumentTypeString, new TextEncoder().encode(s));
+    }
+  }
+  return encode_ag(ArgumentTypeCustom, _serialize(val));
+}
+
 /**
  * 把任意值按类型编码为一帧 AG
  *   - 标量走原语编码
- *   - 复合 (Array/Object/非 Uint8Array) 走 JSON fallback 映射成 String 帧
+ *   - 复合 (Array/Map/Object) 与扩展标量按旧版格式编码（见 encode_legacy）；
+ *     对端协商了 ag_ext 时用 EncodeArgExt，复合类型编码为嵌套帧，见 encode_composite
  * @param {any} arg
  * @returns {Uint8Array}
  * @throws {ErrAgDataTooLarge}
@@ -362,15 +436,126 @@ function EncodeArg(arg) {
 
     case ArgumentTypeSlice:
     case ArgumentTypeMap:
-    case ArgumentTypeStruct: {
-      const s = jsonMarshalFallback(arg);
-      return encode_ag(ArgumentTypeString, new TextEncoder().encode(s));
-    }
+    case ArgumentTypeStruct:
+      return _agExt ? encode_composite(t, arg) : encode_legacy(t, arg);
+
+    case ArgumentTypeTime:
+    case ArgumentTypeBigInt:
+      return _agExt ? encode_ext(t, arg) : encode_legacy(t, arg);
   }
   // 兜底：Custom 类型走 serialize
   return encode_ag(ArgumentTypeCustom, _serialize(arg));
 }
 
+/**
+ * 复合类型编码：VALUE 为嵌套帧的拼接，元素经 EncodeArg（含 Tagged 识别）递归编码
+ *   - Slice  ：Array 或可迭代对象，逐个元素成帧
+ *   - Map    ：Map 实例或普通对象，键帧 + 值帧，按键帧字节排序保证结果稳定
+ *   - Struct ：普通对象，字段名 String 帧 + 值帧，按属性顺序；undefined 省略
+ * @param {number} t ArgumentTypeSlice / Map / Struct
+ * @param {any} val
+ * @returns {Uint8Array}
+ */
+function encode_composite(t, val) {
+  const enc = _patchedEncode;
+  const frames = [];
+  switch (t) {
+    case ArgumentTypeSlice:
+      for (const e of Array.from(val)) frames.push(enc(e));
+      break;
+    case ArgumentTypeMap: {
+      const entries = val instanceof Map ? Array.from(val.entries()) : Object.entries(val);
+      const pairs = entries.map(([k, v]) => [enc(k), enc(v)]);
+      pairs.sort((a, b) => _compareBytes(a[0], b[0]));
+      for (const [k, v] of pairs) frames.push(k, v);
+      break;
+    }
+    default:
+      for (const [k, v] of Object.entries(val)) {
+        if (v === undefined) continue;
+        frames.push(encode_ag(ArgumentTypeString, new TextEncoder().encode(k)), enc(v));
+      }
+  }
+  return encode_ag(t, _concatBytes(frames));
+}
+
+/**
+ * 扩展标量编码
+ *   - Time     ：Date / 毫秒时间戳 / 可被 Date 解析的字符串，按 UTC 写入
+ *   - Duration ：纳秒数（number / bigint）
+ *   - BigInt   ：bigint / 整数 number / 十进制字符串
+ *   - BigFloat ：十进制文本，或 {text, prec}（prec 缺省 64）
+ * @param {number} t
+ * @param {any} val
+ * @returns {Uint8Array}
+ */
+function encode_ext(t, val) {
+  switch (t) {
+    case ArgumentTypeTime: {
+      const ms = (val instanceof Date ? val : new Date(val)).getTime();
+      const sec = Math.floor(ms / 1000);
+      const name = new TextEncoder().encode('UTC');
+      const out = new Uint8Array(16 + name.length);
+      const dv = new DataView(out.buffer);
+      dv.setBigInt64(0, BigInt(sec), false);
+      dv.setUint32(8, (ms - sec * 1000) * 1e6, false);
+      dv.setInt32(12, 0, false);
+      out.set(name, 16);
+      return encode_ag(t, out);
+    }
+    case ArgumentTypeDuration:
+      return encode_ag(t, _intToByteImpl(_toBig(val)));
+    case ArgumentTypeBigInt: {
+      let n = BigInt(val);
+      const neg = n < 0;
+      if (neg) n = -n;
+      let hex = n === BigInt(0) ? '' : n.toString(16);
+      if (hex.length % 2) hex = '0' + hex;
+      const out = new Uint8Array(1 + hex.length / 2);
+      out[0] = neg ? 1 : 0;
+      for (let i = 0; i < hex.length / 2; i++) out[1 + i] = parseInt(hex.substr(i * 2, 2), 16);
+      return encode_ag(t, out);
+    }
+    case ArgumentTypeBigFloat: {
+      const text = new TextEncoder().encode(String(val && typeof val === 'object' ? val.text : val));
+      const out = new Uint8Array(4 + text.length);
+      new DataView(out.buffer).setUint32(0, (val && typeof val === 'object' && val.prec) || 64, false);
+      out.set(text, 4);
+      return encode_ag(t, out);
+    }
+  }
+  throw ErrAgUnknownType;
+}
+
+/**
+ * 扩展标量解码：Time → Date，Duration/BigInt → bigint，BigFloat → 十进制文本
+ * @param {number} t
+ * @param {Uint8Array|null} v
+ * @returns {any}
+ */
+function decode_ext(t, v) {
+  switch (t) {
+    case ArgumentTypeTime: {
+      if (!v || v.length < 16) throw ErrAgLengthMismatch;
+      const dv = new DataView(v.buffer, v.byteOffset, v.byteLength);
+      const sec = Number(dv.getBigInt64(0, false));
+      return new Date(sec * 1000 + Math.floor(dv.getUint32(8, false) / 1e6));
+    }
+    case ArgumentTypeDuration:
+      return to_int64(v);
+    case ArgumentTypeBigInt: {
+      if (!v || v.length === 0) throw ErrAgLengthMismatch;
+      let n = BigInt(0);
+      for (let i = 1; i < v.length; i++) n = (n << BigInt(8)) | BigInt(v[i]);
+      return v[0] === 1 ? -n : n;
+    }
+    case ArgumentTypeBigFloat:
+      if (!v || v.length < 4) throw ErrAgLengthMismatch;
+      return new TextDecoder().decode(v.subarray(4));
+  }
+  throw ErrAgUnknownType;
+}
+
 /** Encoder = EncodeArg 别名 */
 function Encoder(arg) { return EncodeArg(arg); }
 
@@ -475,6 +660,17 @@ function get_value_from(t, v) {
       out.set(v, 0);
       return out;
     }
+    case ArgumentTypeSlice:
+    case ArgumentTypeMap:
+    case ArgumentTypeStruct:
+      return decode_composite(t, v);
+
+    case ArgumentTypeTime:
+    case ArgumentTypeDuration:
+    case ArgumentTypeBigInt:
+    case ArgumentTypeBigFloat:
+      return decode_ext(t, v);
+
     case ArgumentTypeCustom:{
        if (!v) return new Uint8Array(0);
       const out = new Uint8Array(v.length);
@@ -489,6 +685,54 @@ function get_value_from(t, v) {
   throw ErrAgUnknownType;
 }
 
+/**
+ * 复合类型解码：Slice → Array；Struct → 普通对象；
+ * Map → 键全为字符串时为普通对象，否则为 Map 实例。
+ * VALUE 首字节不是 magic 时按旧版 JSON 文本解析
+ * @param {number} t
+ * @param {Uint8Array|null} v
+ * @returns {any}
+ */
+function decode_composite(t, v) {
+  if (v && v.length > 0 && v[0] !== ArgumentMagic1) {
+    return JSON.parse(new TextDecoder().decode(v));
+  }
+  const items = split_frames(v).map(get_value);
+  if (t === ArgumentTypeSlice) return items;
+  if (items.length % 2 !== 0) throw ErrAgLengthMismatch;
+  let plain = true;
+  for (let i = 0; i < items.length; i += 2) {
+    if (typeof items[i] !== 'string') { plain = false; break; }
+  }
+  const out = plain ? {} : new Map();
+  for (let i = 0; i < items.length; i += 2) {
+    if (plain) out[items[i]] = items[i + 1];
+    else out.set(items[i], items[i + 1]);
+  }
+  return out;
+}
+
+/**
+ * 把复合帧的 VALUE 切分为一组完整帧
+ * @param {Uint8Array|null} v
+ * @returns {Uint8Array[]}
+ */
+function split_frames(v) {
+  const frames = [];
+  let off = 0;
+  while (v && off < v.length) {
+    if (v.length - off < ArgumentHeaderSize) throw ErrAgLengthMismatch;
+    if (v[off] !== ArgumentMagic1 || v[off + 1] !== ArgumentMagic2) throw ErrAgBadMagic;
+    const fs = frame_size(v.subarray(off));
+    if (fs === null) throw ErrAgLengthMismatch;
+    const end = off + fs.hdr + fs.length;
+    if (end > v.length) throw ErrAgLengthMismatch;
+    frames.push(v.subarray(off, end));
+    off = end;
+  }
+  return frames;
+}
+
 /**
  * 从完整帧中取出 {type,value} 后再还原 JS 值
  * @param {Uint8Array} b
@@ -516,6 +760,23 @@ function DecodeArg(b) {
  *  内部工具：输入规范化 → Uint8Array
  * ============================================================ */
 
+function _concatBytes(parts) {
+  let n = 0;
+  for (const p of parts) n += p.length;
+  const out = new Uint8Array(n);
+  let off = 0;
+  for (const p of parts) { out.set(p, off); off += p.length; }
+  return out;
+}
+
+function _compareBytes(a, b) {
+  const n = Math.min(a.length, b.length);
+  for (let i = 0; i < n; i++) {
+    if (a[i] !== b[i]) return a[i] - b[i];
+  }
+  return a.length - b.length;
+}
+
 function _asU8(b) {
   if (!b) return null;
   if (b instanceof Uint8Array) return b;
@@ -565,6 +826,10 @@ function typeName(t) {
     case ArgumentTypeMap:        return 'map';
     case ArgumentTypeStruct:     return 'struct';
     case ArgumentTypeCustom:     return 'custom';
+    case ArgumentTypeTime:       return 'time';
+    case ArgumentTypeDuration:   return 'duration';
+    case ArgumentTypeBigInt:     return 'bigint';
+    case ArgumentTypeBigFloat:   return 'bigfloat';
   }
   return `unknown(${t})`;
 }
@@ -602,6 +867,12 @@ function AsUint64(v)  { return Tagged(ArgumentTypeUint64, v); }
 function AsUintptr(v) { return Tagged(ArgumentTypeUintptr, v); }
 function AsFloat32(v) { return Tagged(ArgumentTypeFloat32, v); }
 function AsComplex64(re, im) { return Tagged(ArgumentTypeComplex64, { real: re, imag: im }); }
+/** Go time.Duration，ns 为纳秒数 */
+function AsDuration(ns)  { return Tagged(ArgumentTypeDuration, ns); }
+/** Go *big.Int，v 为 bigint / 整数 / 十进制字符串 */
+function AsBigInt(v)     { return Tagged(ArgumentTypeBigInt, v); }
+/** Go *big.Float，text 为十进制文本，prec 为二进制精度（缺省 64） */
+function AsBigFloat(text, prec) { return Tagged(ArgumentTypeBigFloat, { text: String(text), prec: prec || 64 }); }
 
 /* ---- 在 Encode / typeofTag 中识别 Tagged 对象 ---- */
 
@@ -678,14 +949,17 @@ const _patchedEncode = function (arg) {
         return encode_ag(tag, new Uint8Array(val));
       case ArgumentTypeSlice:
       case ArgumentTypeMap:
-      case ArgumentTypeStruct: {
-        const s = jsonMarshalFallback(val);
-        return encode_ag(ArgumentTypeString, new TextEncoder().encode(s));
-      }
+      case ArgumentTypeStruct:
+        return _agExt ? encode_composite(tag, val) : encode_legacy(tag, val);
       case ArgumentTypeNil:
         return encode_ag(ArgumentTypeNil, null);
       case ArgumentTypeCustom:
         return encode_ag(ArgumentTypeCustom, _serialize(val));
+      case ArgumentTypeTime:
+      case ArgumentTypeDuration:
+      case ArgumentTypeBigInt:
+      case ArgumentTypeBigFloat:
+        return _agExt ? encode_ext(tag, val) : encode_legacy(tag, val);
     }
     // 未知标签 → 走原值自动推断
     return _origEncode(val);
@@ -693,6 +967,21 @@ const _patchedEncode = function (arg) {
   return _origEncode(arg);
 };
 
+/**
+ * 同 EncodeArg，但复合类型编码为嵌套帧、Date/大整数等写扩展标签帧；只发给协商了 ag_ext 的对端
+ * @param {any} arg
+ * @returns {Uint8Array}
+ */
+function EncodeArgExt(arg) {
+  const prev = _agExt;
+  _agExt = true;
+  try {
+    return _patchedEncode(arg);
+  } finally {
+    _agExt = prev;
+  }
+}
+
 /* ============================================================
  *  导出（兼容 ESM / CJS / 浏览器全局）
  * ============================================================ */
@@ -703,6 +992,9 @@ const AGExports = {
   ArgumentMagic2,
   ArgumentHeaderSize,
   ArgumentMaxDataSize,
+  ArgumentExtHeaderSize,
+  ArgumentMaxExtDataSize,
+  ArgumentTypeExtended,
   ArgumentType,
   ArgumentTypeNil,
   ArgumentTypeBool,
@@ -727,6 +1019,10 @@ const AGExports = {
   ArgumentTypeMap,
   ArgumentTypeStruct,
   ArgumentTypeCustom,
+  ArgumentTypeTime,
+  ArgumentTypeDuration,
+  ArgumentTypeBigInt,
+  ArgumentTypeBigFloat,
 
   // 错误
   ErrAgTooShort,
@@ -761,11 +1057,16 @@ const AGExports = {
   // 编码/解码
   typeofTag: _patchedTypeofTag,
   EncodeArg: _patchedEncode,
+  EncodeArgExt,
   Encoder: _patchedEncode,
   DecodeArg,
   Json,
   get_value,
   get_value_from,
+  encode_composite,
+  decode_composite,
+  encode_ext,
+  decode_ext,
 
   // 显式类型包装
   Tagged,
@@ -781,6 +1082,9 @@ const AGExports = {
   AsUintptr,
   AsFloat32,
   AsComplex64,
+  AsDuration,
+  AsBigInt,
+  AsBigFloat,
 };
 
 // CommonJS / Node.js
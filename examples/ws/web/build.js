@@ -161,6 +161,8 @@ const GLOBAL_EXPORTS = [
     'BinaryMessage',
     'LongMessage',
     'CRC',
+    'WideSlice',
+    'MaxNarrowSlices',
     'DataSlice',
     'newOption',
     'get_header_size',
@@ -170,6 +172,8 @@ const GLOBAL_EXPORTS = [
     'CheckCRC',        // 同名函数有两个，JS 的 var/function 提升会让后者覆盖前者
     'Encode',
     'Decode',
+    'EncodeSlice',
+    'DecodeSlice',
     'GetCrC',          // 兼容 slice.js 原 module.exports 的写法（未定义但在导出里写了）
     'SliceMessage',
     'Slice',
@@ -217,6 +221,10 @@ const GLOBAL_EXPORTS = [
     'ACTION_REPLY_SUCCESS',
     'ACTION_REPLY_ERROR',
     'ACTION_BROADCAST',
+    'ACTION_HELLO',
+    'PROTOCOL_VERSION',
+    'SUBPROTOCOL_TEXT',
+    'SUBPROTOCOL_BIN',
     'FN_HEADER_SIZE',
     'FN_MAGIC_1',
     'FN_MAGIC_2',
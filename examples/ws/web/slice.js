@@ -3,14 +3,17 @@ const TextMessage = 0x01;
 const BinaryMessage = 0x02;
 const LongMessage = 0x80;
 const CRC = 0x40;
+// 分片总数与索引各占 4 字节（默认各 1 字节，最多 255 片），对齐 Go frame.WideSlice
+const WideSlice = 0x20;
+const MaxNarrowSlices = 0xFF;
 
 // DataSlice class
 class DataSlice {
     constructor(p, n, t, i, s, d) {
         this.P = p; // Message type
         this.N = n; // Slice name (2 bytes)
-        this.T = t; // Total slices
-        this.I = i; // Current slice index
+        this.T = t; // Total slices (uint32)
+        this.I = i; // Current slice index (uint32)
         this.S = s; // Total message size
         this.D = d; // Slice data
     }
@@ -23,8 +26,12 @@ class DataSlice {
         return this.P & 0x40;
     }
 
+    IsWide() {
+        return (this.P & WideSlice) !== 0 || this.T > MaxNarrowSlices || this.I > MaxNarrowSlices;
+    }
+
     Encode(opts = []) {
-        return Encode(this, opts);
+        return EncodeSlice(this, opts);
     }
 }
 
@@ -38,12 +45,13 @@ function newOption(opts = []) {
     return opt;
 }
 
-function get_header_size(lLen, checkCRC) {
+function get_header_size(lLen, checkCRC, wide) {
     let c = 0x02;
     if (!checkCRC) {
         c = 0;
     }
-    return lLen + 1 + 2 + 1 + 1 + c;
+    const n = wide ? 4 : 1;
+    return lLen + 1 + 2 + n + n + c;
 }
 
 
@@ -79,18 +87,32 @@ function CheckCRC(src, crc) {
     return IsComplete(getCRC(src), crc);
 }
 
-// Encode function
+// Encode / Decode 与 fn.js 同名，打包到同一作用域后会被 fn.js 覆盖，
+// SDK 内部一律使用 EncodeSlice / DecodeSlice
 function Encode(s, opts = []) {
+    return EncodeSlice(s, opts);
+}
+
+function Decode(b) {
+    return DecodeSlice(b);
+}
+
+// EncodeSlice 二进制分片帧（对齐 Go frame.Encode）
+function EncodeSlice(s, opts = []) {
     const opt = newOption(opts);
     // 1byte type
     // 2byte name
-    // 1byte slices
-    // 1byte index
+    // 1/4byte slices
+    // 1/4byte index
     // 2/4byte size
     // 0/2byte crc
     // nbyte data
     let tag = s.P & 0x3F;
     let checkCRC = (s.P & CRC) === CRC;
+    const wide = (tag & WideSlice) !== 0 || s.T > MaxNarrowSlices || s.I > MaxNarrowSlices;
+    if (wide) {
+        tag |= WideSlice;
+    }
     const l = s.D.length;
     
     // Determine if long message tag is needed based on length
@@ -106,7 +128,7 @@ function Encode(s, opts = []) {
         tag |= CRC;
     }
 
-    const headerSize = get_header_size(opt.LengthSize, checkCRC);
+    const headerSize = get_header_size(opt.LengthSize, checkCRC, wide);
     const buf = new Uint8Array(headerSize + l);
     
     buf[0] = tag;
@@ -116,15 +138,22 @@ function Encode(s, opts = []) {
     buf[1] = nameBytes[0] || 0;
     buf[2] = nameBytes[1] || 0;
     
-    buf[3] = s.T;
-    buf[4] = s.I;
+    const dv = new DataView(buf.buffer, buf.byteOffset, buf.byteLength);
+    let off = 5;
+    if (wide) {
+        dv.setUint32(3, s.T >>> 0, false);
+        dv.setUint32(7, s.I >>> 0, false);
+        off = 11;
+    } else {
+        buf[3] = s.T;
+        buf[4] = s.I;
+    }
     
     // Write length
-    const dv = new DataView(buf.buffer, buf.byteOffset, buf.byteLength);
     if (opt.LengthSize === 2) {
-        dv.setUint16(5, l, false); // Big endian
+        dv.setUint16(off, l, false); // Big endian
     } else {
-        dv.setUint32(5, l, false); // Big endian
+        dv.setUint32(off, l, false); // Big endian
     }
     
     // Write CRC if needed
@@ -140,9 +169,9 @@ function Encode(s, opts = []) {
     return buf;
 }
 
-// Decode function
-function Decode(b) {
-    let headerSize = get_header_size(2, false);
+// DecodeSlice 解析二进制分片帧（对齐 Go frame.Decode）
+function DecodeSlice(b) {
+    let headerSize = get_header_size(2, false, false);
     if (b.length < headerSize) {
         throw new Error("invalid slice data length");
     }
@@ -160,22 +189,32 @@ function Decode(b) {
         opt.CheckCRC = true;
     }
     
-    const dv = new DataView(b.buffer, b.byteOffset, b.byteLength);
-    let l = dv.getUint16(5, false); // Big endian
-    if (opt.LengthSize === 4) {
-        l = dv.getUint32(5, false); // Big endian
-    }
-    
-    headerSize = get_header_size(opt.LengthSize, opt.CheckCRC);
-    if (b.length < headerSize + l) {
+    const wide = (tag & WideSlice) !== 0;
+    headerSize = get_header_size(opt.LengthSize, opt.CheckCRC, wide);
+    if (b.length < headerSize) {
         throw new Error("invalid slice data length");
     }
     
+    const dv = new DataView(b.buffer, b.byteOffset, b.byteLength);
     const s = new DataSlice();
     s.P = tag;
     s.N = new TextDecoder().decode(b.subarray(1, 3));
-    s.T = b[3];
-    s.I = b[4];
+    let off = 5;
+    if (wide) {
+        s.T = dv.getUint32(3, false);
+        s.I = dv.getUint32(7, false);
+        off = 11;
+    } else {
+        s.T = b[3];
+        s.I = b[4];
+    }
+    let l = dv.getUint16(off, false); // Big endian
+    if (opt.LengthSize === 4) {
+        l = dv.getUint32(off, false); // Big endian
+    }
+    if (b.length < headerSize + l) {
+        throw new Error("invalid slice data length");
+    }
     s.S = l;
     
     const data = b.subarray(headerSize, headerSize + l);
@@ -199,9 +238,13 @@ if (typeof module !== 'undefined' && module.exports) {
         BinaryMessage,
         LongMessage,
         CRC,
+        WideSlice,
+        MaxNarrowSlices,
         DataSlice,
         Encode,
         Decode,
+        EncodeSlice,
+        DecodeSlice,
         GetCrC,
         IsComplete,
         CheckCRC,
@@ -9,7 +9,7 @@
  *   5. sock_rpc_v3.js
  *
  * Build order exactly matches: examples/ws/web/index_v3.html L17-L21
- * Generated at: 2026-08-17T09:39:31.322Z
+ * Generated at: 2026-10-19T17:25:01.786Z
  */
 (function () {
 "use strict";
@@ -523,14 +523,17 @@ const TextMessage = 0x01;
 const BinaryMessage = 0x02;
 const LongMessage = 0x80;
 const CRC = 0x40;
+// 分片总数与索引各占 4 字节（默认各 1 字节，最多 255 片），对齐 Go frame.WideSlice
+const WideSlice = 0x20;
+const MaxNarrowSlices = 0xFF;
 
 // DataSlice class
 class DataSlice {
     constructor(p, n, t, i, s, d) {
         this.P = p; // Message type
         this.N = n; // Slice name (2 bytes)
-        this.T = t; // Total slices
-        this.I = i; // Current slice index
+        this.T = t; // Total slices (uint32)
+        this.I = i; // Current slice index (uint32)
         this.S = s; // Total message size
         this.D = d; // Slice data
     }
@@ -543,8 +546,12 @@ class DataSlice {
         return this.P & 0x40;
     }
 
+    IsWide() {
+        return (this.P & WideSlice) !== 0 || this.T > MaxNarrowSlices || this.I > MaxNarrowSlices;
+    }
+
     Encode(opts = []) {
-        return Encode(this, opts);
+        return EncodeSlice(this, opts);
     }
 }
 
@@ -558,12 +565,13 @@ function newOption(opts = []) {
     return opt;
 }
 
-function get_header_size(lLen, checkCRC) {
+function get_header_size(lLen, checkCRC, wide) {
     let c = 0x02;
     if (!checkCRC) {
         c = 0;
     }
-    return lLen + 1 + 2 + 1 + 1 + c;
+    const n = wide ? 4 : 1;
+    return lLen + 1 + 2 + n + n + c;
 }
 
 
@@ -599,18 +607,32 @@ function CheckCRC(src, crc) {
     return IsComplete(getCRC(src), crc);
 }
 
-// Encode function
+// Encode / Decode 与 fn.js 同名，打包到同一作用域后会被 fn.js 覆盖，
+// SDK 内部一律使用 EncodeSlice / DecodeSlice
 function Encode(s, opts = []) {
+    return EncodeSlice(s, opts);
+}
+
+function Decode(b) {
+    return DecodeSlice(b);
+}
+
+// EncodeSlice 二进制分片帧（对齐 Go frame.Encode）
+function EncodeSlice(s, opts = []) {
     const opt = newOption(opts);
     // 1byte type
     // 2byte name
-    // 1byte slices
-    // 1byte index
+    // 1/4byte slices
+    // 1/4byte index
     // 2/4byte size
     // 0/2byte crc
     // nbyte data
     let tag = s.P & 0x3F;
     let checkCRC = (s.P & CRC) === CRC;
+    const wide = (tag & WideSlice) !== 0 || s.T > MaxNarrowSlices || s.I > MaxNarrowSlices;
+    if (wide) {
+        tag |= WideSlice;
+    }
     const l = s.D.length;
     
     // Determine if long message tag is needed based on length
@@ -626,7 +648,7 @@ function Encode(s, opts = []) {
         tag |= CRC;
     }
 
-    const headerSize = get_header_size(opt.LengthSize, checkCRC);
+    const headerSize = get_header_size(opt.LengthSize, checkCRC, wide);
     const buf = new Uint8Array(headerSize + l);
     
     buf[0] = tag;
@@ -636,15 +658,22 @@ function Encode(s, opts = []) {
     buf[1] = nameBytes[0] || 0;
     buf[2] = nameBytes[1] || 0;
     
-    buf[3] = s.T;
-    buf[4] = s.I;
+    const dv = new DataView(buf.buffer, buf.byteOffset, buf.byteLength);
+    let off = 5;
+    if (wide) {
+        dv.setUint32(3, s.T >>> 0, false);
+        dv.setUint32(7, s.I >>> 0, false);
+        off = 11;
+    } else {
+        buf[3] = s.T;
+        buf[4] = s.I;
+    }
     
     // Write length
-    const dv = new DataView(buf.buffer, buf.byteOffset, buf.byteLength);
     if (opt.LengthSize === 2) {
-        dv.setUint16(5, l, false); // Big endian
+        dv.setUint16(off, l, false); // Big endian
     } else {
-        dv.setUint32(5, l, false); // Big endian
+        dv.setUint32(off, l, false); // Big endian
     }
     
     // Write CRC if needed
@@ -660,9 +689,9 @@ function Encode(s, opts = []) {
     return buf;
 }
 
-// Decode function
-function Decode(b) {
-    let headerSize = get_header_size(2, false);
+// DecodeSlice 解析二进制分片帧（对齐 Go frame.Decode）
+function DecodeSlice(b) {
+    let headerSize = get_header_size(2, false, false);
     if (b.length < headerSize) {
         throw new Error("invalid slice data length");
     }
@@ -680,22 +709,32 @@ function Decode(b) {
         opt.CheckCRC = true;
     }
     
-    const dv = new DataView(b.buffer, b.byteOffset, b.byteLength);
-    let l = dv.getUint16(5, false); // Big endian
-    if (opt.LengthSize === 4) {
-        l = dv.getUint32(5, false); // Big endian
-    }
-    
-    headerSize = get_header_size(opt.LengthSize, opt.CheckCRC);
-    if (b.length < headerSize + l) {
+    const wide = (tag & WideSlice) !== 0;
+    headerSize = get_header_size(opt.LengthSize, opt.CheckCRC, wide);
+    if (b.length < headerSize) {
         throw new Error("invalid slice data length");
     }
     
+    const dv = new DataView(b.buffer, b.byteOffset, b.byteLength);
     const s = new DataSlice();
     s.P = tag;
     s.N = new TextDecoder().decode(b.subarray(1, 3));
-    s.T = b[3];
-    s.I = b[4];
+    let off = 5;
+    if (wide) {
+        s.T = dv.getUint32(3, false);
+        s.I = dv.getUint32(7, false);
+        off = 11;
+    } else {
+        s.T = b[3];
+        s.I = b[4];
+    }
+    let l = dv.getUint16(off, false); // Big endian
+    if (opt.LengthSize === 4) {
+        l = dv.getUint32(off, false); // Big endian
+    }
+    if (b.length < headerSize + l) {
+        throw new Error("invalid slice data length");
+    }
     s.S = l;
     
     const data = b.subarray(headerSize, headerSize + l);
@@ -719,9 +758,13 @@ if (typeof module !== 'undefined' && module.exports) {
         BinaryMessage,
         LongMessage,
         CRC,
+        WideSlice,
+        MaxNarrowSlices,
         DataSlice,
         Encode,
         Decode,
+        EncodeSlice,
+        DecodeSlice,
         GetCrC,
         IsComplete,
         CheckCRC,
@@ -737,16 +780,26 @@ if (typeof module !== 'undefined' && module.exports) {
  *
  * 帧格式：
  *   MAGIC  :p   2 byte   0x3A 0x70  (ASCII ":p")
- *   TYPE   t    1 byte   ArgumentType* 枚举
- *   LEN    l    2 byte   big endian，Value 字节数 (0~65535)
+ *   TYPE   t    1 byte   ArgumentType* 枚举，最高位为扩展长度标志 ArgumentTypeExtended
+ *   LEN    l    2 byte   big endian，Value 字节数 (0~65535)；扩展帧为 4 byte
  *   VALUE  d    l byte   payload，长度 = l
  *
- * 总帧最小 5 字节，最大 5 + 65535 = 65540 字节。
+ * 总帧最小 5 字节。Value 不超过 65535 字节时写普通帧，超过时写扩展帧
+ * （TYPE 置 0x80、LEN 4 字节、帧头 7 字节），与 Go 版一致。
  *
  * 说明：
  *   - 整数编码采用 Big Endian + 简易压缩（去除前导 0，负数保留 FF 符号位）
  *   - Float32/Float64/Complex64/Complex128 采用 Little Endian（与 Go 版一致）
- *   - 复合类型（Array/Object/非 Uint8Array）降级为 JSON 字符串以 String 帧传输
+ *   - 复合类型的 VALUE 为嵌套的 AG 帧序列（与 Go 版一致）：
+ *       Slice   元素帧 e0 e1 ...              Array
+ *       Map     键帧 值帧 ...（按键帧字节排序） Map 实例
+ *       Struct  字段名(String 帧) 值帧 ...     普通对象（值为 undefined 的属性省略）
+ *     旧版本的复合帧 VALUE 为 JSON 文本，解码时按首字节区分
+ *   - 扩展标量（与 Go 版 ext.go 一致）：
+ *       Time      秒(int64 BE) 纳秒(uint32 BE) UTC 偏移秒(int32 BE) 时区名   ←→ Date（毫秒精度，时区不保留）
+ *       Duration  纳秒数，同 Int64                                          ←→ AsDuration / BigInt
+ *       BigInt    符号(1 byte) 绝对值(BE)                                   ←→ 超出 64 位的 BigInt / AsBigInt
+ *       BigFloat  精度(uint32 BE) 十进制文本                                ←→ AsBigFloat / string
  *   - 64 位整数使用 BigInt 保证精度；若运行环境不支持 BigInt，将退化为 Number
  */
 
@@ -760,11 +813,14 @@ const ArgumentMagic1 = 0x3A;
 const ArgumentMagic2 = 0x70;
 /** 帧头大小 = Magic(2) + Type(1) + Length(2) = 5 */
 const ArgumentHeaderSize = 2 + 1 + 2;
-/** Value 段最大字节数上限（>此值时报 ErrAgDataTooLarge）。
- *  注意：LEN 字段是 16 位无符号，实际最大可表达 65535；当 data 长度恰好 65536 时
- *  不会触发错误但会使 LEN 溢位（与 Go 版行为完全一致）。
- */
+/** 普通帧 Value 长度上限（不含），达到时改写扩展帧 */
 const ArgumentMaxDataSize = 1 << 16;
+/** 扩展帧帧头 = Magic(2) + Type(1) + Length(4) = 7 */
+const ArgumentExtHeaderSize = 2 + 1 + 4;
+/** 扩展帧 Value 最大字节数（>此值时报 ErrAgDataTooLarge） */
+const ArgumentMaxExtDataSize = 0xFFFFFFFF;
+/** TYPE 字节的扩展长度标志 */
+const ArgumentTypeExtended = 0x80;
 
 /**
  * 基本类型枚举（与 Go 原语一一对应，0x01~0x1F 为基础标量；0x20~0x3F 为复合/扩展）
@@ -800,6 +856,11 @@ const ArgumentType = {
   Map: 21,
   Struct: 22,
   Custom: 23,
+
+  Time: 24,
+  Duration: 25,
+  BigInt: 26,
+  BigFloat: 27,
 };
 
 /** 兼容 Go 常量命名的别名 */
@@ -826,6 +887,10 @@ const ArgumentTypeSlice = ArgumentType.Slice;
 const ArgumentTypeMap = ArgumentType.Map;
 const ArgumentTypeStruct = ArgumentType.Struct;
 const ArgumentTypeCustom = ArgumentType.Custom;
+const ArgumentTypeTime = ArgumentType.Time;
+const ArgumentTypeDuration = ArgumentType.Duration;
+const ArgumentTypeBigInt = ArgumentType.BigInt;
+const ArgumentTypeBigFloat = ArgumentType.BigFloat;
 
 /* ============================================================
  *  错误对象
@@ -834,7 +899,7 @@ const ArgumentTypeCustom = ArgumentType.Custom;
 const ErrAgTooShort = new Error('ag: payload too short for header');
 const ErrAgBadMagic = new Error('ag: bad magic header, expect :p');
 const ErrAgLengthMismatch = new Error('ag: payload length mismatch');
-const ErrAgDataTooLarge = new Error(`ag: data length exceeds ${ArgumentMaxDataSize}`);
+const ErrAgDataTooLarge = new Error(`ag: data length exceeds ${ArgumentMaxExtDataSize}`);
 const ErrAgUnknownType = new Error('ag: unknown type tag');
 const ErrAgInvalidHeader = new Error('ag: invalid header');
 
@@ -848,26 +913,46 @@ const ErrAgInvalidHeader = new Error('ag: invalid header');
  * @param {number} t 类型标签 (ArgumentType*)
  * @param {Uint8Array|null} data Value 段
  * @returns {Uint8Array}
- * @throws {ErrAgDataTooLarge} 数据超过 65535 字节
+ * @throws {ErrAgDataTooLarge} 数据超过 ArgumentMaxExtDataSize 字节
  */
 function encode_ag(t, data) {
   const payload = data || new Uint8Array(0);
-  if (payload.length > ArgumentMaxDataSize) {
+  if (payload.length > ArgumentMaxExtDataSize) {
     throw ErrAgDataTooLarge;
   }
-  const out = new Uint8Array(ArgumentHeaderSize + payload.length);
+  const ext = payload.length >= ArgumentMaxDataSize;
+  const hdr = ext ? ArgumentExtHeaderSize : ArgumentHeaderSize;
+  const out = new Uint8Array(hdr + payload.length);
   out[0] = ArgumentMagic1;
   out[1] = ArgumentMagic2;
-  out[2] = t & 0xFF;
-  // 写入 2 字节 BigEndian 长度
-  out[3] = (payload.length >> 8) & 0xFF;
-  out[4] = payload.length & 0xFF;
+  out[2] = ext ? (t | ArgumentTypeExtended) & 0xFF : t & 0xFF;
+  // 写入 BigEndian 长度：普通帧 2 字节，扩展帧 4 字节
+  if (ext) {
+    new DataView(out.buffer).setUint32(3, payload.length, false);
+  } else {
+    out[3] = (payload.length >> 8) & 0xFF;
+    out[4] = payload.length & 0xFF;
+  }
   if (payload.length > 0) {
-    out.set(payload, ArgumentHeaderSize);
+    out.set(payload, hdr);
   }
   return out;
 }
 
+/**
+ * 解析帧头长度与 Value 长度；buf 以 magic 开头且不短于普通帧头
+ * @param {Uint8Array} buf
+ * @returns {{hdr: number, length: number}|null} 扩展帧头不完整时返回 null
+ */
+function frame_size(buf) {
+  if ((buf[2] & ArgumentTypeExtended) === 0) {
+    return { hdr: ArgumentHeaderSize, length: (buf[3] << 8) | buf[4] };
+  }
+  if (buf.length < ArgumentExtHeaderSize) return null;
+  const length = new DataView(buf.buffer, buf.byteOffset, buf.byteLength).getUint32(3, false);
+  return { hdr: ArgumentExtHeaderSize, length };
+}
+
 /**
  * O(1) 校验帧完整性（magic + length 匹配）
  * @param {Uint8Array|ArrayBuffer|Array<number>} b
@@ -877,8 +962,8 @@ function IsArgument(b) {
   const buf = _asU8(b);
   if (!buf || buf.length < ArgumentHeaderSize) return false;
   if (buf[0] !== ArgumentMagic1 || buf[1] !== ArgumentMagic2) return false;
-  const length = (buf[3] << 8) | buf[4];
-  return buf.length === ArgumentHeaderSize + length;
+  const fs = frame_size(buf);
+  return fs !== null && buf.length === fs.hdr + fs.length;
 }
 
 /**
@@ -890,9 +975,9 @@ function Validate(b) {
   const buf = _asU8(b);
   if (!buf || buf.length < ArgumentHeaderSize) return ErrAgTooShort;
   if (buf[0] !== ArgumentMagic1 || buf[1] !== ArgumentMagic2) return ErrAgBadMagic;
-  const length = (buf[3] << 8) | buf[4];
-  if (length > ArgumentMaxDataSize) return ErrAgDataTooLarge;
-  if (buf.length !== ArgumentHeaderSize + length) return ErrAgLengthMismatch;
+  const fs = frame_size(buf);
+  if (fs === null) return ErrAgTooShort;
+  if (buf.length !== fs.hdr + fs.length) return ErrAgLengthMismatch;
   return null;
 }
 
@@ -905,7 +990,7 @@ function ag_get_frame(b) {
   const err = Validate(b);
   if (err) throw err;
   const buf = _asU8(b);
-  const t = buf[2];
+  const t = buf[2] & ~ArgumentTypeExtended;
   const v =  ag_get_data(buf);
   return { t, v };
 }
@@ -923,11 +1008,11 @@ function ag_get_frame(b) {
  */
 function ag_get_data(b) {
   const buf = _asU8(b);
-  const length = (buf[3] << 8) | buf[4];
+  const { hdr, length } = frame_size(buf);
   if (length === 0) return null;
   const raw = new Uint8Array(length);
-  raw.set(buf.subarray(ArgumentHeaderSize, ArgumentHeaderSize + length), 0);
-  const t = buf[2];
+  raw.set(buf.subarray(hdr, hdr + length), 0);
+  const t = buf[2] & ~ArgumentTypeExtended;
   switch (t) {
     case ArgumentTypeUint8:
     case ArgumentTypeInt8:
@@ -943,6 +1028,7 @@ function ag_get_data(b) {
     case ArgumentTypeInt:
     case ArgumentTypeUint:
     case ArgumentTypeUintptr:
+    case ArgumentTypeDuration:
       return zeroExtend8byte(raw);
   }
   return raw;
@@ -985,9 +1071,11 @@ function Decoder(b) {
  *   - null/undefined → Nil
  *   - string         → String
  *   - Uint8Array     → Bytes
- *   - Array          → Slice（实际编码会降级 JSON → String）
- *   - plain Object   → Struct/Map（实际编码会降级 JSON → String）
- *   - BigInt         → Int64 / Uint64（根据符号）
+ *   - Array          → Slice
+ *   - Map            → Map
+ *   - plain Object   → Struct
+ *   - BigInt         → Int64 / Uint64（根据符号），超出 64 位为 BigInt
+ *   - Date           → Time
  * @param {any} arg
  * @returns {number} ArgumentType*
  */
@@ -996,6 +1084,7 @@ function typeofTag(arg) {
   if (typeof arg === 'boolean') return ArgumentTypeBool;
   if (typeof arg === 'string') return ArgumentTypeString;
   if (typeof arg === 'bigint') {
+    if (arg < -(BigInt(1) << BigInt(63)) || arg >= (BigInt(1) << BigInt(64))) return ArgumentTypeBigInt;
     return arg < 0 ? ArgumentTypeInt64 : ArgumentTypeUint64;
   }
   if (typeof arg === 'number') {
@@ -1007,7 +1096,9 @@ function typeofTag(arg) {
     return ArgumentTypeFloat64;
   }
   if (arg instanceof Uint8Array) return ArgumentTypeBytes;
+  if (arg instanceof Date) return ArgumentTypeTime;
   if (Array.isArray(arg)) return ArgumentTypeSlice;
+  if (arg instanceof Map) return ArgumentTypeMap;
   if (arg && typeof arg === 'object') {
     // 判断是否复数对象 {real,imag}
     if ('real' in arg && 'imag' in arg &&
@@ -1024,10 +1115,36 @@ function typeofTag(arg) {
  *  编码：EncodeArg / Encoder / Json
  * ============================================================ */
 
+/**
+ * 对端协商了 ag_ext 时由 EncodeArgExt 临时置 true；默认按旧版格式编码，
+ * 复合类型为 JSON 的 String 帧，Date/大整数等扩展标量为 JSON 文本，旧版服务端都能解析
+ */
+let _agExt = false;
+
+/**
+ * 旧版格式：复合类型与 Date 为 JSON 的 String 帧，其余扩展标量为 Custom 帧
+ * @param {number} t
+ * @param {any} val
+ * @returns {Uint8Array}
+ */
+function encode_legacy(t, val) {
+  switch (t) {
+    case ArgumentTypeSlice:
+    case ArgumentTypeMap:
+    case ArgumentTypeStruct:
+    case ArgumentTypeTime: {
+      const s = jsonMarshalFallback(val instanceof Map ? Object.fromEntries(val) : val);
+      return encode_ag(ArgumentTypeString, new TextEncoder().encode(s));
+    }
+  }
+  return encode_ag(ArgumentTypeCustom, _serialize(val));
+}
+
 /**
  * 把任意值按类型编码为一帧 AG
  *   - 标量走原语编码
- *   - 复合 (Array/Object/非 Uint8Array) 走 JSON fallback 映射成 String 帧
+ *   - 复合 (Array/Map/Object) 与扩展标量按旧版格式编码（见 encode_legacy）；
+ *     对端协商了 ag_ext 时用 EncodeArgExt，复合类型编码为嵌套帧，见 encode_composite
  * @param {any} arg
  * @returns {Uint8Array}
  * @throws {ErrAgDataTooLarge}
@@ -1096,15 +1213,126 @@ function EncodeArg(arg) {
 
     case ArgumentTypeSlice:
     case ArgumentTypeMap:
-    case ArgumentTypeStruct: {
-      const s = jsonMarshalFallback(arg);
-      return encode_ag(ArgumentTypeString, new TextEncoder().encode(s));
-    }
+    case ArgumentTypeStruct:
+      return _agExt ? encode_composite(t, arg) : encode_legacy(t, arg);
+
+    case ArgumentTypeTime:
+    case ArgumentTypeBigInt:
+      return _agExt ? encode_ext(t, arg) : encode_legacy(t, arg);
   }
   // 兜底：Custom 类型走 serialize
   return encode_ag(ArgumentTypeCustom, _serialize(arg));
 }
 
+/**
+ * 复合类型编码：VALUE 为嵌套帧的拼接，元素经 EncodeArg（含 Tagged 识别）递归编码
+ *   - Slice  ：Array 或可迭代对象，逐个元素成帧
+ *   - Map    ：Map 实例或普通对象，键帧 + 值帧，按键帧字节排序保证结果稳定
+ *   - Struct ：普通对象，字段名 String 帧 + 值帧，按属性顺序；undefined 省略
+ * @param {number} t ArgumentTypeSlice / Map / Struct
+ * @param {any} val
+ * @returns {Uint8Array}
+ */
+function encode_composite(t, val) {
+  const enc = _patchedEncode;
+  const frames = [];
+  switch (t) {
+    case ArgumentTypeSlice:
+      for (const e of Array.from(val)) frames.push(enc(e));
+      break;
+    case ArgumentTypeMap: {
+      const entries = val instanceof Map ? Array.from(val.entries()) : Object.entries(val);
+      const pairs = entries.map(([k, v]) => [enc(k), enc(v)]);
+      pairs.sort((a, b) => _compareBytes(a[0], b[0]));
+      for (const [k, v] of pairs) frames.push(k, v);
+      break;
+    }
+    default:
+      for (const [k, v] of Object.entries(val)) {
+        if (v === undefined) continue;
+        frames.push(encode_ag(ArgumentTypeString, new TextEncoder().encode(k)), enc(v));
+      }
+  }
+  return encode_ag(t, _concatBytes(frames));
+}
+
+/**
+ * 扩展标量编码
+ *   - Time     ：Date / 毫秒时间戳 / 可被 Date 解析的字符串，按 UTC 写入
+ *   - Duration ：纳秒数（number / bigint）
+ *   - BigInt   ：bigint / 整数 number / 十进制字符串
+ *   - BigFloat ：十进制文本，或 {text, prec}（prec 缺省 64）
+ * @param {number} t
+ * @param {any} val
+ * @returns {Uint8Array}
+ */
+function encode_ext(t, val) {
+  switch (t) {
+    case ArgumentTypeTime: {
+      const ms = (val instanceof Date ? val : new Date(val)).getTime();
+      const sec = Math.floor(ms / 1000);
+      const name = new TextEncoder().encode('UTC');
+      const out = new Uint8Array(16 + name.length);
+      const dv = new DataView(out.buffer);
+      dv.setBigInt64(0, BigInt(sec), false);
+      dv.setUint32(8, (ms - sec * 1000) * 1e6, false);
+      dv.setInt32(12, 0, false);
+      out.set(name, 16);
+      return encode_ag(t, out);
+    }
+    case ArgumentTypeDuration:
+      return encode_ag(t, _intToByteImpl(_toBig(val)));
+    case ArgumentTypeBigInt: {
+      let n = BigInt(val);
+      const neg = n < 0;
+      if (neg) n = -n;
+      let hex = n === BigInt(0) ? '' : n.toString(16);
+      if (hex.length % 2) hex = '0' + hex;
+      const out = new Uint8Array(1 + hex.length / 2);
+      out[0] = neg ? 1 : 0;
+      for (let i = 0; i < hex.length / 2; i++) out[1 + i] = parseInt(hex.substr(i * 2, 2), 16);
+      return encode_ag(t, out);
+    }
+    case ArgumentTypeBigFloat: {
+      const text = new TextEncoder().encode(String(val && typeof val === 'object' ? val.text : val));
+      const out = new Uint8Array(4 + text.length);
+      new DataView(out.buffer).setUint32(0, (val && typeof val === 'object' && val.prec) || 64, false);
+      out.set(text, 4);
+      return encode_ag(t, out);
+    }
+  }
+  throw ErrAgUnknownType;
+}
+
+/**
+ * 扩展标量解码：Time → Date，Duration/BigInt → bigint，BigFloat → 十进制文本
+ * @param {number} t
+ * @param {Uint8Array|null} v
+ * @returns {any}
+ */
+function decode_ext(t, v) {
+  switch (t) {
+    case ArgumentTypeTime: {
+      if (!v || v.length < 16) throw ErrAgLengthMismatch;
+      const dv = new DataView(v.buffer, v.byteOffset, v.byteLength);
+      const sec = Number(dv.getBigInt64(0, false));
+      return new Date(sec * 1000 + Math.floor(dv.getUint32(8, false) / 1e6));
+    }
+    case ArgumentTypeDuration:
+      return to_int64(v);
+    case ArgumentTypeBigInt: {
+      if (!v || v.length === 0) throw ErrAgLengthMismatch;
+      let n = BigInt(0);
+      for (let i = 1; i < v.length; i++) n = (n << BigInt(8)) | BigInt(v[i]);
+      return v[0] === 1 ? -n : n;
+    }
+    case ArgumentTypeBigFloat:
+      if (!v || v.length < 4) throw ErrAgLengthMismatch;
+      return new TextDecoder().decode(v.subarray(4));
+  }
+  throw ErrAgUnknownType;
+}
+
 /** Encoder = EncodeArg 别名 */
 function Encoder(arg) { return EncodeArg(arg); }
 
@@ -1209,6 +1437,17 @@ function get_value_from(t, v) {
       out.set(v, 0);
       return out;
     }
+    case ArgumentTypeSlice:
+    case ArgumentTypeMap:
+    case ArgumentTypeStruct:
+      return decode_composite(t, v);
+
+    case ArgumentTypeTime:
+    case ArgumentTypeDuration:
+    case ArgumentTypeBigInt:
+    case ArgumentTypeBigFloat:
+      return decode_ext(t, v);
+
     case ArgumentTypeCustom:{
        if (!v) return new Uint8Array(0);
       const out = new Uint8Array(v.length);
@@ -1223,6 +1462,54 @@ function get_value_from(t, v) {
   throw ErrAgUnknownType;
 }
 
+/**
+ * 复合类型解码：Slice → Array；Struct → 普通对象；
+ * Map → 键全为字符串时为普通对象，否则为 Map 实例。
+ * VALUE 首字节不是 magic 时按旧版 JSON 文本解析
+ * @param {number} t
+ * @param {Uint8Array|null} v
+ * @returns {any}
+ */
+function decode_composite(t, v) {
+  if (v && v.length > 0 && v[0] !== ArgumentMagic1) {
+    return JSON.parse(new TextDecoder().decode(v));
+  }
+  const items = split_frames(v).map(get_value);
+  if (t === ArgumentTypeSlice) return items;
+  if (items.length % 2 !== 0) throw ErrAgLengthMismatch;
+  let plain = true;
+  for (let i = 0; i < items.length; i += 2) {
+    if (typeof items[i] !== 'string') { plain = false; break; }
+  }
+  const out = plain ? {} : new Map();
+  for (let i = 0; i < items.length; i += 2) {
+    if (plain) out[items[i]] = items[i + 1];
+    else out.set(items[i], items[i + 1]);
+  }
+  return out;
+}
+
+/**
+ * 把复合帧的 VALUE 切分为一组完整帧
+ * @param {Uint8Array|null} v
+ * @returns {Uint8Array[]}
+ */
+function split_frames(v) {
+  const frames = [];
+  let off = 0;
+  while (v && off < v.length) {
+    if (v.length - off < ArgumentHeaderSize) throw ErrAgLengthMismatch;
+    if (v[off] !== ArgumentMagic1 || v[off + 1] !== ArgumentMagic2) throw ErrAgBadMagic;
+    const fs = frame_size(v.subarray(off));
+    if (fs === null) throw ErrAgLengthMismatch;
+    const end = off + fs.hdr + fs.length;
+    if (end > v.length) throw ErrAgLengthMismatch;
+    frames.push(v.subarray(off, end));
+    off = end;
+  }
+  return frames;
+}
+
 /**
  * 从完整帧中取出 {type,value} 后再还原 JS 值
  * @param {Uint8Array} b
@@ -1250,6 +1537,23 @@ function DecodeArg(b) {
  *  内部工具：输入规范化 → Uint8Array
  * ============================================================ */
 
+function _concatBytes(parts) {
+  let n = 0;
+  for (const p of parts) n += p.length;
+  const out = new Uint8Array(n);
+  let off = 0;
+  for (const p of parts) { out.set(p, off); off += p.length; }
+  return out;
+}
+
+function _compareBytes(a, b) {
+  const n = Math.min(a.length, b.length);
+  for (let i = 0; i < n; i++) {
+    if (a[i] !== b[i]) return a[i] - b[i];
+  }
+  return a.length - b.length;
+}
+
 function _asU8(b) {
   if (!b) return null;
   if (b instanceof Uint8Array) return b;
@@ -1299,6 +1603,10 @@ function typeName(t) {
     case ArgumentTypeMap:        return 'map';
     case ArgumentTypeStruct:     return 'struct';
     case ArgumentTypeCustom:     return 'custom';
+    case ArgumentTypeTime:       return 'time';
+    case ArgumentTypeDuration:   return 'duration';
+    case ArgumentTypeBigInt:     return 'bigint';
+    case ArgumentTypeBigFloat:   return 'bigfloat';
   }
   return `unknown(${t})`;
 }
@@ -1336,6 +1644,12 @@ function AsUint64(v)  { return Tagged(ArgumentTypeUint64, v); }
 function AsUintptr(v) { return Tagged(ArgumentTypeUintptr, v); }
 function AsFloat32(v) { return Tagged(ArgumentTypeFloat32, v); }
 function AsComplex64(re, im) { return Tagged(ArgumentTypeComplex64, { real: re, imag: im }); }
+/** Go time.Duration，ns 为纳秒数 */
+function AsDuration(ns)  { return Tagged(ArgumentTypeDuration, ns); }
+/** Go *big.Int，v 为 bigint / 整数 / 十进制字符串 */
+function AsBigInt(v)     { return Tagged(ArgumentTypeBigInt, v); }
+/** Go *big.Float，text 为十进制文本，prec 为二进制精度（缺省 64） */
+function AsBigFloat(text, prec) { return Tagged(ArgumentTypeBigFloat, { text: String(text), prec: prec || 64 }); }
 
 /* ---- 在 Encode / typeofTag 中识别 Tagged 对象 ---- */
 
@@ -1412,14 +1726,17 @@ const _patchedEncode = function (arg) {
         return encode_ag(tag, new Uint8Array(val));
       case ArgumentTypeSlice:
       case ArgumentTypeMap:
-      case ArgumentTypeStruct: {
-        const s = jsonMarshalFallback(val);
-        return encode_ag(ArgumentTypeString, new TextEncoder().encode(s));
-      }
+      case ArgumentTypeStruct:
+        return _agExt ? encode_composite(tag, val) : encode_legacy(tag, val);
       case ArgumentTypeNil:
         return encode_ag(ArgumentTypeNil, null);
       case ArgumentTypeCustom:
         return encode_ag(ArgumentTypeCustom, _serialize(val));
+      case ArgumentTypeTime:
+      case ArgumentTypeDuration:
+      case ArgumentTypeBigInt:
+      case ArgumentTypeBigFloat:
+        return _agExt ? encode_ext(tag, val) : encode_legacy(tag, val);
     }
     // 未知标签 → 走原值自动推断
     return _origEncode(val);
@@ -1427,6 +1744,21 @@ const _patchedEncode = function (arg) {
   return _origEncode(arg);
 };
 
+/**
+ * 同 EncodeArg，但复合类型编码为嵌套帧、Date/大整数等写扩展标签帧；只发给协商了 ag_ext 的对端
+ * @param {any} arg
+ * @returns {Uint8Array}
+ */
+function EncodeArgExt(arg) {
+  const prev = _agExt;
+  _agExt = true;
+  try {
+    return _patchedEncode(arg);
+  } finally {
+    _agExt = prev;
+  }
+}
+
 /* ============================================================
  *  导出（兼容 ESM / CJS / 浏览器全局）
  * ============================================================ */
@@ -1437,6 +1769,9 @@ const AGExports = {
   ArgumentMagic2,
   ArgumentHeaderSize,
   ArgumentMaxDataSize,
+  ArgumentExtHeaderSize,
+  ArgumentMaxExtDataSize,
+  ArgumentTypeExtended,
   ArgumentType,
   ArgumentTypeNil,
   ArgumentTypeBool,
@@ -1461,6 +1796,10 @@ const AGExports = {
   ArgumentTypeMap,
   ArgumentTypeStruct,
   ArgumentTypeCustom,
+  ArgumentTypeTime,
+  ArgumentTypeDuration,
+  ArgumentTypeBigInt,
+  ArgumentTypeBigFloat,
 
   // 错误
   ErrAgTooShort,
@@ -1495,11 +1834,16 @@ const AGExports = {
   // 编码/解码
   typeofTag: _patchedTypeofTag,
   EncodeArg: _patchedEncode,
+  EncodeArgExt,
   Encoder: _patchedEncode,
   DecodeArg,
   Json,
   get_value,
   get_value_from,
+  encode_composite,
+  decode_composite,
+  encode_ext,
+  decode_ext,
 
   // 显式类型包装
   Tagged,
@@ -1515,6 +1859,9 @@ const AGExports = {
   AsUintptr,
   AsFloat32,
   AsComplex64,
+  AsDuration,
+  AsBigInt,
+  AsBigFloat,
 };
 
 // CommonJS / Node.js
@@ -2091,7 +2438,8 @@ if (typeof self !== 'undefined' && typeof WorkerGlobalScope !== 'undefined' && s
  *   ④ DataSlice 分片 (TextMessage 模式)：
  *        JSON 对象 { P: 1, N: "00", T: totalSlices, I: curIdx, S: totalBytes, D: base64(sliceBytes) }
  *        → Go 通过 json.Unmarshal(message, *DataSlice) 解析：D 字段 []byte 自动 Base64 解码
- *        → Go 切片默认 512 字节 (utils.go slicesTextSend)；片名 %02d 递增，过 99 归零 (utils.go getSliceName)
+ *        → Go 切片默认 512 字节 (utils.go slicesTextSend)；片名两位 [0-9A-Za-z] 循环 (utils.go getSliceName)
+ *        → 不同片名的分片可以交错到达，两端都按片名重组 (decoder/frame/reassemble.go)
  *        → 对应文件：decoder/frame/utils.go Split / FromType + 本目录 slice.js
  *
  * 【反向协议栈 (服务器 → 浏览器)】
@@ -2101,6 +2449,12 @@ if (typeof self !== 'undefined' && typeof WorkerGlobalScope !== 'undefined' && s
  *     · ACTION_CALL        (0x01)：服务器反向调用客户端 Bind 的本地服务
  *                                  Body = JsonCallObject JSON → Args[i] Base64→Uint8Array→AG.DecodeArg→JS 值
  *     · ACTION_BROADCAST  (0xFF)：无 ID，Data (自动 AG.DecodeArg) 广播给所有 OnMessage 监听器
+ *     · ACTION_HELLO      (0x04)：服务端对本端 Hello 的回复，Data = JSON 能力声明 (message.Hello)
+ *
+ * 【能力协商 (Hello)】
+ *   open 后第一条消息即 ACTION_HELLO(ID=0)，声明协议版本 / 编解码器 / 特性；服务端回复自己的 Hello，
+ *   双方取交集后触发 onready(caps)，之后 Capabilities() 可用。旧服务端不回复，2 秒后按 { legacy: true } 触发；
+ *   服务端未声明 binary 时改用文本分片发送。
  *
  * 【浏览器环境依赖】
  *   需要在 HTML 中按以下顺序加载前置文件：
@@ -2122,6 +2476,15 @@ const ACTION_REPLY_SUCCESS = 0x02; // 响应：成功
 const ACTION_REPLY_ERROR   = 0x03; // 响应：错误
 const ACTION_INVALID       = 0x00; // 非法操作 (占位)
 const ACTION_BROADCAST     = 0xFF; // 广播 (无 ID)
+const ACTION_HELLO         = 0x04; // 能力协商 (ID=0，Data 为 JSON)
+
+// 协议版本，对齐 message.ProtocolVersion
+const PROTOCOL_VERSION = 3;
+// WebSocket 子协议，对齐 Go nrpc/wsocket/subprotocol.go
+const SUBPROTOCOL_TEXT = 'sloth.v3.text'; // 只用 JSON 文本分片
+const SUBPROTOCOL_BIN  = 'sloth.v3.bin';  // 可用二进制分片
+// 等待服务端 Hello 的时间，对齐 Go nrpc/wsocket/hello.go helloTimeout
+const _HELLO_TIMEOUT_MS = 2000;
 
 /* ============================================================
  *  DataSlice 模式常量 (对齐 nrpc/wsocket/utils.go L16-L22)
@@ -2129,6 +2492,8 @@ const ACTION_BROADCAST     = 0xFF; // 广播 (无 ID)
 const _SLICE_TEXT   = 0x01; // 同 Go websocket.TextMessage
 const _SLICE_BINARY = 0x02; // 同 Go websocket.BinaryMessage
 const _DEFAULT_SLICE_SIZE = 512; // 与 V2 sock_rpc_v2.js + Go ws_client 保持一致
+/** 片名字符集 (对齐 Go nrpc/wsocket/utils.go sliceNameChars) */
+const _SLICE_NAME_CHARS = '0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz';
 
 /* ============================================================
  *  TLV CRC 帧（最外层包装层，对齐 vendor/github.com/w6xian/tlv 包）
@@ -2438,7 +2803,11 @@ class SockRpcV3 {
      *        timeoutMs: 10000,     // 单次 Call 超时 (ms)，对齐 Go ws_client.writeWait/readWait
      *        sliceSize: 512,       // 分片大小，必须 ∈ [1024 反直觉？不—Go Split 里 max(sliceSize,1024)，见下注释]
      *        autoSliceText: true,  // 是否用 TextMessage JSON 分片发送 (Go server 默认支持)
-     *        onOpen / onClose / onMessage / onError,
+     *        binaryFrames: true,   // 用 BinaryMessage 发送 frame.Encode 二进制分片（D 不再 base64，体积约小 1/4）；
+     *                              // 服务端收到二进制分片后回复也改用二进制。只认文本分片的旧服务端设为 false
+     *        subprotocols: [...],  // 握手时声明的子协议，默认 ['sloth.v3.bin','sloth.v3.text']（binaryFrames=false 时只有 text）；
+     *                              // 不认子协议的旧服务端设为 false（浏览器在服务端不回子协议时会断开）
+     *        onOpen / onReady / onClose / onMessage / onError,   // onReady(caps)：能力协商完成
      *    })
      *  注意：Go decoder/frame/utils.go#L14 有 `sliceSize = max(sliceSize, 1024)` 的下界，
      *       但 V2 sock_rpc_v2.js 历史默认 512，为避免用户旧代码迁移时行为突变，
@@ -2452,6 +2821,16 @@ class SockRpcV3 {
         this.timeoutMs  = opts.timeoutMs  || 10000;
         this.sliceSize  = opts.sliceSize  || _DEFAULT_SLICE_SIZE;
         this.autoSliceText = opts.autoSliceText !== false;
+        this.binaryFrames = opts.binaryFrames !== false;
+        if (opts.subprotocols === false) {
+            this.subprotocols = [];
+        } else if (Array.isArray(opts.subprotocols)) {
+            this.subprotocols = opts.subprotocols.slice();
+        } else {
+            this.subprotocols = this.binaryFrames ? [SUBPROTOCOL_BIN, SUBPROTOCOL_TEXT] : [SUBPROTOCOL_TEXT];
+        }
+        // 服务端选定的子协议，旧服务端为 ''
+        this.subprotocol = '';
         // ---------- 断线重连策略（对齐 V2 SockRpc 的指数退避 + Stop 后禁止）----------
         //   - opts.autoReconnect:  开启后，close 事件如果不是 Stop()/manual close 就自动重试
         //   - opts.reconnectDelay: 首两次重试间隔 ms，默认 1000
@@ -2472,14 +2851,16 @@ class SockRpcV3 {
         this.sock      = null;
         this.connected = false;
 
-        // 事件监听：onopen / onclose / onmessage / onerror (对齐 V2 Code 枚举)
+        // 事件监听：onopen / onready / onclose / onmessage / onerror (对齐 V2 Code 枚举)
         this.listeners = {
             onopen:    [],
+            onready:   [],
             onclose:   [],
             onmessage: [],
             onerror:   [],
         };
         if (_isFunction(opts.onOpen))    this.OnOpen(opts.onOpen);
+        if (_isFunction(opts.onReady))   this.OnReady(opts.onReady);
         if (_isFunction(opts.onClose))   this.OnClose(opts.onClose);
         if (_isFunction(opts.onMessage)) this.OnMessage(opts.onMessage);
         if (_isFunction(opts.onError))   this.OnError(opts.onError);
@@ -2522,7 +2903,7 @@ class SockRpcV3 {
         } catch(_e){/*ignore*/}
         this._nextId    = this._hasBigInt ? BigInt(1) : 1;
 
-        // 分片片名生成：%02d 递增，过 99→0 (对齐 Go nrpc/wsocket/utils.go getSliceName)
+        // 分片片名生成：两位 [0-9A-Za-z] 递增，共 3844 个循环 (对齐 Go nrpc/wsocket/utils.go getSliceName)
         this._sliceCounter = 0;
 
         // 待响应的调用表：id(字符串/BigInt字符串化) → { okCb, errCb, resolve, reject, timer }
@@ -2533,6 +2914,12 @@ class SockRpcV3 {
 
         // 分片缓存清理定时器 (每 60s 扫一次，扔掉 >60s 没收完的碎片)
         this._reapTimer = null;
+
+        // 本次连接协商出的能力 (Hello)，onready 之前为 null
+        this.capabilities = null;
+        this._helloTimer  = null;
+        // 服务端是否接收二进制分片（Hello 中未声明 binary 时为 false）
+        this._peerBinary  = true;
     }
 
     /* ============================================================
@@ -2541,6 +2928,8 @@ class SockRpcV3 {
 
     /** 监听 open 事件（连接就绪） */
     OnOpen(listener, options)    { this.AddEvent('onopen',    listener, options); }
+    /** 监听 ready 事件（能力协商完成，参数为 Capabilities()） */
+    OnReady(listener, options)   { this.AddEvent('onready',   listener, options); }
     /** 监听 close 事件（连接断开） */
     OnClose(listener, options)   { this.AddEvent('onclose',   listener, options); }
     /** 监听 message 事件（广播 / 非 FN 原始消息 / 调试） */
@@ -2550,7 +2939,7 @@ class SockRpcV3 {
 
     /**
      * 通用事件注册（对齐 V2 SockRpc.AddEvent）
-     * @param {'onopen'|'onclose'|'onmessage'|'onerror'} type
+     * @param {'onopen'|'onready'|'onclose'|'onmessage'|'onerror'} type
      * @param {Function} listener
      */
     AddEvent(type, listener /*, options*/) {
@@ -2620,7 +3009,9 @@ class SockRpcV3 {
                 this._scheduleReconnect('WebSocket API missing');
                 return null;
             }
-            this.sock = new WebSocketCtor(this.addr);
+            this.sock = this.subprotocols.length > 0
+                ? new WebSocketCtor(this.addr, this.subprotocols)
+                : new WebSocketCtor(this.addr);
             this.sock.binaryType = binaryType;
 
             // open
@@ -2630,6 +3021,9 @@ class SockRpcV3 {
                 this._reconnectAttempts = 0;
                 this._currentDelay      = this.reconnectDelay;
                 this._manualClose       = false;
+                this.subprotocol        = this.sock.protocol || '';
+                // 第一条消息：Hello
+                this._sendHello();
                 if (_isFunction(opts.ready)) {
                     try { opts.ready.call(this, this.sock); } catch (e) { this._logErr('ready cb throw', e); }
                 }
@@ -2653,6 +3047,7 @@ class SockRpcV3 {
                 this.connected   = false;
                 this.sock        = null;
                 this._stopReapTimer();
+                this._resetHello();
                 this._rejectAllPending(new Error(`connection closed (code=${evt && evt.code ? evt.code : 'n/a'})`));
                 this._reassembly.clear();
                 // 一次性标志位：先把 manual 清掉，防止下一次连接又被误判
@@ -2688,10 +3083,11 @@ class SockRpcV3 {
         }
         this.connected = false;
         this._stopReapTimer();
+        this._resetHello();
         this._rejectAllPending(new Error('stopped by user'));
         this._reassembly.clear();
         // 清空监听器 (对齐 V2 Stop)
-        this.listeners = { onopen: [], onclose: [], onmessage: [], onerror: [] };
+        this.listeners = { onopen: [], onready: [], onclose: [], onmessage: [], onerror: [] };
     }
 
     /**
@@ -2960,11 +3356,11 @@ class SockRpcV3 {
         return cur;
     }
 
-    /** 下一个片名 (两位十进制 00-99 循环) */
+    /** 下一个片名 (两位 [0-9A-Za-z]，3844 个循环；对端按片名重组，交错到达的分片互不干扰) */
     _nextSliceName() {
         const n = this._sliceCounter;
-        this._sliceCounter = (this._sliceCounter + 1) % 100;
-        return String(n).padStart(2, '0');
+        this._sliceCounter = (this._sliceCounter + 1) % (_SLICE_NAME_CHARS.length * _SLICE_NAME_CHARS.length);
+        return _SLICE_NAME_CHARS[Math.floor(n / _SLICE_NAME_CHARS.length)] + _SLICE_NAME_CHARS[n % _SLICE_NAME_CHARS.length];
     }
 
     /* ============================================================
@@ -2985,10 +3381,10 @@ class SockRpcV3 {
                     argsBytes.push(new Uint8Array(arg));
                 } else {
                     // 普通 Uint8Array → 作为 Bytes 类型 AG.Encode
-                    argsBytes.push(this._ag().EncodeArg(arg));
+                    argsBytes.push(this._encodeArg(arg));
                 }
             } else {
-                argsBytes.push(this._ag().EncodeArg(arg));
+                argsBytes.push(this._encodeArg(arg));
             }
         }
 
@@ -3026,7 +3422,7 @@ class SockRpcV3 {
     }
 
     /* ============================================================
-     * 内部实现：发送 FN 帧 (自动分片，对齐 Go slicesTextSend)
+     * 内部实现：发送 FN 帧 (自动分片，对齐 Go slicesSend：默认二进制分片，binaryFrames=false 时为文本分片)
      * ============================================================ */
     _sendFnFrame(fnBytes) {
         if (!this.sock) throw new Error('ws not connected');
@@ -3049,6 +3445,17 @@ class SockRpcV3 {
         if (totalSize % sliceSize !== 0) totalSlice++;
         // Go Split 有 min/max clamp；这里 JS 直接切就行，不做限制
 
+        // 二进制分片：BinaryMessage + slice.js EncodeSlice（对齐 Go slicesBinarySend）
+        if (this.binaryFrames && this._peerBinary && typeof EncodeSlice === 'function' && typeof DataSlice === 'function') {
+            for (let i = 0; i < totalSlice; i++) {
+                const start = i * sliceSize;
+                const end = Math.min(start + sliceSize, totalSize);
+                const ds = new DataSlice(_SLICE_BINARY, name, totalSlice, i, totalSize, tlvWrapped.subarray(start, end));
+                this.sock.send(EncodeSlice(ds));
+            }
+            return;
+        }
+
         for (let i = 0; i < totalSlice; i++) {
             const start = i * sliceSize;
             const end = Math.min(start + sliceSize, totalSize);
@@ -3057,14 +3464,15 @@ class SockRpcV3 {
             // 注意：必须严格对齐 Go decoder/frame/slice.go#L16-L29 的 json tag（全部小写）:
             //   P byte   `json:"p"`
             //   N string `json:"n"`
-            //   T byte   `json:"t"`
-            //   I byte   `json:"i"`
+            //   T uint32 `json:"t"`
+            //   I uint32 `json:"i"`
             //   S uint32 `json:"s"`
             //   D []byte `json:"d"`  → JSON 序列化为 Base64 字符串
             const sliceObj = {
-                p: _SLICE_TEXT,
+                // 超过 255 片标记 WideSlice(0x20)，对齐 Go frame.Split
+                p: totalSlice > 0xFF ? (_SLICE_TEXT | 0x20) : _SLICE_TEXT,
                 n: name,
-                t: totalSlice, // byte：>255 片溢出？Go 版 T 是 byte，总分片数不能 >255
+                t: totalSlice,
                 i: i,
                 s: totalSize,
                 d: _u8ToB64(chunk), // 与 Go json.Marshal DataSlice{D:[]byte} 自动 Base64 一致
@@ -3109,8 +3517,9 @@ class SockRpcV3 {
                 // BinaryMessage → 用 slice.js 的 Decode
                 const buf = data instanceof ArrayBuffer ? new Uint8Array(data) : data;
                 try {
-                    if (window.DataSlice && typeof window.DataSlice === 'function' && typeof Decode === 'function') {
-                        slice = Decode(buf);
+                    // 注意：打包后裸名 Decode 是 fn.js 的，分片解析用 slice.js 的 DecodeSlice
+                    if (typeof DecodeSlice === 'function') {
+                        slice = DecodeSlice(buf);
                     } else {
                         // 没加载 slice.js → 当作完整非分片消息
                         this._maybeHandleFullFrame(buf);
@@ -3307,6 +3716,12 @@ class SockRpcV3 {
                 this._emit('onmessage', val);
                 break;
             }
+            case ACTION_HELLO: {
+                let peer = null;
+                try { peer = JSON.parse(new TextDecoder().decode(body)); } catch (e) { this._logErr('bad hello', e); }
+                if (_isObject(peer)) this._settle(peer);
+                break;
+            }
             default:
                 // 未知 Action → 按原样 onmessage
                 this._emit('onmessage', fnBytes);
@@ -3460,6 +3875,68 @@ class SockRpcV3 {
         }
     }
 
+    /* ============================================================
+     * 能力协商 (Hello)，对齐 Go nrpc/wsocket/hello.go + message/hello.go
+     * ============================================================ */
+
+    /** 协商出的能力：{ protocol, peer_version, codecs, compress, max_frame, features, legacy }，onready 前为 null */
+    Capabilities() { return this.capabilities; }
+
+    /** 服务端选定的子协议 (SUBPROTOCOL_TEXT / SUBPROTOCOL_BIN)，旧服务端为 '' */
+    Subprotocol() { return this.subprotocol; }
+
+    /** 本端能力：浏览器端不做应用层压缩、不支持 fn 帧加密 */
+    _localHello() {
+        const features = [];
+        if (this.binaryFrames && this.subprotocol !== SUBPROTOCOL_TEXT) features.push('binary');
+        features.push('wide_slice', 'ag_ext');
+        return { protocol: PROTOCOL_VERSION, codecs: ['ag'], features: features };
+    }
+
+    _sendHello() {
+        this._resetHello();
+        // 服务端没有选定 sloth 子协议（旧版）时不会回 Hello，直接按旧版处理
+        if (!this.subprotocol) { this._settle(null); return; }
+        this._helloTimer = setTimeout(() => { this._helloTimer = null; this._settle(null); }, _HELLO_TIMEOUT_MS);
+        const fn = this._fn();
+        if (!fn) return;
+        const body = new TextEncoder().encode(JSON.stringify(this._localHello()));
+        const { buffer, error } = fn.Encode(ACTION_HELLO, 0, body);
+        if (error) { this._logErr('fn.Encode hello failed', error); return; }
+        try { this._sendFnFrame(buffer); } catch (e) { this._logErr('send hello failed', e); }
+    }
+
+    /** 取双方能力交集并触发 onready，每个连接一次；peer 为 null 表示旧服务端 */
+    _settle(peer) {
+        if (this.capabilities) return;
+        if (this._helloTimer) { clearTimeout(this._helloTimer); this._helloTimer = null; }
+        let caps;
+        if (!peer) {
+            caps = { legacy: true };
+        } else {
+            const local = this._localHello();
+            const both = (a, b) => (a || []).filter((v) => (b || []).indexOf(v) >= 0);
+            caps = {
+                protocol:     Math.min(local.protocol, peer.protocol | 0),
+                peer_version: peer.version || '',
+                codecs:       both(local.codecs, peer.codecs),
+                compress:     [],
+                max_frame:    peer.max_frame || 0,
+                features:     both(local.features, peer.features),
+            };
+            this._peerBinary = caps.features.indexOf('binary') >= 0;
+        }
+        this.capabilities = caps;
+        this._emit('onready', caps);
+    }
+
+    _resetHello() {
+        if (this._helloTimer) { clearTimeout(this._helloTimer); this._helloTimer = null; }
+        this.capabilities = null;
+        // 选定 text 子协议时只发文本分片
+        this._peerBinary  = this.subprotocol !== SUBPROTOCOL_TEXT;
+    }
+
     /** 给服务器回一个 ACTION_REPLY_SUCCESS (id, data[]byte) */
     _replySuccess(id, dataU8) {
         const fn = this._fn();
@@ -3478,6 +3955,14 @@ class SockRpcV3 {
         try { this._sendFnFrame(buffer); } catch (e) { /* ignore */ }
     }
 
+    /** 参数 AG 编码：服务端协商了 ag_ext 时用嵌套帧，否则为旧版服务端也能解析的 JSON 形式 */
+    _encodeArg(arg) {
+        const ag = this._ag();
+        const caps = this.capabilities;
+        if (caps && caps.features && caps.features.indexOf('ag_ext') >= 0) return ag.EncodeArgExt(arg);
+        return ag.EncodeArg(arg);
+    }
+
     /* ============================================================
      * 内部实现：AG 解码兜底 (非 AG 帧原样返回 Uint8Array)
      * ============================================================ */
@@ -3670,7 +4155,8 @@ class SockRpcV3 {
 
     const _bag = {
         SockRpcV3,
-        ACTION_CALL, ACTION_REPLY_SUCCESS, ACTION_REPLY_ERROR, ACTION_INVALID, ACTION_BROADCAST,
+        ACTION_CALL, ACTION_REPLY_SUCCESS, ACTION_REPLY_ERROR, ACTION_INVALID, ACTION_BROADCAST, ACTION_HELLO,
+        PROTOCOL_VERSION, SUBPROTOCOL_TEXT, SUBPROTOCOL_BIN,
         TlvValue, TlvValueAsText, TlvValueAsJson, TlvJson,
     };
     const root = (typeof window !== 'undefined') ? window :
@@ -3762,6 +4248,8 @@ class SockRpcV3 {
   try { if (typeof BinaryMessage !== "undefined") __root__["BinaryMessage"] = BinaryMessage; } catch (_e) {}
   try { if (typeof LongMessage !== "undefined") __root__["LongMessage"] = LongMessage; } catch (_e) {}
   try { if (typeof CRC !== "undefined") __root__["CRC"] = CRC; } catch (_e) {}
+  try { if (typeof WideSlice !== "undefined") __root__["WideSlice"] = WideSlice; } catch (_e) {}
+  try { if (typeof MaxNarrowSlices !== "undefined") __root__["MaxNarrowSlices"] = MaxNarrowSlices; } catch (_e) {}
   try { if (typeof DataSlice !== "undefined") __root__["DataSlice"] = DataSlice; } catch (_e) {}
   try { if (typeof newOption !== "undefined") __root__["newOption"] = newOption; } catch (_e) {}
   try { if (typeof get_header_size !== "undefined") __root__["get_header_size"] = get_header_size; } catch (_e) {}
@@ -3771,6 +4259,8 @@ class SockRpcV3 {
   try { if (typeof CheckCRC !== "undefined") __root__["CheckCRC"] = CheckCRC; } catch (_e) {}
   try { if (typeof Encode !== "undefined") __root__["Encode"] = Encode; } catch (_e) {}
   try { if (typeof Decode !== "undefined") __root__["Decode"] = Decode; } catch (_e) {}
+  try { if (typeof EncodeSlice !== "undefined") __root__["EncodeSlice"] = EncodeSlice; } catch (_e) {}
+  try { if (typeof DecodeSlice !== "undefined") __root__["DecodeSlice"] = DecodeSlice; } catch (_e) {}
   try { if (typeof GetCrC !== "undefined") __root__["GetCrC"] = GetCrC; } catch (_e) {}
   try { if (typeof SliceMessage !== "undefined") __root__["SliceMessage"] = SliceMessage; } catch (_e) {}
   try { if (typeof Slice !== "undefined") __root__["Slice"] = Slice; } catch (_e) {}
@@ -3814,6 +4304,10 @@ class SockRpcV3 {
   try { if (typeof ACTION_REPLY_SUCCESS !== "undefined") __root__["ACTION_REPLY_SUCCESS"] = ACTION_REPLY_SUCCESS; } catch (_e) {}
   try { if (typeof ACTION_REPLY_ERROR !== "undefined") __root__["ACTION_REPLY_ERROR"] = ACTION_REPLY_ERROR; } catch (_e) {}
   try { if (typeof ACTION_BROADCAST !== "undefined") __root__["ACTION_BROADCAST"] = ACTION_BROADCAST; } catch (_e) {}
+  try { if (typeof ACTION_HELLO !== "undefined") __root__["ACTION_HELLO"] = ACTION_HELLO; } catch (_e) {}
+  try { if (typeof PROTOCOL_VERSION !== "undefined") __root__["PROTOCOL_VERSION"] = PROTOCOL_VERSION; } catch (_e) {}
+  try { if (typeof SUBPROTOCOL_TEXT !== "undefined") __root__["SUBPROTOCOL_TEXT"] = SUBPROTOCOL_TEXT; } catch (_e) {}
+  try { if (typeof SUBPROTOCOL_BIN !== "undefined") __root__["SUBPROTOCOL_BIN"] = SUBPROTOCOL_BIN; } catch (_e) {}
   try { if (typeof FN_HEADER_SIZE !== "undefined") __root__["FN_HEADER_SIZE"] = FN_HEADER_SIZE; } catch (_e) {}
   try { if (typeof FN_MAGIC_1 !== "undefined") __root__["FN_MAGIC_1"] = FN_MAGIC_1; } catch (_e) {}
   try { if (typeof FN_MAGIC_2 !== "undefined") __root__["FN_MAGIC_2"] = FN_MAGIC_2; } catch (_e) {}
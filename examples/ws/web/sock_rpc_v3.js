@@ -32,7 +32,8 @@
  *   ④ DataSlice 分片 (TextMessage 模式)：
  *        JSON 对象 { P: 1, N: "00", T: totalSlices, I: curIdx, S: totalBytes, D: base64(sliceBytes) }
  *        → Go 通过 json.Unmarshal(message, *DataSlice) 解析：D 字段 []byte 自动 Base64 解码
- *        → Go 切片默认 512 字节 (utils.go slicesTextSend)；片名 %02d 递增，过 99 归零 (utils.go getSliceName)
+ *        → Go 切片默认 512 字节 (utils.go slicesTextSend)；片名两位 [0-9A-Za-z] 循环 (utils.go getSliceName)
+ *        → 不同片名的分片可以交错到达，两端都按片名重组 (decoder/frame/reassemble.go)
  *        → 对应文件：decoder/frame/utils.go Split / FromType + 本目录 slice.js
  *
  * 【反向协议栈 (服务器 → 浏览器)】
@@ -42,6 +43,12 @@
  *     · ACTION_CALL        (0x01)：服务器反向调用客户端 Bind 的本地服务
  *                                  Body = JsonCallObject JSON → Args[i] Base64→Uint8Array→AG.DecodeArg→JS 值
  *     · ACTION_BROADCAST  (0xFF)：无 ID，Data (自动 AG.DecodeArg) 广播给所有 OnMessage 监听器
+ *     · ACTION_HELLO      (0x04)：服务端对本端 Hello 的回复，Data = JSON 能力声明 (message.Hello)
+ *
+ * 【能力协商 (Hello)】
+ *   open 后第一条消息即 ACTION_HELLO(ID=0)，声明协议版本 / 编解码器 / 特性；服务端回复自己的 Hello，
+ *   双方取交集后触发 onready(caps)，之后 Capabilities() 可用。旧服务端不回复，2 秒后按 { legacy: true } 触发；
+ *   服务端未声明 binary 时改用文本分片发送。
  *
  * 【浏览器环境依赖】
  *   需要在 HTML 中按以下顺序加载前置文件：
@@ -63,6 +70,15 @@ const ACTION_REPLY_SUCCESS = 0x02; // 响应：成功
 const ACTION_REPLY_ERROR   = 0x03; // 响应：错误
 const ACTION_INVALID       = 0x00; // 非法操作 (占位)
 const ACTION_BROADCAST     = 0xFF; // 广播 (无 ID)
+const ACTION_HELLO         = 0x04; // 能力协商 (ID=0，Data 为 JSON)
+
+// 协议版本，对齐 message.ProtocolVersion
+const PROTOCOL_VERSION = 3;
+// WebSocket 子协议，对齐 Go nrpc/wsocket/subprotocol.go
+const SUBPROTOCOL_TEXT = 'sloth.v3.text'; // 只用 JSON 文本分片
+const SUBPROTOCOL_BIN  = 'sloth.v3.bin';  // 可用二进制分片
+// 等待服务端 Hello 的时间，对齐 Go nrpc/wsocket/hello.go helloTimeout
+const _HELLO_TIMEOUT_MS = 2000;
 
 /* ============================================================
  *  DataSlice 模式常量 (对齐 nrpc/wsocket/utils.go L16-L22)
@@ -70,6 +86,8 @@ const ACTION_BROADCAST     = 0xFF; // 广播 (无 ID)
 const _SLICE_TEXT   = 0x01; // 同 Go websocket.TextMessage
 const _SLICE_BINARY = 0x02; // 同 Go websocket.BinaryMessage
 const _DEFAULT_SLICE_SIZE = 512; // 与 V2 sock_rpc_v2.js + Go ws_client 保持一致
+/** 片名字符集 (对齐 Go nrpc/wsocket/utils.go sliceNameChars) */
+const _SLICE_NAME_CHARS = '0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz';
 
 /* ============================================================
  *  TLV CRC 帧（最外层包装层，对齐 vendor/github.com/w6xian/tlv 包）
@@ -379,7 +397,11 @@ class SockRpcV3 {
      *        timeoutMs: 10000,     // 单次 Call 超时 (ms)，对齐 Go ws_client.writeWait/readWait
      *        sliceSize: 512,       // 分片大小，必须 ∈ [1024 反直觉？不—Go Split 里 max(sliceSize,1024)，见下注释]
      *        autoSliceText: true,  // 是否用 TextMessage JSON 分片发送 (Go server 默认支持)
-     *        onOpen / onClose / onMessage / onError,
+     *        binaryFrames: true,   // 用 BinaryMessage 发送 frame.Encode 二进制分片（D 不再 base64，体积约小 1/4）；
+     *                              // 服务端收到二进制分片后回复也改用二进制。只认文本分片的旧服务端设为 false
+     *        subprotocols: [...],  // 握手时声明的子协议，默认 ['sloth.v3.bin','sloth.v3.text']（binaryFrames=false 时只有 text）；
+     *                              // 不认子协议的旧服务端设为 false（浏览器在服务端不回子协议时会断开）
+     *        onOpen / onReady / onClose / onMessage / onError,   // onReady(caps)：能力协商完成
      *    })
      *  注意：Go decoder/frame/utils.go#L14 有 `sliceSize = max(sliceSize, 1024)` 的下界，
      *       但 V2 sock_rpc_v2.js 历史默认 512，为避免用户旧代码迁移时行为突变，
@@ -393,6 +415,16 @@ class SockRpcV3 {
         this.timeoutMs  = opts.timeoutMs  || 10000;
         this.sliceSize  = opts.sliceSize  || _DEFAULT_SLICE_SIZE;
         this.autoSliceText = opts.autoSliceText !== false;
+        this.binaryFrames = opts.binaryFrames !== false;
+        if (opts.subprotocols === false) {
+            this.subprotocols = [];
+        } else if (Array.isArray(opts.subprotocols)) {
+            this.subprotocols = opts.subprotocols.slice();
+        } else {
+            this.subprotocols = this.binaryFrames ? [SUBPROTOCOL_BIN, SUBPROTOCOL_TEXT] : [SUBPROTOCOL_TEXT];
+        }
+        // 服务端选定的子协议，旧服务端为 ''
+        this.subprotocol = '';
         // ---------- 断线重连策略（对齐 V2 SockRpc 的指数退避 + Stop 后禁止）----------
         //   - opts.autoReconnect:  开启后，close 事件如果不是 Stop()/manual close 就自动重试
         //   - opts.reconnectDelay: 首两次重试间隔 ms，默认 1000
@@ -413,14 +445,16 @@ class SockRpcV3 {
         this.sock      = null;
         this.connected = false;
 
-        // 事件监听：onopen / onclose / onmessage / onerror (对齐 V2 Code 枚举)
+        // 事件监听：onopen / onready / onclose / onmessage / onerror (对齐 V2 Code 枚举)
         this.listeners = {
             onopen:    [],
+            onready:   [],
             onclose:   [],
             onmessage: [],
             onerror:   [],
         };
         if (_isFunction(opts.onOpen))    this.OnOpen(opts.onOpen);
+        if (_isFunction(opts.onReady))   this.OnReady(opts.onReady);
         if (_isFunction(opts.onClose))   this.OnClose(opts.onClose);
         if (_isFunction(opts.onMessage)) this.OnMessage(opts.onMessage);
         if (_isFunction(opts.onError))   this.OnError(opts.onError);
@@ -463,7 +497,7 @@ class SockRpcV3 {
         } catch(_e){/*ignore*/}
         this._nextId    = this._hasBigInt ? BigInt(1) : 1;
 
-        // 分片片名生成：%02d 递增，过 99→0 (对齐 Go nrpc/wsocket/utils.go getSliceName)
+        // 分片片名生成：两位 [0-9A-Za-z] 递增，共 3844 个循环 (对齐 Go nrpc/wsocket/utils.go getSliceName)
         this._sliceCounter = 0;
 
         // 待响应的调用表：id(字符串/BigInt字符串化) → { okCb, errCb, resolve, reject, timer }
@@ -474,6 +508,12 @@ class SockRpcV3 {
 
         // 分片缓存清理定时器 (每 60s 扫一次，扔掉 >60s 没收完的碎片)
         this._reapTimer = null;
+
+        // 本次连接协商出的能力 (Hello)，onready 之前为 null
+        this.capabilities = null;
+        this._helloTimer  = null;
+        // 服务端是否接收二进制分片（Hello 中未声明 binary 时为 false）
+        this._peerBinary  = true;
     }
 
     /* ============================================================
@@ -482,6 +522,8 @@ class SockRpcV3 {
 
     /** 监听 open 事件（连接就绪） */
     OnOpen(listener, options)    { this.AddEvent('onopen',    listener, options); }
+    /** 监听 ready 事件（能力协商完成，参数为 Capabilities()） */
+    OnReady(listener, options)   { this.AddEvent('onready',   listener, options); }
     /** 监听 close 事件（连接断开） */
     OnClose(listener, options)   { this.AddEvent('onclose',   listener, options); }
     /** 监听 message 事件（广播 / 非 FN 原始消息 / 调试） */
@@ -491,7 +533,7 @@ class SockRpcV3 {
 
     /**
      * 通用事件注册（对齐 V2 SockRpc.AddEvent）
-     * @param {'onopen'|'onclose'|'onmessage'|'onerror'} type
+     * @param {'onopen'|'onready'|'onclose'|'onmessage'|'onerror'} type
      * @param {Function} listener
      */
     AddEvent(type, listener /*, options*/) {
@@ -561,7 +603,9 @@ class SockRpcV3 {
                 this._scheduleReconnect('WebSocket API missing');
                 return null;
             }
-            this.sock = new WebSocketCtor(this.addr);
+            this.sock = this.subprotocols.length > 0
+                ? new WebSocketCtor(this.addr, this.subprotocols)
+                : new WebSocketCtor(this.addr);
             this.sock.binaryType = binaryType;
 
             // open
@@ -571,6 +615,9 @@ class SockRpcV3 {
                 this._reconnectAttempts = 0;
                 this._currentDelay      = this.reconnectDelay;
                 this._manualClose       = false;
+                this.subprotocol        = this.sock.protocol || '';
+                // 第一条消息：Hello
+                this._sendHello();
                 if (_isFunction(opts.ready)) {
                     try { opts.ready.call(this, this.sock); } catch (e) { this._logErr('ready cb throw', e); }
                 }
@@ -594,6 +641,7 @@ class SockRpcV3 {
                 this.connected   = false;
                 this.sock        = null;
                 this._stopReapTimer();
+                this._resetHello();
                 this._rejectAllPending(new Error(`connection closed (code=${evt && evt.code ? evt.code : 'n/a'})`));
                 this._reassembly.clear();
                 // 一次性标志位：先把 manual 清掉，防止下一次连接又被误判
@@ -629,10 +677,11 @@ class SockRpcV3 {
         }
         this.connected = false;
         this._stopReapTimer();
+        this._resetHello();
         this._rejectAllPending(new Error('stopped by user'));
         this._reassembly.clear();
         // 清空监听器 (对齐 V2 Stop)
-        this.listeners = { onopen: [], onclose: [], onmessage: [], onerror: [] };
+        this.listeners = { onopen: [], onready: [], onclose: [], onmessage: [], onerror: [] };
     }
 
     /**
@@ -901,11 +950,11 @@ class SockRpcV3 {
         return cur;
     }
 
-    /** 下一个片名 (两位十进制 00-99 循环) */
+    /** 下一个片名 (两位 [0-9A-Za-z]，3844 个循环；对端按片名重组，交错到达的分片互不干扰) */
     _nextSliceName() {
         const n = this._sliceCounter;
-        this._sliceCounter = (this._sliceCounter + 1) % 100;
-        return String(n).padStart(2, '0');
+        this._sliceCounter = (this._sliceCounter + 1) % (_SLICE_NAME_CHARS.length * _SLICE_NAME_CHARS.length);
+        return _SLICE_NAME_CHARS[Math.floor(n / _SLICE_NAME_CHARS.length)] + _SLICE_NAME_CHARS[n % _SLICE_NAME_CHARS.length];
     }
 
     /* ============================================================
@@ -926,10 +975,10 @@ class SockRpcV3 {
                     argsBytes.push(new Uint8Array(arg));
                 } else {
                     // 普通 Uint8Array → 作为 Bytes 类型 AG.Encode
-                    argsBytes.push(this._ag().EncodeArg(arg));
+                    argsBytes.push(this._encodeArg(arg));
                 }
             } else {
-                argsBytes.push(this._ag().EncodeArg(arg));
+                argsBytes.push(this._encodeArg(arg));
             }
         }
 
@@ -967,7 +1016,7 @@ class SockRpcV3 {
     }
 
     /* ============================================================
-     * 内部实现：发送 FN 帧 (自动分片，对齐 Go slicesTextSend)
+     * 内部实现：发送 FN 帧 (自动分片，对齐 Go slicesSend：默认二进制分片，binaryFrames=false 时为文本分片)
      * ============================================================ */
     _sendFnFrame(fnBytes) {
         if (!this.sock) throw new Error('ws not connected');
@@ -990,6 +1039,17 @@ class SockRpcV3 {
         if (totalSize % sliceSize !== 0) totalSlice++;
         // Go Split 有 min/max clamp；这里 JS 直接切就行，不做限制
 
+        // 二进制分片：BinaryMessage + slice.js EncodeSlice（对齐 Go slicesBinarySend）
+        if (this.binaryFrames && this._peerBinary && typeof EncodeSlice === 'function' && typeof DataSlice === 'function') {
+            for (let i = 0; i < totalSlice; i++) {
+                const start = i * sliceSize;
+                const end = Math.min(start + sliceSize, totalSize);
+                const ds = new DataSlice(_SLICE_BINARY, name, totalSlice, i, totalSize, tlvWrapped.subarray(start, end));
+                this.sock.send(EncodeSlice(ds));
+            }
+            return;
+        }
+
         for (let i = 0; i < totalSlice; i++) {
             const start = i * sliceSize;
             const end = Math.min(start + sliceSize, totalSize);
@@ -998,14 +1058,15 @@ class SockRpcV3 {
             // 注意：必须严格对齐 Go decoder/frame/slice.go#L16-L29 的 json tag（全部小写）:
             //   P byte   `json:"p"`
             //   N string `json:"n"`
-            //   T byte   `json:"t"`
-            //   I byte   `json:"i"`
+            //   T uint32 `json:"t"`
+            //   I uint32 `json:"i"`
             //   S uint32 `json:"s"`
             //   D []byte `json:"d"`  → JSON 序列化为 Base64 字符串
             const sliceObj = {
-                p: _SLICE_TEXT,
+                // 超过 255 片标记 WideSlice(0x20)，对齐 Go frame.Split
+                p: totalSlice > 0xFF ? (_SLICE_TEXT | 0x20) : _SLICE_TEXT,
                 n: name,
-                t: totalSlice, // byte：>255 片溢出？Go 版 T 是 byte，总分片数不能 >255
+                t: totalSlice,
                 i: i,
                 s: totalSize,
                 d: _u8ToB64(chunk), // 与 Go json.Marshal DataSlice{D:[]byte} 自动 Base64 一致
@@ -1050,8 +1111,9 @@ class SockRpcV3 {
                 // BinaryMessage → 用 slice.js 的 Decode
                 const buf = data instanceof ArrayBuffer ? new Uint8Array(data) : data;
                 try {
-                    if (window.DataSlice && typeof window.DataSlice === 'function' && typeof Decode === 'function') {
-                        slice = Decode(buf);
+                    // 注意：打包后裸名 Decode 是 fn.js 的，分片解析用 slice.js 的 DecodeSlice
+                    if (typeof DecodeSlice === 'function') {
+                        slice = DecodeSlice(buf);
                     } else {
                         // 没加载 slice.js → 当作完整非分片消息
                         this._maybeHandleFullFrame(buf);
@@ -1248,6 +1310,12 @@ class SockRpcV3 {
                 this._emit('onmessage', val);
                 break;
             }
+            case ACTION_HELLO: {
+                let peer = null;
+                try { peer = JSON.parse(new TextDecoder().decode(body)); } catch (e) { this._logErr('bad hello', e); }
+                if (_isObject(peer)) this._settle(peer);
+                break;
+            }
             default:
                 // 未知 Action → 按原样 onmessage
                 this._emit('onmessage', fnBytes);
@@ -1401,6 +1469,68 @@ class SockRpcV3 {
         }
     }
 
+    /* ============================================================
+     * 能力协商 (Hello)，对齐 Go nrpc/wsocket/hello.go + message/hello.go
+     * ============================================================ */
+
+    /** 协商出的能力：{ protocol, peer_version, codecs, compress, max_frame, features, legacy }，onready 前为 null */
+    Capabilities() { return this.capabilities; }
+
+    /** 服务端选定的子协议 (SUBPROTOCOL_TEXT / SUBPROTOCOL_BIN)，旧服务端为 '' */
+    Subprotocol() { return this.subprotocol; }
+
+    /** 本端能力：浏览器端不做应用层压缩、不支持 fn 帧加密 */
+    _localHello() {
+        const features = [];
+        if (this.binaryFrames && this.subprotocol !== SUBPROTOCOL_TEXT) features.push('binary');
+        features.push('wide_slice', 'ag_ext');
+        return { protocol: PROTOCOL_VERSION, codecs: ['ag'], features: features };
+    }
+
+    _sendHello() {
+        this._resetHello();
+        // 服务端没有选定 sloth 子协议（旧版）时不会回 Hello，直接按旧版处理
+        if (!this.subprotocol) { this._settle(null); return; }
+        this._helloTimer = setTimeout(() => { this._helloTimer = null; this._settle(null); }, _HELLO_TIMEOUT_MS);
+        const fn = this._fn();
+        if (!fn) return;
+        const body = new TextEncoder().encode(JSON.stringify(this._localHello()));
+        const { buffer, error } = fn.Encode(ACTION_HELLO, 0, body);
+        if (error) { this._logErr('fn.Encode hello failed', error); return; }
+        try { this._sendFnFrame(buffer); } catch (e) { this._logErr('send hello failed', e); }
+    }
+
+    /** 取双方能力交集并触发 onready，每个连接一次；peer 为 null 表示旧服务端 */
+    _settle(peer) {
+        if (this.capabilities) return;
+        if (this._helloTimer) { clearTimeout(this._helloTimer); this._helloTimer = null; }
+        let caps;
+        if (!peer) {
+            caps = { legacy: true };
+        } else {
+            const local = this._localHello();
+            const both = (a, b) => (a || []).filter((v) => (b || []).indexOf(v) >= 0);
+            caps = {
+                protocol:     Math.min(local.protocol, peer.protocol | 0),
+                peer_version: peer.version || '',
+                codecs:       both(local.codecs, peer.codecs),
+                compress:     [],
+                max_frame:    peer.max_frame || 0,
+                features:     both(local.features, peer.features),
+            };
+            this._peerBinary = caps.features.indexOf('binary') >= 0;
+        }
+        this.capabilities = caps;
+        this._emit('onready', caps);
+    }
+
+    _resetHello() {
+        if (this._helloTimer) { clearTimeout(this._helloTimer); this._helloTimer = null; }
+        this.capabilities = null;
+        // 选定 text 子协议时只发文本分片
+        this._peerBinary  = this.subprotocol !== SUBPROTOCOL_TEXT;
+    }
+
     /** 给服务器回一个 ACTION_REPLY_SUCCESS (id, data[]byte) */
     _replySuccess(id, dataU8) {
         const fn = this._fn();
@@ -1419,6 +1549,14 @@ class SockRpcV3 {
         try { this._sendFnFrame(buffer); } catch (e) { /* ignore */ }
     }
 
+    /** 参数 AG 编码：服务端协商了 ag_ext 时用嵌套帧，否则为旧版服务端也能解析的 JSON 形式 */
+    _encodeArg(arg) {
+        const ag = this._ag();
+        const caps = this.capabilities;
+        if (caps && caps.features && caps.features.indexOf('ag_ext') >= 0) return ag.EncodeArgExt(arg);
+        return ag.EncodeArg(arg);
+    }
+
     /* ============================================================
      * 内部实现：AG 解码兜底 (非 AG 帧原样返回 Uint8Array)
      * ============================================================ */
@@ -1611,7 +1749,8 @@ class SockRpcV3 {
 
     const _bag = {
         SockRpcV3,
-        ACTION_CALL, ACTION_REPLY_SUCCESS, ACTION_REPLY_ERROR, ACTION_INVALID, ACTION_BROADCAST,
+        ACTION_CALL, ACTION_REPLY_SUCCESS, ACTION_REPLY_ERROR, ACTION_INVALID, ACTION_BROADCAST, ACTION_HELLO,
+        PROTOCOL_VERSION, SUBPROTOCOL_TEXT, SUBPROTOCOL_BIN,
         TlvValue, TlvValueAsText, TlvValueAsJson, TlvJson,
     };
     const root = (typeof window !== 'undefined') ? window :
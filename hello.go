@@ -0,0 +1,31 @@
+package sloth
+
+import (
+	"net/http"
+
+	"github.com/w6xian/sloth/v3/message"
+	"github.com/w6xian/sloth/v3/nrpc/wsocket"
+)
+
+// PeerCapabilities 连接与对端协商出的能力（协议版本、编解码器、压缩、最大帧、特性），
+// ch 为 OnReady 或服务方法中拿到的连接（ctx.Value(ChannelKey)）；协商完成前或连接不支持时返回 nil
+func PeerCapabilities(ch any) *message.Capabilities {
+	if c, ok := ch.(interface{ Capabilities() *message.Capabilities }); ok {
+		return c.Capabilities()
+	}
+	return nil
+}
+
+// Subprotocol 升级时选定的 WebSocket 子协议（wsocket.SubprotocolText / wsocket.SubprotocolBin），
+// v 可以是服务端 OnConnect 的 *http.Request、客户端 OnConnect 的 *http.Response 或连接；对端为旧版时为空
+func Subprotocol(v any) string {
+	switch t := v.(type) {
+	case *http.Request:
+		return wsocket.Subprotocol(t)
+	case *http.Response:
+		return wsocket.ResponseSubprotocol(t)
+	case interface{ Subprotocol() string }:
+		return t.Subprotocol()
+	}
+	return ""
+}
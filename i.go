@@ -8,3 +8,8 @@ type IRpc interface {
 	SetEncoder(encoder Encoder)
 	SetDecoder(decoder Decoder)
 }
+
+// ICodecRpc 可选接口：支持按名称选择编解码器的 IRpc 实现（ClientRpc、ServerRpc），见 UseCodec
+type ICodecRpc interface {
+	SetCodec(name string)
+}
@@ -2,163 +2,191 @@ package ref
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math"
 	"reflect"
+
+	"github.com/w6xian/sloth/v3/decoder/ag"
 )
 
+// bad_length 标量参数只接受 1/2/4/8 字节（ag 解码后按类型补零），其余长度视为报文错误
+func bad_length(l int) error {
+	return fmt.Errorf("invalid length %d", l)
+}
+
 // BytesToInt converts a byte slice to a 64-bit integer.
-func bytes_to_int(data []byte) int {
+func bytes_to_int(data []byte) (int, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return int(data[0])
+		return int(data[0]), nil
 	case 2:
-		return int(binary.BigEndian.Uint16(data))
+		return int(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return int(binary.BigEndian.Uint32(data))
+		return int(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return int(binary.BigEndian.Uint64(data))
+		return int(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_int16 converts a byte slice to a 16-bit integer.
-func bytes_to_int16(data []byte) int16 {
+func bytes_to_int16(data []byte) (int16, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return int16(data[0])
+		return int16(data[0]), nil
 	case 2:
-		return int16(binary.BigEndian.Uint16(data))
+		return int16(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return int16(binary.BigEndian.Uint32(data))
+		return int16(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return int16(binary.BigEndian.Uint64(data))
+		return int16(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_int32 converts a byte slice to a 32-bit integer.
-func bytes_to_int32(data []byte) int32 {
+func bytes_to_int32(data []byte) (int32, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return int32(int8(data[0]))
+		return int32(int8(data[0])), nil
 	case 2:
-		return int32(binary.BigEndian.Uint16(data))
+		return int32(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return int32(binary.BigEndian.Uint32(data))
+		return int32(binary.BigEndian.Uint32(data)), nil
+	case 8:
+		return int32(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_int64 converts a byte slice to a 64-bit integer.
-func bytes_to_int64(data []byte) int64 {
+func bytes_to_int64(data []byte) (int64, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return int64(int8(data[0]))
+		return int64(int8(data[0])), nil
 	case 2:
-		return int64(binary.BigEndian.Uint16(data))
+		return int64(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return int64(binary.BigEndian.Uint32(data))
+		return int64(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return int64(binary.BigEndian.Uint64(data))
+		return int64(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_uint converts a byte slice to a 64-bit unsigned integer.
-func bytes_to_uint(data []byte) uint {
+func bytes_to_uint(data []byte) (uint, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return uint(data[0])
+		return uint(data[0]), nil
 	case 2:
-		return uint(binary.BigEndian.Uint16(data))
+		return uint(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return uint(binary.BigEndian.Uint32(data))
+		return uint(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return uint(binary.BigEndian.Uint64(data))
+		return uint(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_uint16 converts a byte slice to a 16-bit unsigned integer.
-func bytes_to_uint16(data []byte) uint16 {
+func bytes_to_uint16(data []byte) (uint16, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return uint16(data[0])
+		return uint16(data[0]), nil
 	case 2:
-		return uint16(binary.BigEndian.Uint16(data))
+		return uint16(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return uint16(binary.BigEndian.Uint32(data))
+		return uint16(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return uint16(binary.BigEndian.Uint64(data))
+		return uint16(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_uint32 converts a byte slice to a 32-bit unsigned integer.
-func bytes_to_uint32(data []byte) uint32 {
+func bytes_to_uint32(data []byte) (uint32, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return uint32(data[0])
+		return uint32(data[0]), nil
 	case 2:
-		return uint32(binary.BigEndian.Uint16(data))
+		return uint32(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return uint32(binary.BigEndian.Uint32(data))
+		return uint32(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return uint32(binary.BigEndian.Uint64(data))
+		return uint32(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_uint64 converts a byte slice to a 64-bit unsigned integer.
-func bytes_to_uint64(data []byte) uint64 {
+func bytes_to_uint64(data []byte) (uint64, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return uint64(data[0])
+		return uint64(data[0]), nil
 	case 2:
-		return uint64(binary.BigEndian.Uint16(data))
+		return uint64(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return uint64(binary.BigEndian.Uint32(data))
+		return uint64(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return uint64(binary.BigEndian.Uint64(data))
+		return uint64(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // bytes_to_float32 converts a byte slice to a 32-bit floating-point number.
-func bytes_to_float32(data []byte) float32 {
+func bytes_to_float32(data []byte) (float32, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return math.Float32frombits(uint32(data[0]))
+		return math.Float32frombits(uint32(data[0])), nil
 	case 2:
-		return math.Float32frombits(uint32(binary.BigEndian.Uint16(data)))
+		return math.Float32frombits(uint32(binary.BigEndian.Uint16(data))), nil
 	case 4:
-		return math.Float32frombits(binary.BigEndian.Uint32(data))
+		return math.Float32frombits(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return math.Float32frombits(uint32(binary.BigEndian.Uint64(data)))
+		return math.Float32frombits(uint32(binary.BigEndian.Uint64(data))), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
 // BytesToFloat64 converts a byte slice to a 64-bit floating-point number.
-func bytes_to_float64(data []byte) float64 {
+func bytes_to_float64(data []byte) (float64, error) {
 	bits := 0
 	l := len(data)
 	switch l {
@@ -170,134 +198,135 @@ func bytes_to_float64(data []byte) float64 {
 		bits = int(binary.BigEndian.Uint32(data))
 	case 8:
 		bits = int(binary.BigEndian.Uint64(data))
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
-	return math.Float64frombits(uint64(bits))
+	return math.Float64frombits(uint64(bits)), nil
 }
 
 // BytesToBool converts a byte slice to a boolean value.
-func bytes_to_bool(data []byte) bool {
-	return data[0] != 0
+func bytes_to_bool(data []byte) (bool, error) {
+	switch len(data) {
+	case 0:
+		return false, nil
+	case 1:
+		return data[0] != 0, nil
+	default:
+		return false, bad_length(len(data))
+	}
 }
 
 // bytes_to_uintptr converts a byte slice to a uintptr value.
-func bytes_to_uintptr(data []byte) uintptr {
+func bytes_to_uintptr(data []byte) (uintptr, error) {
 	l := len(data)
 	switch l {
 	case 1:
-		return uintptr(data[0])
+		return uintptr(data[0]), nil
 	case 2:
-		return uintptr(binary.BigEndian.Uint16(data))
+		return uintptr(binary.BigEndian.Uint16(data)), nil
 	case 4:
-		return uintptr(binary.BigEndian.Uint32(data))
+		return uintptr(binary.BigEndian.Uint32(data)), nil
 	case 8:
-		return uintptr(binary.BigEndian.Uint64(data))
+		return uintptr(binary.BigEndian.Uint64(data)), nil
+	case 0:
+		return 0, nil
 	default:
-		return 0
+		return 0, bad_length(l)
 	}
 }
 
-func get_param_type(needPtr bool, name string, data []byte) reflect.Value {
-	// []string{"int", "int32", "int64", "uint", "uint32", "uint64", "float32", "float64", "string", "uint8", "bool"}
+// scalar_decoder 按类型名选定标量参数的转换，结果写入 dst（由调用方 reflect.New 得到）
+// 空数据（nil 参数）得到零值
+func scalar_decoder(name string) func(data []byte, dst reflect.Value) error {
 	switch name {
 	case "int":
-		by := bytes_to_int(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
+		return set_int(bytes_to_int)
 	case "int16":
-		by := bytes_to_int16(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
-	case "int32":
-		by := bytes_to_int32(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
+		return set_int(bytes_to_int16)
+	case "int32", "rune":
+		return set_int(bytes_to_int32)
 	case "int64":
-		by := bytes_to_int64(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
+		return set_int(bytes_to_int64)
 	case "uint":
-		by := bytes_to_uint(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
+		return set_uint(bytes_to_uint)
 	case "uint16":
-		by := bytes_to_uint16(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
+		return set_uint(bytes_to_uint16)
 	case "uint32":
-		by := bytes_to_uint32(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
-	case "uint64":
-		by := bytes_to_uint64(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
+		return set_uint(bytes_to_uint32)
+	case "uint64", "uint8", "byte":
+		// uint8 按 uint64 读取后由 SetUint 截断
+		return set_uint(bytes_to_uint64)
+	case "uintptr":
+		return set_uint(bytes_to_uintptr)
+	case "int8":
+		return func(data []byte, dst reflect.Value) error {
+			n, err := bytes_to_uint64(data)
+			dst.SetInt(int64(int8(n)))
+			return err
 		}
-		return reflect.ValueOf(by)
 	case "float32":
-		by := bytes_to_float32(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
+		return set_float(bytes_to_float32)
 	case "float64":
-		by := bytes_to_float64(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
+		return set_float(bytes_to_float64)
 	case "string":
-		str := string(data)
-		if needPtr {
-			return reflect.ValueOf(&str)
-		}
-		return reflect.ValueOf(str)
-	case "uint8":
-		by := data[0]
-		if needPtr {
-			return reflect.ValueOf(&by)
+		return func(data []byte, dst reflect.Value) error {
+			dst.SetString(string(data))
+			return nil
 		}
-		return reflect.ValueOf(by)
-	case "int8":
-		by := int8(data[0])
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
-	case "uintptr":
-		by := bytes_to_uintptr(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(by)
-	case "rune":
-		by := bytes_to_int(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
-		}
-		return reflect.ValueOf(rune(by))
 	case "bool":
-		by := bytes_to_bool(data)
-		if needPtr {
-			return reflect.ValueOf(&by)
+		return func(data []byte, dst reflect.Value) error {
+			b, err := bytes_to_bool(data)
+			dst.SetBool(b)
+			return err
 		}
-		return reflect.ValueOf(by)
 	default:
-		return reflect.ValueOf(data)
+		return func(data []byte, dst reflect.Value) error {
+			return fmt.Errorf("unsupported type %s", name)
+		}
+	}
+}
+
+// ext_decoder time.Time / big.Int / big.Float 参数收到的是整个 ag 帧（见 ag.Decoder），
+// time.Duration 为 8 字节整数；旧客户端的文本（"1.5s"、RFC3339、JSON）交给 ag.DecodeInto 解析
+func ext_decoder(t reflect.Type) func(data []byte, dst reflect.Value) error {
+	switch t {
+	case typeOfTime, typeOfDuration, typeOfBigInt, typeOfBigFloat:
+		return func(data []byte, dst reflect.Value) error {
+			switch {
+			case len(data) == 0:
+				return nil
+			case len(data) == 8 && t == typeOfDuration:
+				dst.SetInt(int64(binary.BigEndian.Uint64(data)))
+				return nil
+			}
+			return ag.DecodeInto(data, dst.Addr().Interface())
+		}
+	}
+	return nil
+}
+
+func set_int[T int | int16 | int32 | int64](conv func([]byte) (T, error)) func([]byte, reflect.Value) error {
+	return func(data []byte, dst reflect.Value) error {
+		n, err := conv(data)
+		dst.SetInt(int64(n))
+		return err
+	}
+}
+
+func set_uint[T uint | uint16 | uint32 | uint64 | uintptr](conv func([]byte) (T, error)) func([]byte, reflect.Value) error {
+	return func(data []byte, dst reflect.Value) error {
+		n, err := conv(data)
+		dst.SetUint(uint64(n))
+		return err
+	}
+}
+
+func set_float[T float32 | float64](conv func([]byte) (T, error)) func([]byte, reflect.Value) error {
+	return func(data []byte, dst reflect.Value) error {
+		f, err := conv(data)
+		dst.SetFloat(float64(f))
+		return err
 	}
 }
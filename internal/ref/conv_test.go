@@ -0,0 +1,54 @@
+package ref
+
+import (
+	"fmt"
+	"testing"
+)
+
+// 标量参数只接受 0/1/2/4/8 字节，其余长度返回 invalid length
+func TestBytesTo_BadLength(t *testing.T) {
+	convs := map[string]func([]byte) error{
+		"int":     func(b []byte) error { _, err := bytes_to_int(b); return err },
+		"int16":   func(b []byte) error { _, err := bytes_to_int16(b); return err },
+		"int32":   func(b []byte) error { _, err := bytes_to_int32(b); return err },
+		"int64":   func(b []byte) error { _, err := bytes_to_int64(b); return err },
+		"uint":    func(b []byte) error { _, err := bytes_to_uint(b); return err },
+		"uint16":  func(b []byte) error { _, err := bytes_to_uint16(b); return err },
+		"uint32":  func(b []byte) error { _, err := bytes_to_uint32(b); return err },
+		"uint64":  func(b []byte) error { _, err := bytes_to_uint64(b); return err },
+		"uintptr": func(b []byte) error { _, err := bytes_to_uintptr(b); return err },
+		"float32": func(b []byte) error { _, err := bytes_to_float32(b); return err },
+		"float64": func(b []byte) error { _, err := bytes_to_float64(b); return err },
+	}
+	for name, conv := range convs {
+		for _, l := range []int{0, 1, 2, 4, 8} {
+			if err := conv(make([]byte, l)); err != nil {
+				t.Errorf("%s(%d bytes): %v", name, l, err)
+			}
+		}
+		for _, l := range []int{3, 5, 6, 7, 9} {
+			err := conv(make([]byte, l))
+			if want := fmt.Sprintf("invalid length %d", l); err == nil || err.Error() != want {
+				t.Errorf("%s(%d bytes): err = %v, want %s", name, l, err, want)
+			}
+		}
+	}
+	if _, err := bytes_to_bool([]byte{1, 0}); err == nil || err.Error() != "invalid length 2" {
+		t.Errorf("bool(2 bytes): err = %v", err)
+	}
+}
+
+func TestBytesTo_Values(t *testing.T) {
+	if v, _ := bytes_to_int32([]byte{0xff}); v != -1 {
+		t.Errorf("int32(0xff) = %d, want -1", v)
+	}
+	if v, _ := bytes_to_int64([]byte{0, 0, 1, 0}); v != 256 {
+		t.Errorf("int64 = %d, want 256", v)
+	}
+	if v, _ := bytes_to_uint16([]byte{1, 2}); v != 0x0102 {
+		t.Errorf("uint16 = %#x", v)
+	}
+	if v, _ := bytes_to_bool([]byte{2}); !v {
+		t.Error("bool(2) = false")
+	}
+}
@@ -1,7 +1,9 @@
 package ref
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -9,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/w6xian/sloth/v3/internal/utils"
-	"github.com/w6xian/sloth/v3/internal/utils/array"
 )
 
 // Register 注册服务
@@ -38,6 +39,10 @@ func Register(rcvr any) *ServiceFuncs {
 	m, a := suitable_methods(getType)
 	service.M = m
 	service.A = a
+	service.I = make(map[string]*invoker, len(m))
+	for name, mtd := range m {
+		service.I[name] = new_invoker(mtd, 2)
+	}
 	return service
 }
 
@@ -47,59 +52,46 @@ func suitable_methods(typ reflect.Type) (map[string]reflect.Method, map[string]F
 	iface := make(map[string]FuncStruct)
 
 	// 遍历所有方法
-	for m := 0; m < typ.NumMethod(); m++ {
-		m := typ.Method(m)
-		// 这里可以加一些方法需要什么样的参数，比如第一个参数必须是context.Context
-		if m.Type.NumIn() < 2 || m.Type.In(1) != reflect.TypeOf((*context.Context)(nil)).Elem() {
-			continue
-		}
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
 		// Method must be exported.
-		if m.PkgPath != "" {
-			continue
-		}
-		if !m.IsExported() {
+		if m.PkgPath != "" || !m.IsExported() {
 			continue
 		}
-		// 只限定第一个参数，一这是context.Context，后面的参数可以是任意类型
-		if m.Type.NumIn() < 2 {
-			log.Printf("[notice]method %s must have at least 1 arguments", m.Name)
+		if reason := check_signature(m.Type); reason != "" {
+			log.Printf("[notice]method %s rejected: %s, signature %s", m.Name, reason, m.Type.String())
 			continue
 		}
-		arg1 := m.Type.In(1)
-		// 判定第一个参数是不是context.Context
-		if !arg1.Implements(typeOfContext) {
-			log.Printf("[notice]method %s must have at least 1 arguments, first argument must be context.Context", m.Name)
-			continue
-		}
-		// 返回值最后一个值需要是error
-		if m.Type.NumOut() < 1 {
-			log.Printf("[notice]method %s must have 1-2 return value and last return value must be error", m.Name)
-			continue
-		}
-		if m.Type.NumOut() > 2 {
-			log.Printf("[notice]method %s must have 1-2 return values and last return value must be error", m.Name)
-			continue
-		}
-		out := m.Type.Out(m.Type.NumOut() - 1)
-		if !out.Implements(typeOfError) {
-			log.Printf("[notice]method %s must have at least 1 return value, last return value must be error", m.Name)
+		if err := check_validate_tags(m.Type); err != nil {
+			log.Printf("[notice]method %s rejected: invalid validate tag: %v", m.Name, err)
 			continue
 		}
 		methods[m.Name] = m
 		// 方法的参数
 		args := make([]ArgStruct, 0)
 		for i := 2; i < m.Type.NumIn(); i++ {
+			typ := m.Type.In(i).String()
+			if m.Type.IsVariadic() && i == m.Type.NumIn()-1 {
+				typ = "..." + m.Type.In(i).Elem().String()
+			}
 			args = append(args, ArgStruct{
 				Name: fmt.Sprintf("arg%d", i-2),
-				Type: m.Type.In(i).String(),
+				Type: typ,
 			})
 		}
+		// 返回值
+		rets := make([]string, 0, m.Type.NumOut())
+		for i := 0; i < m.Type.NumOut(); i++ {
+			rets = append(rets, m.Type.Out(i).String())
+		}
 		s := strings.SplitN(m.Type.String(), ",", 2)
 		api := fmt.Sprintf("%s(", m.Name)
 		s[0] = api
 		iface[m.Name] = FuncStruct{
-			Name:   m.Name,
-			Define: fmt.Sprintf("%s", strings.Join(s, "")),
+			Name:    m.Name,
+			Define:  fmt.Sprintf("%s", strings.Join(s, "")),
+			Args:    args,
+			Returns: rets,
 		}
 	}
 
@@ -110,109 +102,79 @@ func suitable_methods(typ reflect.Type) (map[string]reflect.Method, map[string]F
 	return methods, iface
 }
 
-func instance_params(params reflect.Type, data []byte) (reflect.Value, error) {
-	isPtr := params.Kind() == reflect.Pointer
-	structType := params
-	if isPtr {
-		structType = params.Elem()
+// check_signature 可调用的方法：第一个参数（接收者之后）为 context.Context，最后一个返回值为 error
+func check_signature(ft reflect.Type) string {
+	if ft.NumIn() < 2 || ft.In(1) != typeOfContext {
+		return "first argument must be context.Context"
 	}
-	nameStr := structType.String()
-	if nameStr == "[]byte" || nameStr == "[]uint8" {
-		if isPtr {
-			return reflect.ValueOf(&data), nil
-		}
-		return reflect.ValueOf(data), nil
-	} else if array.InArray(nameStr, commonTypes) {
-		// 检查参数类型，根据参数类型进行转换（[]byte改成 “name“对应的类型）
-		r := get_param_type(isPtr, nameStr, data)
-		return r, nil
-	} else {
-		// 转换参数类型为reflect.Value
-		if instance, cErr := new_instance_reflect(structType); cErr == nil {
-			utils.Deserialize(data, instance.Interface())
-			// 根据需要返回对应的类型
-			if !isPtr {
-				return instance.Elem(), nil
-			}
-			return instance, nil
-		}
+	if ft.NumOut() < 1 || !ft.Out(ft.NumOut()-1).Implements(typeOfError) {
+		return "last return value must be error"
 	}
-	return reflect.Value{}, fmt.Errorf("unknown type: %s", params.String())
+	return ""
 }
 
-// 根据type生成新的实例
-func new_instance_reflect(typ reflect.Type) (reflect.Value, error) {
-	if typ == nil {
-		return reflect.Value{}, fmt.Errorf("unknown type: %s", typ.Name())
+// check_validate_tags 注册时解析参数类型上的 validate tag，写错的规则在注册阶段暴露
+func check_validate_tags(ft reflect.Type) error {
+	for i := 2; i < ft.NumIn(); i++ {
+		if _, err := compile_rules(ft.In(i)); err != nil {
+			return err
+		}
 	}
-	instance := reflect.New(typ)
-	return instance, nil
+	return nil
 }
 
-func CallFuncWithContext(ctx context.Context, Fns *ServiceFuncs, method string, args ...[]byte) ([]byte, error) {
-	mtd, ok := Fns.M[method]
-	if !ok {
-		return nil, errors.New("method not found")
-	}
-	funcArgs := []reflect.Value{
-		Fns.V,                // 需要第一个为方法所属对象，【必须】这个是反射参数要求
-		reflect.ValueOf(ctx), // 这个是context.Context参数，是习惯传递第一个参数，不是反射参数要求
+// deserialize strict 时拒绝未知字段以及多余的数据
+func deserialize(data []byte, v any, strict bool) error {
+	if !strict {
+		return utils.Deserialize(data, v)
 	}
-	return call_instance_func(mtd, funcArgs, args...)
-}
-
-// CallFunc 调用方法
-// @param ctx 上下文
-// @param Fns 方法
-// @param method 方法名，反射参（第一个默认是方法所属对象）
-// @param args 传参
-// @return []byte, error
-func CallFunc(fns *ServiceFuncs, method string, args ...[]byte) ([]byte, error) {
-	mtd, ok := fns.M[method]
-	if !ok {
-		return nil, errors.New("method not found")
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
 	}
-	funcArgs := []reflect.Value{
-		fns.V, // 需要第一个为方法所属对象，【必须】这个是反射参数要求
+	if dec.More() {
+		return errors.New("invalid character after top-level value")
 	}
-	return call_instance_func(mtd, funcArgs, args...)
+	return nil
 }
 
-func call_instance_func(mtd reflect.Method, params []reflect.Value, args ...[]byte) ([]byte, error) {
-	defArgsNum := len(params)
-	// func f(ctx)
-	rArgsLen := len(args)
-	maxArgs := mtd.Type.NumIn() - defArgsNum
-	if rArgsLen > maxArgs {
-		return nil, fmt.Errorf("too many arguments: got %d, want at most %d", rArgsLen, maxArgs)
-	}
-	// Elem() 相当于 *T 取指针指向的类型
-	// more args
-	for i := range rArgsLen {
-		data := args[i]
-		inx := mtd.Type.In(i + defArgsNum)
-		param, iErr := instance_params(inx, data)
-		if iErr != nil {
-			return nil, iErr
-		}
-		params = append(params, param)
-	}
-	ret := mtd.Func.Call(params)
-	if len(ret) != 2 {
-		return nil, fmt.Errorf("call func  error, expect 2 return values, but got %d", len(ret))
+func CallFuncWithContext(ctx context.Context, Fns *ServiceFuncs, method string, args ...[]byte) ([]byte, error) {
+	inv, ret, err := invoke_with_context(ctx, Fns, method, nil, args)
+	if err != nil {
+		return nil, err
 	}
+	return inv.bytes(ret)
+}
 
-	iErr, ok := ret[1].Interface().(error)
-	if ok && iErr != nil {
-		return nil, iErr
+// InvokeFuncWithContext 与 CallFuncWithContext 相同，但返回方法的原始返回值（不做编码），
+// 由调用方决定返回值的编码方式（如带类型标签的 ag 帧）。
+func InvokeFuncWithContext(ctx context.Context, Fns *ServiceFuncs, method string, args ...[]byte) (any, error) {
+	inv, ret, err := invoke_with_context(ctx, Fns, method, nil, args)
+	if err != nil {
+		return nil, err
 	}
+	return inv.value(ret), nil
+}
 
-	// 调用成功，返回结果
-	data := ret[0].Interface()
-	// 调用成功，返回结果
-	resp, err := utils.AnyToBytes(data)
+// InvokeFuncWithCodec 与 InvokeFuncWithContext 相同，但参数由 unmarshal 直接解码为方法声明的类型
+func InvokeFuncWithCodec(ctx context.Context, Fns *ServiceFuncs, method string, unmarshal Unmarshal, args ...[]byte) (any, error) {
+	inv, ret, err := invoke_with_context(ctx, Fns, method, unmarshal, args)
 	if err != nil {
 		return nil, err
 	}
-	return resp, nil
+	return inv.value(ret), nil
+}
+
+func invoke_with_context(ctx context.Context, fns *ServiceFuncs, method string, unmarshal Unmarshal, args [][]byte) (*invoker, []reflect.Value, error) {
+	inv, ok := fns.I[method]
+	if !ok {
+		return nil, nil, errors.New("method not found")
+	}
+	params := make([]reflect.Value, 2, 2+len(args))
+	params[0] = fns.V // 需要第一个为方法所属对象，【必须】这个是反射参数要求
+	// context.Context参数，是习惯传递第一个参数；以接口类型传入，省去 Call 时的接口转换检查
+	params[1] = reflect.ValueOf(&ctx).Elem()
+	ret, err := inv.invoke(params, fns.S, unmarshal, args)
+	return inv, ret, err
 }
@@ -0,0 +1,287 @@
+package ref
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/w6xian/sloth/v3/internal/utils"
+	"github.com/w6xian/sloth/v3/internal/utils/array"
+)
+
+// param_decoder 把一个参数的原始字节解码为方法声明的类型
+type param_decoder func(data []byte, strict bool) (reflect.Value, error)
+
+// result_encoder 把方法的第一个返回值编码为字节
+type result_encoder func(v reflect.Value) ([]byte, error)
+
+// 按 reflect.Type 缓存，不同服务的同类型参数/返回值共用
+var (
+	paramDecoders  sync.Map // reflect.Type -> param_decoder
+	resultEncoders sync.Map // reflect.Type -> result_encoder
+)
+
+var typeOfBytes = reflect.TypeOf([]byte(nil))
+
+// invoker 注册时为每个方法预先生成的调用器：参数解码器、是否需要校验、返回值编码器都已确定，
+// 调用时不再拼接类型名、扫描 commonTypes
+//
+// 支持的签名：func(ctx, args...) error / (T, error) / (T1, ..., Tn, error)，
+// 最后一个参数可以是可变参数（调用方逐个传入元素）；缺少的尾部参数按零值补齐。
+type invoker struct {
+	fn       reflect.Value  // 方法函数，第一个参数为接收者
+	in       []reflect.Type // 业务参数类型，不含调用方提供的固定参数（接收者、ctx）
+	decode   []param_decoder
+	check    []bool // 参数类型上有 validate 规则
+	encode   result_encoder
+	variadic bool // in 的最后一项为可变参数的元素类型
+}
+
+func new_invoker(m reflect.Method, fixed int) *invoker {
+	ft := m.Type
+	inv := &invoker{
+		fn:       m.Func,
+		variadic: ft.IsVariadic(),
+	}
+	for i := fixed; i < ft.NumIn(); i++ {
+		t := ft.In(i)
+		if inv.variadic && i == ft.NumIn()-1 {
+			t = t.Elem()
+		}
+		rules, _ := compile_rules(t)
+		inv.in = append(inv.in, t)
+		inv.decode = append(inv.decode, decoder_for(t))
+		inv.check = append(inv.check, rules != nil)
+	}
+	if ft.NumOut() == 2 {
+		inv.encode = encoder_for(ft.Out(0))
+	}
+	return inv
+}
+
+// invoke params 为已填好的固定参数，容量足够时追加参数不再扩容；返回值不含最后的 error。
+// unmarshal 不为 nil 时参数由编解码器直接解码，不再经过预先生成的解码器
+func (inv *invoker) invoke(params []reflect.Value, strict bool, unmarshal Unmarshal, args [][]byte) ([]reflect.Value, error) {
+	n, want := len(args), len(inv.in)
+	if n > want && !inv.variadic {
+		return nil, fmt.Errorf("%w: too many arguments: got %d, want at most %d", ErrBadArguments, n, want)
+	}
+	for i, data := range args {
+		k := min(i, want-1) // 可变参数部分共用最后一个解码器
+		param, err := inv.decode_arg(k, data, strict, unmarshal)
+		if err != nil {
+			return nil, &ArgError{Index: i, Type: inv.in[k].String(), Err: err}
+		}
+		if err := inv.validate(i, k, param); err != nil {
+			return nil, err
+		}
+		params = append(params, param)
+	}
+	// 缺少的尾部参数按零值补齐，可变参数部分为空
+	for i := n; i < want; i++ {
+		if inv.variadic && i == want-1 {
+			break
+		}
+		zero := reflect.Zero(inv.in[i])
+		if err := inv.validate(i, i, zero); err != nil {
+			return nil, err
+		}
+		params = append(params, zero)
+	}
+	ret := inv.fn.Call(params)
+	last := ret[len(ret)-1]
+	if !is_nil(last) {
+		return nil, last.Interface().(error)
+	}
+	return ret[:len(ret)-1], nil
+}
+
+func (inv *invoker) decode_arg(k int, data []byte, strict bool, unmarshal Unmarshal) (reflect.Value, error) {
+	if unmarshal == nil {
+		return inv.decode[k](data, strict)
+	}
+	ptr := reflect.New(inv.in[k])
+	if err := unmarshal(data, ptr.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return ptr.Elem(), nil
+}
+
+// validate validate tag 校验，列出全部失败字段
+func (inv *invoker) validate(i, k int, param reflect.Value) error {
+	if !inv.check[k] {
+		return nil
+	}
+	vErrs, err := validate_value(param)
+	if err != nil {
+		return err
+	}
+	if len(vErrs) > 0 {
+		return &ArgError{Index: i, Type: inv.in[k].String(), Err: vErrs}
+	}
+	return nil
+}
+
+// value 方法返回值（不含 error）：没有返回值为 nil，多个返回值合并为 []any
+func (inv *invoker) value(ret []reflect.Value) any {
+	switch len(ret) {
+	case 0:
+		return nil
+	case 1:
+		return ret[0].Interface()
+	}
+	out := make([]any, len(ret))
+	for i, v := range ret {
+		out[i] = v.Interface()
+	}
+	return out
+}
+
+// bytes 返回值编码，单个返回值走预先选定的编码器，多个返回值编码为 JSON 数组
+func (inv *invoker) bytes(ret []reflect.Value) ([]byte, error) {
+	if len(ret) == 1 {
+		return inv.encode(ret[0])
+	}
+	return utils.AnyToBytes(inv.value(ret))
+}
+
+func is_nil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+func decoder_for(t reflect.Type) param_decoder {
+	if d, ok := paramDecoders.Load(t); ok {
+		return d.(param_decoder)
+	}
+	d := build_decoder(t)
+	paramDecoders.Store(t, d)
+	return d
+}
+
+// build_decoder 指针参数（*[]byte 除外）收到 nil（Nil 帧，见 ag.Decoder）时为 nil 指针，
+// 空值帧得到指向零值的指针
+func build_decoder(t reflect.Type) param_decoder {
+	d := build_value_decoder(t)
+	if t.Kind() != reflect.Pointer || t.Elem() == typeOfBytes {
+		return d
+	}
+	null := reflect.Zero(t)
+	return func(data []byte, strict bool) (reflect.Value, error) {
+		if data == nil {
+			return null, nil
+		}
+		return d(data, strict)
+	}
+}
+
+func build_value_decoder(t reflect.Type) param_decoder {
+	isPtr := t.Kind() == reflect.Pointer
+	elem := t
+	if isPtr {
+		elem = t.Elem()
+	}
+	name := elem.String()
+	if name == "[]byte" || name == "[]uint8" {
+		if isPtr {
+			return func(data []byte, _ bool) (reflect.Value, error) {
+				return reflect.ValueOf(&data), nil
+			}
+		}
+		return func(data []byte, _ bool) (reflect.Value, error) {
+			return reflect.ValueOf(data), nil
+		}
+	}
+	if set := ext_decoder(elem); set != nil {
+		return func(data []byte, _ bool) (reflect.Value, error) {
+			ptr := reflect.New(elem)
+			if err := set(data, ptr.Elem()); err != nil {
+				return reflect.Value{}, err
+			}
+			if isPtr {
+				return ptr, nil
+			}
+			return ptr.Elem(), nil
+		}
+	}
+	if array.InArray(name, commonTypes) {
+		// 检查参数类型，根据参数类型进行转换（[]byte改成 “name“对应的类型）
+		set := scalar_decoder(name)
+		return func(data []byte, _ bool) (reflect.Value, error) {
+			ptr := reflect.New(elem)
+			if err := set(data, ptr.Elem()); err != nil {
+				return reflect.Value{}, err
+			}
+			if isPtr {
+				return ptr, nil
+			}
+			return ptr.Elem(), nil
+		}
+	}
+	// 结构体、map、slice 等按 JSON 解码
+	return func(data []byte, strict bool) (reflect.Value, error) {
+		instance := reflect.New(elem)
+		// 空数据（nil 参数）保持零值
+		if len(data) > 0 {
+			if err := deserialize(data, instance.Interface(), strict); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		if isPtr {
+			return instance, nil
+		}
+		return instance.Elem(), nil
+	}
+}
+
+func encoder_for(t reflect.Type) result_encoder {
+	if e, ok := resultEncoders.Load(t); ok {
+		return e.(result_encoder)
+	}
+	e := build_encoder(t)
+	resultEncoders.Store(t, e)
+	return e
+}
+
+// build_encoder 与 utils.AnyToBytes 的输出一致，内置标量类型直接编码，其余交给 AnyToBytes
+func build_encoder(t reflect.Type) result_encoder {
+	if t.Kind() != reflect.Interface && !t.Implements(typeOfError) && t.PkgPath() == "" {
+		switch {
+		case t == typeOfBytes:
+			return func(v reflect.Value) ([]byte, error) {
+				b := v.Bytes()
+				out := make([]byte, len(b))
+				copy(out, b)
+				return out, nil
+			}
+		case t.Kind() == reflect.String:
+			return func(v reflect.Value) ([]byte, error) {
+				return []byte(v.String()), nil
+			}
+		case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+			return func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendInt(nil, v.Int(), 10), nil
+			}
+		case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+			return func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendUint(nil, v.Uint(), 10), nil
+			}
+		case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+			bits := t.Bits()
+			return func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendFloat(nil, v.Float(), 'f', -1, bits), nil
+			}
+		case t.Kind() == reflect.Bool:
+			return func(v reflect.Value) ([]byte, error) {
+				return strconv.AppendBool(nil, v.Bool()), nil
+			}
+		}
+	}
+	return func(v reflect.Value) ([]byte, error) {
+		return utils.AnyToBytes(v.Interface())
+	}
+}
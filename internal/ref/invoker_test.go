@@ -0,0 +1,220 @@
+package ref
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/w6xian/sloth/v3/decoder/ag"
+)
+
+type sigOpt struct {
+	N int `json:"n" validate:"min=1"`
+}
+
+type sigSvc struct{}
+
+func (s *sigSvc) Ping(ctx context.Context) error        { return nil }
+func (s *sigSvc) Fail(ctx context.Context, n int) error { return errors.New("boom") }
+func (s *sigSvc) Sum(ctx context.Context, base int, n ...int64) (int64, error) {
+	for _, v := range n {
+		base += int(v)
+	}
+	return int64(base), nil
+}
+func (s *sigSvc) Pair(ctx context.Context, a string) (string, int, error) { return a, len(a), nil }
+func (s *sigSvc) Opt(ctx context.Context, a string, p *sigOpt, b int) (bool, error) {
+	return a == "" && p == nil && b == 0, nil
+}
+func (s *sigSvc) Req(ctx context.Context, o sigOpt) (int, error) { return o.N, nil }
+func (s *sigSvc) NoCtx(a int) error                              { return nil }
+func (s *sigSvc) NoErr(ctx context.Context) int                  { return 0 }
+
+func TestInvoker_Signatures(t *testing.T) {
+	fns := Register(&sigSvc{})
+	for _, name := range []string{"NoCtx", "NoErr"} {
+		if _, ok := fns.I[name]; ok {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+	ctx := context.Background()
+	cases := []struct {
+		method string
+		args   [][]byte
+		want   string
+		err    string
+	}{
+		{method: "Ping", want: ""},
+		{method: "Fail", args: [][]byte{{1}}, err: "boom"},
+		{method: "Sum", args: [][]byte{{1}}, want: "1"},
+		{method: "Sum", args: [][]byte{{1}, {2}, {0, 0, 0, 3}}, want: "6"},
+		{method: "Sum", args: [][]byte{{1}, {2, 3, 4}}, err: "bad arguments: arg1 (int64): invalid length 3"},
+		{method: "Pair", args: [][]byte{[]byte("abc")}, want: `["abc",3]`},
+		// 缺少的尾部参数为零值，指针为 nil
+		{method: "Opt", want: "true"},
+		{method: "Opt", args: [][]byte{[]byte("x")}, want: "false"},
+		// 零值同样参与校验
+		{method: "Req", err: "bad arguments: arg0 (ref.sigOpt): n: min=1"},
+		{method: "Ping", args: [][]byte{{1}}, err: "bad arguments: too many arguments: got 1, want at most 0"},
+	}
+	for _, c := range cases {
+		out, err := CallFuncWithContext(ctx, fns, c.method, c.args...)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: err = %v, want %s", c.method, err, c.err)
+			}
+			continue
+		}
+		if err != nil || string(out) != c.want {
+			t.Errorf("%s: got %q, %v, want %q", c.method, out, err, c.want)
+		}
+	}
+	if v, err := InvokeFuncWithContext(ctx, fns, "Ping"); v != nil || err != nil {
+		t.Errorf("Ping = %v, %v", v, err)
+	}
+	if v, _ := InvokeFuncWithContext(ctx, fns, "Pair", []byte("ab")); len(v.([]any)) != 2 {
+		t.Errorf("Pair = %#v", v)
+	}
+	if got := fns.A["Sum"].Args[1].Type; got != "...int64" {
+		t.Errorf("variadic arg type = %s", got)
+	}
+}
+
+func TestInvoke_Codec(t *testing.T) {
+	fns := Register(&sigSvc{})
+	ctx := context.Background()
+	unmarshal := func(data []byte, v any) error { return json.Unmarshal(data, v) }
+	v, err := InvokeFuncWithCodec(ctx, fns, "Sum", unmarshal, []byte("1"), []byte("2"), []byte("3"))
+	if err != nil || v != int64(6) {
+		t.Fatalf("Sum = %v, %v", v, err)
+	}
+	if v, err := InvokeFuncWithCodec(ctx, fns, "Req", unmarshal, []byte(`{"n":2}`)); err != nil || v != 2 {
+		t.Fatalf("Req = %v, %v", v, err)
+	}
+	// 解码失败与校验失败同样是 bad arguments
+	_, err = InvokeFuncWithCodec(ctx, fns, "Req", unmarshal, []byte(`{"n":"x"}`))
+	var ae *ArgError
+	if !errors.Is(err, ErrBadArguments) || !errors.As(err, &ae) || ae.Index != 0 {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := InvokeFuncWithCodec(ctx, fns, "Req", unmarshal, []byte(`{"n":0}`)); !errors.Is(err, ErrBadArguments) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// 严格模式（ServiceFuncs.S）拒绝未知字段与多余的数据，默认忽略未知字段
+func TestInvoke_Strict(t *testing.T) {
+	fns := Register(&sigSvc{})
+	ctx := context.Background()
+	unknown := []byte(`{"n":2,"x":1}`)
+	if out, err := CallFuncWithContext(ctx, fns, "Req", unknown); err != nil || string(out) != "2" {
+		t.Fatalf("loose: %q, %v", out, err)
+	}
+
+	fns.S = true
+	if out, err := CallFuncWithContext(ctx, fns, "Req", []byte(`{"n":2}`)); err != nil || string(out) != "2" {
+		t.Fatalf("strict known fields: %q, %v", out, err)
+	}
+	cases := []struct {
+		method string
+		args   [][]byte
+		index  int
+		typ    string
+		reason string
+	}{
+		{method: "Req", args: [][]byte{unknown}, index: 0, typ: "ref.sigOpt", reason: `json: unknown field "x"`},
+		{method: "Req", args: [][]byte{[]byte(`{"n":2} {}`)}, index: 0, typ: "ref.sigOpt", reason: "invalid character after top-level value"},
+		{method: "Opt", args: [][]byte{[]byte("a"), []byte(`{"n":1,"m":2}`)}, index: 1, typ: "*ref.sigOpt", reason: `json: unknown field "m"`},
+	}
+	for _, c := range cases {
+		_, err := CallFuncWithContext(ctx, fns, c.method, c.args...)
+		var ae *ArgError
+		if !errors.Is(err, ErrBadArguments) || !errors.As(err, &ae) {
+			t.Fatalf("%s: err = %v", c.method, err)
+		}
+		if ae.Index != c.index || ae.Type != c.typ || ae.Err.Error() != c.reason {
+			t.Errorf("%s: ArgError = {%d %s %v}", c.method, ae.Index, ae.Type, ae.Err)
+		}
+		if want := fmt.Sprintf("bad arguments: arg%d (%s): %s", c.index, c.typ, c.reason); err.Error() != want {
+			t.Errorf("%s: err = %q, want %q", c.method, err, want)
+		}
+	}
+}
+
+func TestArgError(t *testing.T) {
+	reason := errors.New("invalid length 3")
+	err := error(&ArgError{Index: 2, Type: "int64", Err: reason})
+	if err.Error() != "bad arguments: arg2 (int64): invalid length 3" {
+		t.Fatalf("Error() = %q", err)
+	}
+	if !errors.Is(err, ErrBadArguments) || !errors.Is(err, reason) {
+		t.Fatalf("errors.Is failed for %v", err)
+	}
+	var se *json.SyntaxError
+	err = &ArgError{Index: 0, Type: "ref.sigOpt", Err: json.Unmarshal([]byte("{"), new(sigOpt))}
+	if !errors.As(err, &se) {
+		t.Fatalf("errors.As(*json.SyntaxError) failed for %v", err)
+	}
+}
+
+type extSvc struct{}
+
+func (s *extSvc) Ext(ctx context.Context, at time.Time, ttl time.Duration, n *big.Int, f *big.Float) (string, error) {
+	return fmt.Sprintf("%s %s %s %s %s", at.Format(time.RFC3339Nano), at.Location(), ttl, n, f.Text('g', -1)), nil
+}
+
+func (s *extSvc) Null(ctx context.Context, n *int, at *time.Time, o *sigOpt) (string, error) {
+	return fmt.Sprintf("%v %v %v", n == nil, at == nil, o == nil), nil
+}
+
+// 协商了 ag_ext 的客户端的参数经 ag.Decoder 后解码为扩展类型；Nil 帧得到 nil 指针，零值得到非 nil 指针
+func TestInvoke_AgExt(t *testing.T) {
+	fns := Register(&extSvc{})
+	ctx := context.Background()
+	call := func(method string, args ...any) string {
+		t.Helper()
+		raw := make([][]byte, len(args))
+		for i, a := range args {
+			b, err := ag.EncodeExt(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if raw[i], err = ag.Decoder(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out, err := CallFuncWithContext(ctx, fns, method, raw...)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		return string(out)
+	}
+
+	at := time.Date(2024, 5, 6, 7, 8, 9, 10, time.FixedZone("CST", 8*3600))
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	pi, _, _ := big.ParseFloat("3.1415926535897932384626", 10, 100, big.ToNearestEven)
+	got := call("Ext", at, 90*time.Second, huge, pi)
+	want := "2024-05-06T07:08:09.00000001+08:00 CST 1m30s -123456789012345678901234567890 3.1415926535897932384626"
+	if got != want {
+		t.Errorf("Ext = %q, want %q", got, want)
+	}
+
+	zero, epoch := 0, time.Unix(0, 0)
+	if got := call("Null", nil, nil, nil); got != "true true true" {
+		t.Errorf("Null(nil) = %q", got)
+	}
+	if got := call("Null", &zero, &epoch, &sigOpt{N: 1}); got != "false false false" {
+		t.Errorf("Null(zero) = %q", got)
+	}
+
+	// 旧客户端的文本参数
+	text, _ := time.Parse(time.RFC3339, "2024-05-06T07:08:09+08:00")
+	out, err := CallFuncWithContext(ctx, fns, "Ext", []byte("2024-05-06T07:08:09+08:00"), []byte("1.5s"), []byte("12"), []byte("0.5"))
+	if err != nil || string(out) != fmt.Sprintf("2024-05-06T07:08:09+08:00 %s 1.5s 12 0.5", text.Location()) {
+		t.Errorf("Ext(text) = %q, %v", out, err)
+	}
+}
@@ -2,7 +2,11 @@ package ref
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/big"
 	"reflect"
+	"time"
 )
 
 var commonTypes = []string{"int", "int8", "int16", "int32", "int64", "uint", "uint16", "uint32", "uint64", "float32", "float64", "string", "uint8", "byte", "rune", "bool"}
@@ -13,6 +17,35 @@ var typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
 // Precompute the reflect type for error.
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 
+var (
+	typeOfTime     = reflect.TypeOf(time.Time{})
+	typeOfDuration = reflect.TypeOf(time.Duration(0))
+	typeOfBigInt   = reflect.TypeOf(big.Int{})
+	typeOfBigFloat = reflect.TypeOf(big.Float{})
+)
+
+// Unmarshal 编解码器的解码函数，把 data 解码到 v 指向的值
+type Unmarshal func(data []byte, v any) error
+
+// ErrBadArguments 参数无法解码为方法声明的类型
+var ErrBadArguments = errors.New("bad arguments")
+
+// ArgError 第 Index 个参数（从 0 开始，不含 ctx）解码失败
+// errors.Is(err, ErrBadArguments) 成立，也可 errors.As 取出底层原因（如 *json.SyntaxError）
+type ArgError struct {
+	Index int    // 参数下标，对应 ArgStruct.Name 中的 argN
+	Type  string // 期望的 Go 类型
+	Err   error  // 原因
+}
+
+func (e *ArgError) Error() string {
+	return fmt.Sprintf("%s: arg%d (%s): %v", ErrBadArguments, e.Index, e.Type, e.Err)
+}
+
+func (e *ArgError) Unwrap() []error {
+	return []error{ErrBadArguments, e.Err}
+}
+
 type Functions []string
 type ServiceApi map[string]FuncStruct
 
@@ -21,11 +54,19 @@ type ServiceFuncs struct {
 	V reflect.Value             // receiver of methods for the service
 	M map[string]reflect.Method // registered methods
 	A ServiceApi                // arguments of methods
+	D string                    // metadata of service
+	S bool                      // strict: 结构体参数拒绝未知 JSON 字段
+	I map[string]*invoker       // precompiled invokers, keyed by method name
+
+	Version    string // 注册的版本，未带版本为空
+	Deprecated string // 弃用说明，非空表示已弃用
 }
 
 type FuncStruct struct {
-	Name   string `json:"name"`
-	Define string `json:"define"`
+	Name    string      `json:"name"`
+	Define  string      `json:"define"`
+	Args    []ArgStruct `json:"args"`
+	Returns []string    `json:"returns"`
 }
 
 type ArgStruct struct {
@@ -0,0 +1,312 @@
+package ref
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 参数结构体的声明式校验，规则写在 validate tag 中，逗号分隔：
+//
+//	type Req struct {
+//		Name  string   `json:"name" validate:"required,min=1,max=64"`
+//		Level string   `json:"level" validate:"oneof=low mid high"`
+//		Tags  []string `json:"tags" validate:"omitempty,max=8"`
+//		Addr  *Addr    `json:"addr"` // 嵌套结构体（含指针、slice、map 中的元素）递归校验
+//	}
+//
+// required 非零值；min/max 数值比较大小，string（按字符）、slice、map、array 比较长度；
+// len 长度相等；oneof（别名 enum）取值在空格分隔的集合内；omitempty 零值时跳过其余规则。
+
+// FieldError 单个字段的校验失败
+type FieldError struct {
+	Field string `json:"field"` // 字段路径，按 json 名，如 items[0].name
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+func (e FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Rule)
+	}
+	return fmt.Sprintf("%s: %s=%s", e.Field, e.Rule, e.Param)
+}
+
+// ValidationErrors 一个参数上全部失败的字段
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	s := make([]string, len(v))
+	for i, e := range v {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+type rule struct {
+	name  string
+	param string
+	num   float64
+	set   []string
+}
+
+type field_rules struct {
+	index     int
+	name      string
+	rules     []rule
+	omitempty bool
+	embedded  bool
+}
+
+// struct_rules nil 表示该类型（含嵌套）没有任何规则
+type struct_rules struct {
+	fields []field_rules
+}
+
+var (
+	rulesCache sync.Map // reflect.Type -> *struct_rules
+	rulesMu    sync.Mutex
+)
+
+// compile_rules 解析 t 上的 validate tag，结果按类型缓存；tag 写错时返回错误
+func compile_rules(t reflect.Type) (*struct_rules, error) {
+	if r, ok := rulesCache.Load(t); ok {
+		return r.(*struct_rules), nil
+	}
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	r, err := compile_type(t, map[reflect.Type]bool{})
+	if err == nil {
+		rulesCache.Store(t, r)
+	}
+	return r, err
+}
+
+func compile_type(t reflect.Type, visiting map[reflect.Type]bool) (*struct_rules, error) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	if r, ok := rulesCache.Load(t); ok {
+		return r.(*struct_rules), nil
+	}
+	if visiting[t] {
+		// 递归类型：按“有规则”处理，运行时再逐层判断
+		return &struct_rules{}, nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	sr := &struct_rules{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		fr := field_rules{index: i, name: field_name(f), embedded: f.Anonymous && f.Tag.Get("json") == ""}
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			if err := parse_rules(&fr, f.Type, tag); err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.String(), f.Name, err)
+			}
+		}
+		nested, err := compile_type(f.Type, visiting)
+		if err != nil {
+			return nil, err
+		}
+		if len(fr.rules) == 0 && nested == nil {
+			continue
+		}
+		sr.fields = append(sr.fields, fr)
+	}
+	if len(sr.fields) == 0 {
+		sr = nil
+	}
+	rulesCache.Store(t, sr)
+	return sr, nil
+}
+
+func field_name(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+func parse_rules(fr *field_rules, t reflect.Type, tag string) error {
+	base := t
+	for base.Kind() == reflect.Pointer {
+		base = base.Elem()
+	}
+	for _, item := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+		r := rule{name: name, param: param}
+		switch name {
+		case "":
+			continue
+		case "omitempty":
+			fr.omitempty = true
+			continue
+		case "required":
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s=%q", name, param)
+			}
+			if !has_size(base.Kind()) && (name == "len" || !is_number(base.Kind())) {
+				return fmt.Errorf("%s not applicable to %s", name, t.String())
+			}
+			r.num = n
+		case "oneof", "enum":
+			r.name = "oneof"
+			r.set = strings.Fields(param)
+			if len(r.set) == 0 {
+				return fmt.Errorf("empty %s", name)
+			}
+			if base.Kind() != reflect.String && !is_integer(base.Kind()) {
+				return fmt.Errorf("%s not applicable to %s", name, t.String())
+			}
+		default:
+			return fmt.Errorf("unknown rule %q", name)
+		}
+		fr.rules = append(fr.rules, r)
+	}
+	return nil
+}
+
+// validate_value 校验参数值，返回全部失败字段；没有规则时直接返回 nil
+func validate_value(v reflect.Value) (ValidationErrors, error) {
+	sr, err := compile_rules(v.Type())
+	if err != nil || sr == nil {
+		return nil, err
+	}
+	var errs ValidationErrors
+	walk_value(v, "", &errs)
+	return errs, nil
+}
+
+func walk_value(v reflect.Value, path string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if r, _ := compile_rules(v.Type().Elem()); r == nil {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			walk_value(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		if r, _ := compile_rules(v.Type().Elem()); r == nil {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			walk_value(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key().Interface()), errs)
+		}
+	case reflect.Struct:
+		sr, _ := compile_rules(v.Type())
+		if sr == nil {
+			return
+		}
+		for _, fr := range sr.fields {
+			fv := v.Field(fr.index)
+			fpath := fr.name
+			if path != "" {
+				fpath = path + "." + fr.name
+			}
+			if fr.omitempty && fv.IsZero() {
+				continue
+			}
+			for _, r := range fr.rules {
+				if !check_rule(r, fv) {
+					*errs = append(*errs, FieldError{Field: fpath, Rule: r.name, Param: r.param})
+				}
+			}
+			if fr.embedded {
+				// 与 encoding/json 一致，匿名字段展开到外层
+				fpath = path
+			}
+			walk_value(fv, fpath, errs)
+		}
+	}
+}
+
+func check_rule(r rule, v reflect.Value) bool {
+	if r.name == "required" {
+		return !v.IsZero()
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			// nil 只由 required 处理
+			return true
+		}
+		v = v.Elem()
+	}
+	switch r.name {
+	case "min", "max", "len":
+		var n float64
+		switch {
+		case v.Kind() == reflect.String:
+			n = float64(utf8.RuneCountInString(v.String()))
+		case has_size(v.Kind()):
+			n = float64(v.Len())
+		case is_integer(v.Kind()) && v.CanInt():
+			n = float64(v.Int())
+		case v.CanUint():
+			n = float64(v.Uint())
+		case v.CanFloat():
+			n = v.Float()
+		}
+		switch r.name {
+		case "min":
+			return n >= r.num
+		case "max":
+			return n <= r.num
+		default:
+			return n == r.num
+		}
+	case "oneof":
+		var s string
+		switch {
+		case v.Kind() == reflect.String:
+			s = v.String()
+		case v.CanInt():
+			s = strconv.FormatInt(v.Int(), 10)
+		default:
+			s = strconv.FormatUint(v.Uint(), 10)
+		}
+		for _, o := range r.set {
+			if o == s {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func has_size(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	}
+	return false
+}
+
+func is_integer(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uintptr
+}
+
+func is_number(k reflect.Kind) bool {
+	return is_integer(k) || k == reflect.Float32 || k == reflect.Float64
+}
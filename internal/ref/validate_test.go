@@ -0,0 +1,109 @@
+package ref
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type vAddr struct {
+	City string `json:"city" validate:"required"`
+}
+
+type vBase struct {
+	Id int64 `json:"id" validate:"min=1"`
+}
+
+type vReq struct {
+	vBase
+	Name  string            `json:"name" validate:"required,min=2,max=4"`
+	Level string            `json:"level" validate:"oneof=low mid high"`
+	Code  int               `json:"code" validate:"enum=1 2 3"`
+	Tags  []string          `json:"tags" validate:"omitempty,len=2"`
+	Score *float64          `json:"score" validate:"max=100"`
+	Addr  *vAddr            `json:"addr" validate:"required"`
+	Items []vAddr           `json:"items"`
+	ByKey map[string]*vAddr `json:"by_key"`
+	skip  string            `validate:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	score := 120.0
+	req := &vReq{
+		Name:  "世界和平啊",
+		Level: "top",
+		Code:  4,
+		Tags:  []string{"a"},
+		Score: &score,
+		Items: []vAddr{{City: "x"}, {}},
+		ByKey: map[string]*vAddr{"k": {}},
+	}
+	errs, err := validate_value(reflect.ValueOf(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ValidationErrors{
+		{Field: "id", Rule: "min", Param: "1"},
+		{Field: "name", Rule: "max", Param: "4"},
+		{Field: "level", Rule: "oneof", Param: "low mid high"},
+		{Field: "code", Rule: "oneof", Param: "1 2 3"},
+		{Field: "tags", Rule: "len", Param: "2"},
+		{Field: "score", Rule: "max", Param: "100"},
+		{Field: "addr", Rule: "required"},
+		{Field: "items[1].city", Rule: "required"},
+		{Field: "by_key[k].city", Rule: "required"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Fatalf("errs =\n%v\nwant\n%v", errs, want)
+	}
+
+	ok := &vReq{vBase: vBase{Id: 1}, Name: "ab", Level: "mid", Code: 2, Addr: &vAddr{City: "c"}}
+	if errs, _ := validate_value(reflect.ValueOf(ok)); len(errs) != 0 {
+		t.Fatalf("unexpected %v", errs)
+	}
+	// 没有规则的类型
+	if errs, err := validate_value(reflect.ValueOf(42)); errs != nil || err != nil {
+		t.Fatal(errs, err)
+	}
+}
+
+type vBadTag struct {
+	N bool `validate:"min=1"`
+}
+
+type vUnknown struct {
+	N string `validate:"email"`
+}
+
+func TestValidate_BadTag(t *testing.T) {
+	for _, v := range []any{vBadTag{}, &vUnknown{}} {
+		if _, err := compile_rules(reflect.TypeOf(v)); err == nil {
+			t.Errorf("%T: want error", v)
+		}
+	}
+}
+
+type vSvc struct{}
+
+func (s *vSvc) Save(ctx context.Context, id int, req *vReq) (int, error) { return id, nil }
+func (s *vSvc) Bad(ctx context.Context, req vBadTag) (int, error)        { return 0, nil }
+
+func TestInvoke_Validate(t *testing.T) {
+	fns := Register(&vSvc{})
+	if _, ok := fns.M["Bad"]; ok {
+		t.Fatal("method with invalid validate tag should be rejected")
+	}
+	_, err := InvokeFuncWithContext(context.Background(), fns, "Save", []byte{1}, []byte(`{"name":"a","level":"low","code":1}`))
+	var ae *ArgError
+	var ve ValidationErrors
+	if !errors.Is(err, ErrBadArguments) || !errors.As(err, &ae) || !errors.As(err, &ve) {
+		t.Fatalf("err = %v", err)
+	}
+	if ae.Index != 1 || len(ve) != 3 {
+		t.Fatalf("index = %d, fields = %v", ae.Index, ve)
+	}
+	if got := err.Error(); got != "bad arguments: arg1 (*ref.vReq): id: min=1; name: min=2; addr: required" {
+		t.Fatalf("err = %s", got)
+	}
+}
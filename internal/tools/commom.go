@@ -10,19 +10,18 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
-	"github.com/bwmarrin/snowflake"
 	"io"
+	"strconv"
 	"time"
+
+	"github.com/w6xian/sloth/v3/internal/utils/id"
 )
 
 const SessionPrefix = "sess_"
 
 func GetSnowflakeId() string {
-	//default node id eq 1,this can modify to different serverId node
-	node, _ := snowflake.NewNode(1)
-	// Generate a snowflake ID.
-	id := node.Generate().String()
-	return id
+	// 进程默认节点（id.SetNode 配置），不再每次新建 snowflake 节点
+	return strconv.FormatInt(id.Next(), 10)
 }
 
 func GetRandomToken(length int) string {
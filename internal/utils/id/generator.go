@@ -0,0 +1,112 @@
+package id
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ID 与 snowflake 布局一致：41 位毫秒时间戳 | 10 位节点 | 12 位序号
+const (
+	NodeBits = 10
+	SeqBits  = 12
+	MaxNode  = 1<<NodeBits - 1
+
+	// Epoch 与 github.com/bwmarrin/snowflake 默认值一致，旧 ID 与新 ID 可以比较先后
+	Epoch int64 = 1288834974657
+
+	seqMask = 1<<SeqBits - 1
+)
+
+// DefaultNode 未配置时的节点号（与旧版 NextId(1) 一致）
+const DefaultNode int64 = 1
+
+// Generator 单调递增的 ID 源，并发安全、无锁。
+// 同一毫秒内序号用完时借用下一毫秒，时钟回拨时沿用已发出的最大时间戳，保证同一 Generator 不重复
+type Generator struct {
+	node int64
+	// last 最近一次发出的 时间戳<<SeqBits | 序号
+	last atomic.Int64
+	now  func() int64
+}
+
+// NewGenerator node 取 0..MaxNode，集群中每个进程配置不同的 node 即全局唯一
+func NewGenerator(node int64) (*Generator, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("id: node %d out of range [0, %d]", node, MaxNode)
+	}
+	return &Generator{node: node, now: now_ms}, nil
+}
+
+func (g *Generator) Node() int64 {
+	return g.node
+}
+
+// Next 下一个 ID，严格大于本 Generator 之前发出的所有 ID
+func (g *Generator) Next() int64 {
+	ts := g.now() << SeqBits
+	for {
+		last := g.last.Load()
+		next := max(ts, last+1)
+		if g.last.CompareAndSwap(last, next) {
+			return next>>SeqBits<<(NodeBits+SeqBits) | g.node<<SeqBits | next&seqMask
+		}
+	}
+}
+
+func now_ms() int64 {
+	return time.Now().UnixMilli() - Epoch
+}
+
+var (
+	std   atomic.Pointer[Generator]
+	nodes sync.Map // int64 -> *Generator
+)
+
+func init() {
+	g, _ := NewGenerator(DefaultNode)
+	std.Store(g)
+	nodes.Store(DefaultNode, g)
+}
+
+// SetNode 设置进程默认 Generator 的节点号，之后 Next 使用新节点
+func SetNode(node int64) error {
+	g, err := node_generator(node)
+	if err != nil {
+		return err
+	}
+	std.Store(g)
+	return nil
+}
+
+// Node 进程默认 Generator 的节点号
+func Node() int64 {
+	return std.Load().node
+}
+
+// Next 进程默认 Generator 的下一个 ID
+func Next() int64 {
+	return std.Load().Next()
+}
+
+// NextId 节点 svr 的下一个 ID，同一节点在进程内共用一个 Generator；svr 非法时返回 0
+func NextId(svr int64) int64 {
+	g, err := node_generator(svr)
+	if err != nil {
+		return 0
+	}
+	return g.Next()
+}
+
+func node_generator(node int64) (*Generator, error) {
+	if g, ok := nodes.Load(node); ok {
+		return g.(*Generator), nil
+	}
+	g, err := NewGenerator(node)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := nodes.LoadOrStore(node, g)
+	return actual.(*Generator), nil
+}
@@ -0,0 +1,155 @@
+package id
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bwmarrin/snowflake"
+)
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    int64
+		wantErr bool
+	}{
+		{name: "zero", node: 0},
+		{name: "default", node: DefaultNode},
+		{name: "max", node: MaxNode},
+		{name: "negative", node: -1, wantErr: true},
+		{name: "too-large", node: MaxNode + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGenerator(tt.node)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewGenerator(%d) err = %v, wantErr %v", tt.node, err, tt.wantErr)
+			}
+			if err == nil && g.Node() != tt.node {
+				t.Errorf("Node() = %d, want %d", g.Node(), tt.node)
+			}
+		})
+	}
+}
+
+func TestGeneratorSnowflakeLayout(t *testing.T) {
+	g, _ := NewGenerator(777)
+	for i := 0; i < 10; i++ {
+		sf := snowflake.ParseInt64(g.Next())
+		if sf.Node() != 777 {
+			t.Fatalf("Node() = %d, want 777", sf.Node())
+		}
+		if d := now_ms() + Epoch - sf.Time(); d < 0 || d > 1000 {
+			t.Fatalf("Time() off by %d ms", d)
+		}
+	}
+}
+
+func TestGeneratorSeqOverflow(t *testing.T) {
+	g, _ := NewGenerator(3)
+	g.now = func() int64 { return 1000 }
+	var prev int64
+	for i := 0; i < 3*(seqMask+1); i++ {
+		id := g.Next()
+		if id <= prev {
+			t.Fatalf("Next() = %d after %d", id, prev)
+		}
+		prev = id
+	}
+	// 序号用完后借用下一毫秒
+	if ts := prev >> (NodeBits + SeqBits); ts != 1002 {
+		t.Errorf("timestamp = %d, want 1002", ts)
+	}
+}
+
+func TestGeneratorClockBackwards(t *testing.T) {
+	g, _ := NewGenerator(3)
+	ms := int64(5000)
+	g.now = func() int64 { return ms }
+	a := g.Next()
+	ms = 4000
+	b := g.Next()
+	if b <= a {
+		t.Fatalf("Next() = %d after %d with clock moved backwards", b, a)
+	}
+}
+
+func TestGeneratorConcurrent(t *testing.T) {
+	const workers, per = 16, 20000
+	g, _ := NewGenerator(5)
+	out := make([][]int64, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			ids := make([]int64, per)
+			for i := range ids {
+				ids[i] = g.Next()
+			}
+			out[w] = ids
+		}(w)
+	}
+	wg.Wait()
+	seen := make(map[int64]struct{}, workers*per)
+	for _, ids := range out {
+		for i, id := range ids {
+			if i > 0 && id <= ids[i-1] {
+				t.Fatalf("not monotonic: %d after %d", id, ids[i-1])
+			}
+			if _, ok := seen[id]; ok {
+				t.Fatalf("duplicate id %d", id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+}
+
+func TestNextIdSharesNode(t *testing.T) {
+	seen := make(map[int64]struct{})
+	for i := 0; i < 10000; i++ {
+		id := NextId(9)
+		if _, ok := seen[id]; ok {
+			t.Fatalf("duplicate id %d", id)
+		}
+		seen[id] = struct{}{}
+	}
+	if got := NextId(MaxNode + 1); got != 0 {
+		t.Errorf("NextId(invalid) = %d, want 0", got)
+	}
+}
+
+func TestSetNode(t *testing.T) {
+	defer SetNode(DefaultNode)
+	if err := SetNode(MaxNode + 1); err == nil {
+		t.Fatal("SetNode(invalid) err = nil")
+	}
+	if err := SetNode(42); err != nil {
+		t.Fatal(err)
+	}
+	if Node() != 42 || snowflake.ParseInt64(Next()).Node() != 42 {
+		t.Errorf("Node() = %d, want 42", Node())
+	}
+}
+
+func BenchmarkNext(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Next()
+	}
+}
+
+func BenchmarkNextParallel(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			Next()
+		}
+	})
+}
+
+// BenchmarkSnowflakeNewNode 旧实现：每次新建 snowflake 节点
+func BenchmarkSnowflakeNewNode(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		node, _ := snowflake.NewNode(1)
+		node.Generate()
+	}
+}
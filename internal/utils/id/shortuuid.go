@@ -2,7 +2,6 @@ package id
 
 import (
 	"github.com/btcsuite/btcutil/base58"
-	"github.com/bwmarrin/snowflake"
 	"github.com/google/uuid"
 )
 
@@ -25,12 +24,3 @@ func ShortID() string {
 	return ShortStringID()
 }
 
-func NextId(svr int64) int64 {
-	node, err := snowflake.NewNode(svr)
-	if err != nil {
-		return 0
-	}
-	id := node.Generate()
-	return id.Int64()
-}
-
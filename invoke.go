@@ -0,0 +1,57 @@
+package sloth
+
+import (
+	"context"
+
+	"github.com/w6xian/sloth/v3/message"
+)
+
+// 返回值编码协商：调用方在 header 中携带 ReplyTypeHeader=ReplyTypeAg 时，
+// 服务方把返回值编码为带类型标签的 ag 帧（ag.EncodeTyped），否则沿用 utils.AnyToBytes。
+// 协商了编解码器（CodecHeader）时以编解码器为准。
+const (
+	ReplyTypeHeader = "reply_type"
+	ReplyTypeAg     = "ag"
+)
+
+// Invoke 调用服务端方法，并把返回值解码为 Resp
+// @example
+//
+//	res, err := sloth.Invoke[*Result](ctx, client, "v1.Test", &AB{A: 1, B: 2})
+func Invoke[Resp any](ctx context.Context, rpc *ServerRpc, mtd string, args ...any) (Resp, error) {
+	return InvokeWithHeader[Resp](ctx, rpc, message.Header{}, mtd, args...)
+}
+
+// InvokeWithHeader 同 Invoke，附带本次调用的 header
+func InvokeWithHeader[Resp any](ctx context.Context, rpc *ServerRpc, header message.Header, mtd string, args ...any) (Resp, error) {
+	var resp Resp
+	data, err := rpc.CallWithHeader(ctx, replyHeader(header), mtd, args...)
+	if err != nil {
+		return resp, err
+	}
+	err = decode_reply(data, &resp, header, rpc.Header)
+	return resp, err
+}
+
+// InvokeUser 调用指定用户连接上注册的方法，并把返回值解码为 Resp
+func InvokeUser[Resp any](ctx context.Context, rpc *ClientRpc, userId int64, mtd string, args ...any) (Resp, error) {
+	return InvokeUserWithHeader[Resp](ctx, rpc, message.Header{}, userId, mtd, args...)
+}
+
+// InvokeUserWithHeader 同 InvokeUser，附带本次调用的 header
+func InvokeUserWithHeader[Resp any](ctx context.Context, rpc *ClientRpc, header message.Header, userId int64, mtd string, args ...any) (Resp, error) {
+	var resp Resp
+	data, err := rpc.CallWithHeader(ctx, replyHeader(header), userId, mtd, args...)
+	if err != nil {
+		return resp, err
+	}
+	err = decode_reply(data, &resp, header, rpc.Header)
+	return resp, err
+}
+
+// replyHeader 复制 header 并要求对端以 ag 帧返回结果
+func replyHeader(header message.Header) message.Header {
+	h := header.Clone()
+	h.Set(ReplyTypeHeader, ReplyTypeAg)
+	return h
+}
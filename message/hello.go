@@ -0,0 +1,107 @@
+package message
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// ProtocolVersion 线上协议版本，帧格式或语义有不兼容变化时递增
+const ProtocolVersion = 3
+
+// hello 中的特性名，表示本端能接收/理解的格式
+const (
+	// FeatureBinary 二进制分片（frame.Encode）
+	FeatureBinary = "binary"
+	// FeatureWideSlice 超过 255 片的 WideSlice 分片
+	FeatureWideSlice = "wide_slice"
+	// FeatureCompress 带 frame.Compressed 标记的压缩分片
+	FeatureCompress = "compress"
+	// FeatureEncrypt fn 帧体加密（握手头中已完成密钥交换）
+	FeatureEncrypt = "encrypt"
+	// FeatureAgExt ag 原生复合帧、扩展长度帧与时间/大数标签
+	FeatureAgExt = "ag_ext"
+)
+
+// Hello 连接建立后双方交换的能力声明，以 ACTION_HELLO 的 fn 帧（ID 为 0，Data 为 JSON）发送：
+// 客户端升级后立即发送，服务端收到后回复自己的 Hello
+type Hello struct {
+	// Protocol 协议版本（ProtocolVersion）
+	Protocol int `json:"protocol"`
+	// Version SDK 版本
+	Version string `json:"version,omitempty"`
+	// Codecs 支持的编解码器（codec 注册表中的名字）
+	Codecs []string `json:"codecs,omitempty"`
+	// Compress 能解压的算法
+	Compress []string `json:"compress,omitempty"`
+	// MaxFrame 单条消息最大字节数，0 为不限制
+	MaxFrame int64 `json:"max_frame,omitempty"`
+	// Features 支持的特性（Feature*）
+	Features []string `json:"features,omitempty"`
+}
+
+func (h *Hello) Bytes() []byte {
+	b, _ := json.Marshal(h)
+	return b
+}
+
+func ParseHello(b []byte) (*Hello, error) {
+	h := &Hello{}
+	if err := json.Unmarshal(b, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Capabilities 双方能力的交集
+type Capabilities struct {
+	// Protocol 双方协议版本中较小的一个
+	Protocol int `json:"protocol"`
+	// PeerVersion 对端 SDK 版本
+	PeerVersion string   `json:"peer_version,omitempty"`
+	Codecs      []string `json:"codecs,omitempty"`
+	Compress    []string `json:"compress,omitempty"`
+	// MaxFrame 双方上限中较小的一个，0 为不限制
+	MaxFrame int64    `json:"max_frame,omitempty"`
+	Features []string `json:"features,omitempty"`
+	// Legacy 对端没有发送 Hello（旧版本），其余字段为空，按旧版行为处理
+	Legacy bool `json:"legacy,omitempty"`
+}
+
+// Has 双方是否都支持 feature
+func (c *Capabilities) Has(feature string) bool {
+	return c != nil && slices.Contains(c.Features, feature)
+}
+
+// HasCodec 双方是否都支持编解码器 name
+func (c *Capabilities) HasCodec(name string) bool {
+	return c != nil && slices.Contains(c.Codecs, name)
+}
+
+// Negotiate 取本端与对端能力的交集（保持本端的顺序）；peer 为 nil 时返回 Legacy
+func Negotiate(local, peer *Hello) *Capabilities {
+	if peer == nil {
+		return &Capabilities{Legacy: true}
+	}
+	c := &Capabilities{
+		Protocol:    min(local.Protocol, peer.Protocol),
+		PeerVersion: peer.Version,
+		Codecs:      intersect(local.Codecs, peer.Codecs),
+		Compress:    intersect(local.Compress, peer.Compress),
+		Features:    intersect(local.Features, peer.Features),
+		MaxFrame:    local.MaxFrame,
+	}
+	if peer.MaxFrame > 0 && (c.MaxFrame <= 0 || peer.MaxFrame < c.MaxFrame) {
+		c.MaxFrame = peer.MaxFrame
+	}
+	return c
+}
+
+func intersect(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if slices.Contains(b, v) && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
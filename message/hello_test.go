@@ -0,0 +1,51 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	local := &Hello{
+		Protocol: 3, Version: "3.0.2",
+		Codecs:   []string{"ag", "json", "tlv"},
+		Compress: []string{"deflate"},
+		MaxFrame: 1 << 20,
+		Features: []string{FeatureBinary, FeatureWideSlice, FeatureCompress, FeatureAgExt},
+	}
+	raw := (&Hello{
+		Protocol: 4, Version: "3.1.0",
+		Codecs:   []string{"json", "ag", "msgpack"},
+		MaxFrame: 64 << 10,
+		Features: []string{FeatureAgExt, FeatureBinary, "future"},
+	}).Bytes()
+	peer, err := ParseHello(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Negotiate(local, peer)
+	want := &Capabilities{
+		Protocol: 3, PeerVersion: "3.1.0",
+		Codecs:   []string{"ag", "json"},
+		MaxFrame: 64 << 10,
+		Features: []string{FeatureBinary, FeatureAgExt},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if !got.Has(FeatureBinary) || got.Has(FeatureCompress) || !got.HasCodec("json") || got.HasCodec("tlv") {
+		t.Fatalf("Has/HasCodec on %+v", got)
+	}
+
+	// 对端不限制时取本端上限
+	if c := Negotiate(local, &Hello{Protocol: 3}); c.MaxFrame != 1<<20 || c.Features != nil {
+		t.Fatalf("unlimited peer: %+v", c)
+	}
+	if c := Negotiate(local, nil); !c.Legacy || c.Has(FeatureBinary) {
+		t.Fatalf("legacy: %+v", c)
+	}
+	var none *Capabilities
+	if none.Has(FeatureBinary) {
+		t.Fatal("nil capabilities has feature")
+	}
+}
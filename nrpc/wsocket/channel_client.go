@@ -10,9 +10,11 @@ import (
 	"time"
 
 	"github.com/w6xian/sloth/v3/actions"
+	"github.com/w6xian/sloth/v3/decoder"
 	"github.com/w6xian/sloth/v3/decoder/fn"
+	"github.com/w6xian/sloth/v3/decoder/frame"
+	"github.com/w6xian/sloth/v3/decoder/seal"
 	"github.com/w6xian/sloth/v3/internal/utils"
-	"github.com/w6xian/sloth/v3/internal/utils/id"
 	"github.com/w6xian/sloth/v3/message"
 	"github.com/w6xian/sloth/v3/types/auth"
 	"github.com/w6xian/sloth/v3/types/trpc"
@@ -47,6 +49,22 @@ type WsChannelClient struct {
 	readWait time.Duration
 	// func
 	rpc_io atomic.Int64
+	// reasm 按片名重组收到的分片
+	reasm *frame.Reassembler
+	// names 发送分片的片名
+	names sliceNames
+	// binary 以二进制分片发送（默认），关闭时为 JSON 文本分片
+	binary atomic.Bool
+	// comp 服务端声明支持压缩时不为 nil
+	comp *frame.Compressor
+	// sess 服务端同意加密时不为 nil，fn 帧体加密
+	sess *seal.Session
+	// caps 与服务端协商出的能力，OnReady 之前确定
+	caps      atomic.Pointer[message.Capabilities]
+	helloOnce sync.Once
+	// proto 服务端选定的子协议；text 本连接只发文本分片（TextFrames 或子协议为 SubprotocolText）
+	proto string
+	text  bool
 }
 
 func NewWsChannelClient(connect trpc.ICallRpc, opts ...ChannelClientOption) (c *WsChannelClient) {
@@ -64,12 +82,47 @@ func NewWsChannelClient(connect trpc.ICallRpc, opts ...ChannelClientOption) (c *
 	c.Sign = ""
 	c.Connect = connect
 	c.defaultHeader = message.Header{}
+	c.reasm = frame.NewReassembler()
+	c.binary.Store(true)
 	for _, opt := range opts {
 		opt(c)
 	}
 	return
 }
 
+// Binary 是否以二进制分片发送
+func (c *WsChannelClient) Binary() bool {
+	return c.binary.Load()
+}
+
+// ReassembleStats 分片重组统计
+func (c *WsChannelClient) ReassembleStats() frame.ReassembleStats {
+	return c.reasm.Stats()
+}
+
+// Capabilities 与服务端协商出的能力，协商完成（OnReady）前为 nil
+func (c *WsChannelClient) Capabilities() *message.Capabilities {
+	return c.caps.Load()
+}
+
+// Subprotocol 服务端选定的子协议，旧版服务端为空
+func (c *WsChannelClient) Subprotocol() string {
+	return c.proto
+}
+
+// Encrypted fn 帧是否加密传输
+func (c *WsChannelClient) Encrypted() bool {
+	return c.sess != nil
+}
+
+// CompressStats 发送侧压缩统计，对端不支持压缩时为零值
+func (c *WsChannelClient) CompressStats() frame.CompressStats {
+	if c.comp == nil {
+		return frame.CompressStats{}
+	}
+	return c.comp.Stats()
+}
+
 func (c *WsChannelClient) DefaultHeader() message.Header {
 	return c.defaultHeader
 }
@@ -174,7 +227,7 @@ func (ch *WsChannelClient) Call(ctx context.Context, header message.Header, mtd
 	msg.Args = args
 	payload := utils.Serialize(msg)
 	putCallObj(msg)
-	callId := uint64(id.NextId(1))
+	callId := decoder.NextId()
 	payload, err := fn.Encode(actions.ACTION_CALL, callId, payload)
 	if err != nil {
 		return nil, err
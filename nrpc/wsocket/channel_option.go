@@ -1,8 +1,31 @@
 package wsocket
 
+import "github.com/w6xian/sloth/v3/decoder/frame"
+
 type ChannelServerOption func(ch *WsChannelServer)
 type ChannelClientOption func(s *WsChannelClient)
 
+// WithTextFrames 客户端只发送 JSON 文本分片
+func WithTextFrames(text bool) ChannelClientOption {
+	return func(s *WsChannelClient) {
+		s.binary.Store(!text)
+	}
+}
+
+// WithServerReassemble 分片重组的大小/超时等限制
+func WithServerReassemble(opts ...frame.ReassembleOption) ChannelServerOption {
+	return func(ch *WsChannelServer) {
+		ch.reasm = frame.NewReassembler(opts...)
+	}
+}
+
+// WithClientReassemble 分片重组的大小/超时等限制
+func WithClientReassemble(opts ...frame.ReassembleOption) ChannelClientOption {
+	return func(s *WsChannelClient) {
+		s.reasm = frame.NewReassembler(opts...)
+	}
+}
+
 func WithAddr(addr string) ChannelClientOption {
 	return func(s *WsChannelClient) {
 		s.addr = addr
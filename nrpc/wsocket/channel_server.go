@@ -12,9 +12,11 @@ import (
 
 	"github.com/w6xian/sloth/v3/actions"
 	"github.com/w6xian/sloth/v3/bucket"
+	"github.com/w6xian/sloth/v3/decoder"
 	"github.com/w6xian/sloth/v3/decoder/fn"
+	"github.com/w6xian/sloth/v3/decoder/frame"
+	"github.com/w6xian/sloth/v3/decoder/seal"
 	"github.com/w6xian/sloth/v3/internal/utils"
-	"github.com/w6xian/sloth/v3/internal/utils/id"
 	"github.com/w6xian/sloth/v3/message"
 	"github.com/w6xian/sloth/v3/types/auth"
 	"github.com/w6xian/sloth/v3/types/trpc"
@@ -52,6 +54,22 @@ type WsChannelServer struct {
 
 	callObjPool sync.Pool
 	backObjPool sync.Pool
+	// reasm 按片名重组收到的分片
+	reasm *frame.Reassembler
+	// names 发送分片的片名
+	names sliceNames
+	// binary 对端发来过二进制分片，回复也用二进制分片
+	binary atomic.Bool
+	// comp 客户端声明支持压缩时不为 nil
+	comp *frame.Compressor
+	// sess 客户端同意加密时不为 nil，fn 帧体加密
+	sess *seal.Session
+	// caps 与客户端协商出的能力，OnReady 之前确定
+	caps      atomic.Pointer[message.Capabilities]
+	helloOnce sync.Once
+	// proto 升级时选定的子协议；text 本连接只发文本分片（服务端 TextFrames 或子协议为 SubprotocolText）
+	proto string
+	text  bool
 }
 
 func (ch *WsChannelServer) Next(n ...bucket.IChannel) bucket.IChannel {
@@ -143,6 +161,7 @@ func NewWsChannelServer(connect trpc.ICallRpc, opts ...ChannelServerOption) (c *
 	c.pingPeriod = 54 * time.Second
 	c._sign = ""
 	c.Connect = connect
+	c.reasm = frame.NewReassembler()
 	c.errHandler = func(err error) {
 		log.Println("Channel errHandler:", err.Error())
 	}
@@ -163,6 +182,39 @@ func NewWsChannelServer(connect trpc.ICallRpc, opts ...ChannelServerOption) (c *
 	return
 }
 
+// Binary 是否以二进制分片发送
+func (ch *WsChannelServer) Binary() bool {
+	return ch.binary.Load()
+}
+
+// ReassembleStats 分片重组统计
+func (ch *WsChannelServer) ReassembleStats() frame.ReassembleStats {
+	return ch.reasm.Stats()
+}
+
+// Capabilities 与客户端协商出的能力，协商完成（OnReady）前为 nil
+func (ch *WsChannelServer) Capabilities() *message.Capabilities {
+	return ch.caps.Load()
+}
+
+// Subprotocol 升级时选定的子协议，旧版客户端为空
+func (ch *WsChannelServer) Subprotocol() string {
+	return ch.proto
+}
+
+// Encrypted fn 帧是否加密传输
+func (ch *WsChannelServer) Encrypted() bool {
+	return ch.sess != nil
+}
+
+// CompressStats 发送侧压缩统计，对端不支持压缩时为零值
+func (ch *WsChannelServer) CompressStats() frame.CompressStats {
+	if ch.comp == nil {
+		return frame.CompressStats{}
+	}
+	return ch.comp.Stats()
+}
+
 func (ch *WsChannelServer) OnError(f func(err error)) {
 	ch.errHandler = f
 }
@@ -217,7 +269,7 @@ func (ch *WsChannelServer) Call(ctx context.Context, header message.Header, mtd
 	payload := utils.Serialize(msg)
 
 	putCallObj(msg)
-	callId := uint64(id.NextId(1))
+	callId := decoder.NextId()
 	payload, err := fn.Encode(actions.ACTION_CALL, callId, payload)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,105 @@
+package wsocket_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/w6xian/sloth/v3"
+	"github.com/w6xian/sloth/v3/actions"
+	"github.com/w6xian/sloth/v3/decoder/fn"
+	"github.com/w6xian/sloth/v3/decoder/frame"
+	"github.com/w6xian/sloth/v3/decoder/seal"
+	"github.com/w6xian/sloth/v3/message"
+	"github.com/w6xian/sloth/v3/nrpc/wsocket"
+)
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	psk := []byte("test psk")
+	sh := newServerHandler()
+	addr, path := serve(t, sh, sloth.WithEncryptionKey(psk))
+	ch := newClientHandler()
+	cli := dial(t, addr, path, ch, sloth.WithEncryptionRequired(true), sloth.WithEncryptionKey(psk))
+
+	if sc := recv(t, sh.ready); !sc.(interface{ Encrypted() bool }).Encrypted() {
+		t.Fatal("server channel not encrypted")
+	}
+	if cc := recv(t, ch.ready); !cc.(interface{ Encrypted() bool }).Encrypted() {
+		t.Fatal("client channel not encrypted")
+	}
+	data, err := cli.Call(t.Context(), "echo.Say", "hi")
+	if err != nil || !strings.Contains(string(data), "echo:hi") {
+		t.Fatalf("Call = %q, %v", data, err)
+	}
+}
+
+func TestEncryptionRequired(t *testing.T) {
+	addr, path := serve(t, newServerHandler(), sloth.WithEncryptionRequired(true))
+	_, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+path, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("dial without key: resp %v, err %v", resp, err)
+	}
+}
+
+func TestEncryptionKeyMismatch(t *testing.T) {
+	addr, path := serve(t, newServerHandler(), sloth.WithEncryptionKey([]byte("server")))
+	ch := newClientHandler()
+	dial(t, addr, path, ch, sloth.WithEncryptionKey([]byte("client")))
+	if err := recv(t, ch.errs); !errors.Is(err, seal.ErrKeyMismatch) {
+		t.Fatalf("err = %v, want ErrKeyMismatch", err)
+	}
+}
+
+func TestEncryptedTamperedFrame(t *testing.T) {
+	sh := newServerHandler()
+	addr, path := serve(t, sh, sloth.WithEncryption(true))
+	key, _ := seal.GenerateKey()
+	header := http.Header{}
+	header.Set(wsocket.KeyHeader, base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()))
+	conn, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+path, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	peer, _ := base64.RawURLEncoding.DecodeString(resp.Header.Get(wsocket.KeyHeader))
+	sess, err := seal.NewSession(key, peer, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(m []byte) {
+		t.Helper()
+		slices, err := frame.Split("t0", m, 4096, frame.BinaryMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range slices {
+			if err := conn.WriteMessage(websocket.BinaryMessage, s.Encode()); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	hello, _ := fn.Encode(actions.ACTION_HELLO, 0, (&message.Hello{Protocol: message.ProtocolVersion}).Bytes())
+	sealed, err := sess.Seal(hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1]++
+	send(tampered)
+	if err := recv(t, sh.errs); !errors.Is(err, seal.ErrOpen) {
+		t.Fatalf("tampered err = %v, want ErrOpen", err)
+	}
+	// 被拒绝的伪造帧不影响真实的帧，真实帧重放则被拒绝
+	send(sealed)
+	if sc := recv(t, sh.ready); !sc.(interface{ Encrypted() bool }).Encrypted() {
+		t.Fatal("server channel not encrypted")
+	}
+	send(sealed)
+	if err := recv(t, sh.errs); !errors.Is(err, seal.ErrReplay) {
+		t.Fatalf("replay err = %v, want ErrReplay", err)
+	}
+}
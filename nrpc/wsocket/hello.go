@@ -0,0 +1,39 @@
+package wsocket
+
+import (
+	"time"
+
+	"github.com/w6xian/sloth/v3/actions"
+	"github.com/w6xian/sloth/v3/codec"
+	"github.com/w6xian/sloth/v3/decoder/fn"
+	"github.com/w6xian/sloth/v3/message"
+)
+
+// helloTimeout 升级时协商了 sloth 子协议后等待对端 Hello 的时间，超时按旧版对端处理（Capabilities.Legacy）；
+// 没有协商子协议的连接不等待，直接按旧版处理
+const helloTimeout = 2 * time.Second
+
+// localHello 本端能力：textFrames 时不声明 binary，对端随之只发文本分片
+func localHello(version string, maxFrame int64, textFrames bool, encrypted bool) *message.Hello {
+	features := make([]string, 0, 5)
+	if !textFrames {
+		features = append(features, message.FeatureBinary)
+	}
+	features = append(features, message.FeatureWideSlice, message.FeatureCompress, message.FeatureAgExt)
+	if encrypted {
+		features = append(features, message.FeatureEncrypt)
+	}
+	return &message.Hello{
+		Protocol: message.ProtocolVersion,
+		Version:  version,
+		Codecs:   codec.Names(),
+		Compress: []string{compressDeflate},
+		MaxFrame: maxFrame,
+		Features: features,
+	}
+}
+
+func helloFrame(h *message.Hello) []byte {
+	b, _ := fn.Encode(actions.ACTION_HELLO, 0, h.Bytes())
+	return b
+}
@@ -0,0 +1,95 @@
+package wsocket_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/w6xian/sloth/v3"
+	"github.com/w6xian/sloth/v3/message"
+	"github.com/w6xian/sloth/v3/nrpc/wsocket"
+)
+
+func TestHelloExchange(t *testing.T) {
+	sh := newServerHandler()
+	addr, path := serve(t, sh)
+	ch := newClientHandler()
+	start := time.Now()
+	dial(t, addr, path, ch)
+
+	for _, c := range []any{recv(t, sh.ready), recv(t, ch.ready)} {
+		caps := sloth.PeerCapabilities(c)
+		if caps == nil || caps.Legacy || caps.Protocol != message.ProtocolVersion || !caps.Has(message.FeatureBinary) {
+			t.Fatalf("caps = %+v", caps)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("OnReady after %v", d)
+	}
+}
+
+// 旧版客户端不声明子协议、不发 Hello，服务端不等待直接按旧版处理
+func TestHelloLegacyClient(t *testing.T) {
+	sh := newServerHandler()
+	addr, path := serve(t, sh)
+	start := time.Now()
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if caps := sloth.PeerCapabilities(recv(t, sh.ready)); caps == nil || !caps.Legacy {
+		t.Fatalf("caps = %+v", caps)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("OnReady after %v", d)
+	}
+}
+
+// 声明了子协议却不发 Hello，超时后按旧版处理
+func TestHelloTimeout(t *testing.T) {
+	sh := newServerHandler()
+	addr, path := serve(t, sh)
+	dialer := websocket.Dialer{Subprotocols: []string{wsocket.SubprotocolBin}}
+	start := time.Now()
+	conn, _, err := dialer.Dial("ws://"+addr+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if caps := sloth.PeerCapabilities(recv(t, sh.ready)); caps == nil || !caps.Legacy {
+		t.Fatalf("caps = %+v", caps)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Fatalf("OnReady after %v, want hello timeout", d)
+	}
+}
+
+// 旧版服务端不选子协议，客户端不发 Hello，直接按旧版处理
+func TestHelloLegacyServer(t *testing.T) {
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer hs.Close()
+	ch := newClientHandler()
+	start := time.Now()
+	dial(t, strings.TrimPrefix(hs.URL, "http://"), "/ws", ch)
+	if caps := sloth.PeerCapabilities(recv(t, ch.ready)); caps == nil || !caps.Legacy {
+		t.Fatalf("caps = %+v", caps)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("OnReady after %v", d)
+	}
+}
@@ -0,0 +1,95 @@
+package wsocket_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/w6xian/sloth/v3/actions"
+	"github.com/w6xian/sloth/v3/decoder/ag"
+	"github.com/w6xian/sloth/v3/decoder/fn"
+	"github.com/w6xian/sloth/v3/decoder/frame"
+	"github.com/w6xian/sloth/v3/message"
+	"github.com/w6xian/sloth/v3/nrpc/wsocket"
+)
+
+// 超过 255 片的消息：协商了 wide_slice 的对端正常收发
+func TestWideSliceReply(t *testing.T) {
+	addr, path := serve(t, newServerHandler())
+	ch := newClientHandler()
+	cli := dial(t, addr, path, ch)
+	if caps := recv(t, ch.ready).(interface{ Capabilities() *message.Capabilities }).Capabilities(); !caps.Has(message.FeatureWideSlice) {
+		t.Fatalf("caps = %+v", caps)
+	}
+	big := strings.Repeat("x", 300*1024)
+	data, err := cli.Call(t.Context(), "echo.Say", big)
+	if err != nil || !strings.Contains(string(data), "echo:"+big) {
+		t.Fatalf("Call = %d bytes, %v", len(data), err)
+	}
+}
+
+// 旧版对端不认识 WideSlice：超过 255 片的回复改为 ErrTooManySlices 错误回复，连接保持；
+// 握手头声明了压缩但没有 hello 协商 compress 时同样不发压缩分片
+func TestWideSliceLegacyPeer(t *testing.T) {
+	addr, path := serve(t, newServerHandler())
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+path, http.Header{wsocket.CompressHeader: {"deflate"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	call := func(id uint64, s string) (byte, []byte) {
+		t.Helper()
+		arg, _ := ag.Encoder(s)
+		body, _ := json.Marshal(&message.JsonCallObject{Method: "echo.Say", Args: [][]byte{arg}})
+		payload, _ := fn.Encode(actions.ACTION_CALL, id, body)
+		slices, err := frame.Split(fmt.Sprintf("c%d", id), payload, 1024, frame.BinaryMessage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range slices {
+			if err := conn.WriteMessage(websocket.BinaryMessage, s.Encode()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		reasm := frame.NewReassembler()
+		for {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := frame.Decode(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.P&frame.WideSlice != 0 {
+				t.Fatal("WideSlice frame sent to legacy peer")
+			}
+			if s.P&frame.Compressed != 0 {
+				t.Fatal("Compressed frame sent to legacy peer")
+			}
+			m, err := reasm.Push(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m == nil {
+				continue
+			}
+			action, _ := fn.Action(m)
+			if fn.Id(m) != id {
+				t.Fatalf("reply id = %d, want %d", fn.Id(m), id)
+			}
+			return action, fn.Data(m)
+		}
+	}
+
+	action, data := call(1, strings.Repeat("x", 300*1024))
+	if action != actions.ACTION_REPLY_ERROR || !strings.Contains(string(data), frame.ErrTooManySlices.Error()) {
+		t.Fatalf("big reply = %d %.64q", action, data)
+	}
+	if action, data = call(2, "hi"); action != actions.ACTION_REPLY_SUCCESS || !strings.Contains(string(data), "echo:hi") {
+		t.Fatalf("small reply = %d %q", action, data)
+	}
+}
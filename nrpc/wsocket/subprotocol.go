@@ -0,0 +1,89 @@
+package wsocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// sloth 线上格式注册的 WebSocket 子协议（Sec-WebSocket-Protocol），网关/负载均衡可按此路由
+const (
+	// SubprotocolText 只用 JSON 文本分片
+	SubprotocolText = "sloth.v3.text"
+	// SubprotocolBin 可用 frame.Encode 二进制分片（也能接收文本分片）
+	SubprotocolBin = "sloth.v3.bin"
+)
+
+var ErrUnsupportedSubprotocol = errors.New("wsocket: unsupported subprotocol")
+
+type subprotocol_key struct{}
+
+// Subprotocol 升级时选定的子协议，r 为 OnConnect/OnReady 中拿到的请求；旧版客户端未声明子协议时为空
+func Subprotocol(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	p, _ := r.Context().Value(subprotocol_key{}).(string)
+	return p
+}
+
+// ResponseSubprotocol 服务端选定的子协议，resp 为客户端 OnConnect 中拿到的握手响应；旧版服务端为空
+func ResponseSubprotocol(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	return resp.Header.Get("Sec-WebSocket-Protocol")
+}
+
+// subprotocols 本端支持的子协议，按偏好排序
+func subprotocols(textFrames bool) []string {
+	if textFrames {
+		return []string{SubprotocolText, SubprotocolBin}
+	}
+	return []string{SubprotocolBin, SubprotocolText}
+}
+
+// clientSubprotocols 客户端声明的子协议：只发文本分片时只声明 SubprotocolText
+func clientSubprotocols(textFrames bool) []string {
+	if textFrames {
+		return []string{SubprotocolText}
+	}
+	return subprotocols(false)
+}
+
+// selectSubprotocol 按服务端偏好选出客户端声明的子协议；客户端未声明时返回空（旧版客户端），
+// required 时拒绝；只声明了不支持的子协议时返回 ErrUnsupportedSubprotocol
+func selectSubprotocol(r *http.Request, textFrames, required bool) (string, error) {
+	offered := websocket.Subprotocols(r)
+	supported := subprotocols(textFrames)
+	if len(offered) == 0 {
+		if required {
+			return "", fmt.Errorf("%w: none offered, want one of %s", ErrUnsupportedSubprotocol, strings.Join(supported, ", "))
+		}
+		return "", nil
+	}
+	for _, p := range supported {
+		if slices.Contains(offered, p) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s, want one of %s", ErrUnsupportedSubprotocol, strings.Join(offered, ", "), strings.Join(supported, ", "))
+}
+
+// withSubprotocol 把选定的子协议带进请求，OnConnect 之后都能用 Subprotocol 取到
+func withSubprotocol(r *http.Request, p string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), subprotocol_key{}, p))
+}
+
+// checkSubprotocol 客户端校验服务端选定的子协议在自己声明的列表中
+func checkSubprotocol(offered []string, selected string) error {
+	if selected == "" || slices.Contains(offered, selected) {
+		return nil
+	}
+	return fmt.Errorf("%w: server selected %s", ErrUnsupportedSubprotocol, selected)
+}
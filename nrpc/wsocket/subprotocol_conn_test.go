@@ -0,0 +1,84 @@
+package wsocket_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/w6xian/sloth/v3"
+	"github.com/w6xian/sloth/v3/nrpc/wsocket"
+	"github.com/w6xian/sloth/v3/option"
+)
+
+func TestSubprotocolNegotiated(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []sloth.ConnOption
+		want string
+	}{
+		{name: "bin", want: wsocket.SubprotocolBin},
+		{name: "text", opts: []sloth.ConnOption{sloth.WithTextFrames(true)}, want: wsocket.SubprotocolText},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sh := newServerHandler()
+			addr, path := serve(t, sh)
+			ch := newClientHandler()
+			dial(t, addr, path, ch, tc.opts...)
+			if got := sloth.Subprotocol(recv(t, sh.connect)); got != tc.want {
+				t.Errorf("server OnConnect subprotocol = %q, want %q", got, tc.want)
+			}
+			if got := sloth.Subprotocol(recv(t, ch.connect)); got != tc.want {
+				t.Errorf("client OnConnect subprotocol = %q, want %q", got, tc.want)
+			}
+			if got := sloth.Subprotocol(recv(t, sh.ready)); got != tc.want {
+				t.Errorf("server channel subprotocol = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSubprotocolRequired(t *testing.T) {
+	sh := newServerHandler()
+	addr, path := serve(t, sh, sloth.WithSubprotocolRequired(true))
+	_, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+path, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("dial without subprotocol: resp %v, err %v", resp, err)
+	}
+	dialer := websocket.Dialer{Subprotocols: []string{"chat"}}
+	if _, resp, err = dialer.Dial("ws://"+addr+path, nil); err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("dial with unsupported subprotocol: resp %v, err %v", resp, err)
+	}
+	select {
+	case <-sh.connect:
+		t.Fatal("OnConnect called for a rejected upgrade")
+	default:
+	}
+}
+
+// 服务端选了客户端没有声明的子协议，客户端断开
+func TestSubprotocolClientMismatch(t *testing.T) {
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, http.Header{"Sec-Websocket-Protocol": {"chat"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer hs.Close()
+	conn := sloth.ClientConn(sloth.DefaultClient())
+	cli := wsocket.NewLocalClient(conn,
+		option.WithAddress(strings.Replace(hs.URL, "http://", "ws://", 1)),
+		option.WithUriPath("/ws"),
+	)
+	if err := cli.ListenAndServe(t.Context()); !errors.Is(err, wsocket.ErrUnsupportedSubprotocol) {
+		t.Fatalf("err = %v, want ErrUnsupportedSubprotocol", err)
+	}
+}
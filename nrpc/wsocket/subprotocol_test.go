@@ -0,0 +1,63 @@
+package wsocket
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSelectSubprotocol(t *testing.T) {
+	cases := []struct {
+		name       string
+		offered    string
+		textFrames bool
+		required   bool
+		want       string
+		wantErr    bool
+	}{
+		{name: "legacy", offered: ""},
+		{name: "legacy-required", offered: "", required: true, wantErr: true},
+		{name: "bin-preferred", offered: "sloth.v3.text, sloth.v3.bin", want: SubprotocolBin},
+		{name: "text-frames", offered: "sloth.v3.bin, sloth.v3.text", textFrames: true, want: SubprotocolText},
+		{name: "text-only", offered: "sloth.v3.text", want: SubprotocolText},
+		{name: "bin-only-text-server", offered: "sloth.v3.bin", textFrames: true, want: SubprotocolBin},
+		{name: "unknown-mixed", offered: "chat, sloth.v3.bin", required: true, want: SubprotocolBin},
+		{name: "unsupported", offered: "chat, sloth.v2", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ws", nil)
+			if c.offered != "" {
+				r.Header.Set("Sec-WebSocket-Protocol", c.offered)
+			}
+			got, err := selectSubprotocol(r, c.textFrames, c.required)
+			if c.wantErr {
+				if !errors.Is(err, ErrUnsupportedSubprotocol) {
+					t.Fatalf("err = %v, want ErrUnsupportedSubprotocol", err)
+				}
+				return
+			}
+			if err != nil || got != c.want {
+				t.Fatalf("got %q, %v, want %q", got, err, c.want)
+			}
+			if p := Subprotocol(withSubprotocol(r, got)); p != got {
+				t.Fatalf("Subprotocol(r) = %q, want %q", p, got)
+			}
+		})
+	}
+}
+
+func TestCheckSubprotocol(t *testing.T) {
+	offered := clientSubprotocols(false)
+	for _, selected := range []string{"", SubprotocolBin, SubprotocolText} {
+		if err := checkSubprotocol(offered, selected); err != nil {
+			t.Errorf("checkSubprotocol(%q) = %v", selected, err)
+		}
+	}
+	if err := checkSubprotocol(clientSubprotocols(true), SubprotocolBin); !errors.Is(err, ErrUnsupportedSubprotocol) {
+		t.Errorf("text client, bin selected: err = %v", err)
+	}
+	if err := checkSubprotocol(offered, "chat"); !errors.Is(err, ErrUnsupportedSubprotocol) {
+		t.Errorf("unknown selected: err = %v", err)
+	}
+}
@@ -1,13 +1,22 @@
 package wsocket
 
 import (
+	"compress/flate"
 	"context"
+	"crypto/ecdh"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"net/http"
 	"sync/atomic"
 
+	"github.com/w6xian/sloth/v3/actions"
 	"github.com/w6xian/sloth/v3/bucket"
+	"github.com/w6xian/sloth/v3/decoder/fn"
 	"github.com/w6xian/sloth/v3/decoder/frame"
+	"github.com/w6xian/sloth/v3/decoder/seal"
 	"github.com/w6xian/sloth/v3/internal/tools"
+	"github.com/w6xian/sloth/v3/message"
 	"github.com/w6xian/sloth/v3/types/handler"
 
 	"github.com/gorilla/websocket"
@@ -27,20 +36,127 @@ func GetBucket(ctx context.Context, buckets []*bucket.Bucket, id int64) *bucket.
 	return buckets[int64(idx)]
 }
 
-var ids int32 = 0
+// sliceNameChars 片名字符：2 字节片名可用 62*62 个，文本与二进制帧都能原样传输
+const sliceNameChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 
-func getSliceName() string {
-	atomic.AddInt32(&ids, 1)
-	if ids > 99 {
-		atomic.StoreInt32(&ids, 0)
+// sliceNames 连接的片名计数器，片名只需在同一连接内不重名
+type sliceNames struct {
+	n atomic.Uint32
+}
+
+// next 下一个片名，同一连接上同时在途的消息不超过 3844 条即不会重名
+func (s *sliceNames) next() string {
+	n := (s.n.Add(1) - 1) % uint32(len(sliceNameChars)*len(sliceNameChars))
+	return string([]byte{sliceNameChars[n/uint32(len(sliceNameChars))], sliceNameChars[n%uint32(len(sliceNameChars))]})
+}
+
+// CompressHeader 握手头：双方都带上表示能解压 frame.Compressed 分片；
+// 还要在 hello 中协商 compress 特性才会真正发送压缩分片
+const CompressHeader = "Sloth-Compress"
+
+const compressDeflate = "deflate"
+
+// newCompressor 对端声明支持压缩时返回连接的压缩器，否则返回 nil
+func newCompressor(threshold int, peer http.Header) *frame.Compressor {
+	if threshold <= 0 || peer.Get(CompressHeader) != compressDeflate {
+		return nil
+	}
+	return frame.NewCompressor(threshold, flate.BestSpeed)
+}
+
+// KeyHeader 握手头：本端 X25519 公钥（base64url，无填充），双方都带上时 fn 帧体加密
+const KeyHeader = "Sloth-Key"
+
+// KeyConfirmHeader 服务端握手响应头：会话密钥确认值（base64url），客户端核对后才启用会话
+const KeyConfirmHeader = "Sloth-Key-Confirm"
+
+var ErrEncryptRequired = errors.New("wsocket: peer does not support encryption")
+
+// encodeKey 握手头中的公钥
+func encodeKey(priv *ecdh.PrivateKey) string {
+	return base64.RawURLEncoding.EncodeToString(priv.PublicKey().Bytes())
+}
+
+// newSession 由本端私钥与对端握手头中的公钥导出会话，psk 见 option.Options.EncryptKey
+func newSession(priv *ecdh.PrivateKey, peer string, client bool, psk []byte) (*seal.Session, error) {
+	b, err := base64.RawURLEncoding.DecodeString(peer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", seal.ErrBadKey, err)
+	}
+	return seal.NewSession(priv, b, client, psk)
+}
+
+// encodeConfirm 握手头中的密钥确认值
+func encodeConfirm(sess *seal.Session) string {
+	return base64.RawURLEncoding.EncodeToString(sess.Confirm())
+}
+
+// verifyConfirm 核对服务端握手头中的密钥确认值，缺失视同不一致
+func verifyConfirm(sess *seal.Session, confirm string) error {
+	b, err := base64.RawURLEncoding.DecodeString(confirm)
+	if err != nil || len(b) == 0 {
+		return seal.ErrKeyMismatch
+	}
+	return sess.Verify(b)
+}
+
+// sealFrame 加密会话下 fn 帧换成密文，其他消息原样返回
+func sealFrame(sess *seal.Session, payload []byte) ([]byte, error) {
+	if sess == nil || !fn.IsFn(payload) {
+		return payload, nil
+	}
+	return sess.Seal(payload)
+}
+
+// openFrame 加密会话下解密 fn 帧；未加密、被篡改或重放的帧返回错误
+func openFrame(sess *seal.Session, m []byte) ([]byte, error) {
+	if sess == nil {
+		return m, nil
+	}
+	return sess.Open(m)
+}
+
+// slicesSend 以片名 name 分片发送：binary 为 true 时发送 frame.Encode 二进制分片，否则为 JSON 文本分片
+// （D 经 base64 编码，体积约增加 1/3）；comp 不为 nil 且对端协商了 compress 时超过阈值的消息先压缩。
+// 对端没有协商 wide_slice（旧版对端，或协商完成前 caps 为 nil）时最多 255 片，超出返回 frame.ErrTooManySlices
+func slicesSend(name string, conn *websocket.Conn, binary bool, caps *message.Capabilities, comp *frame.Compressor, data []byte, sliceSize int) error {
+	var opts []frame.FrameOption
+	if !caps.Has(message.FeatureWideSlice) {
+		opts = append(opts, frame.NarrowSlices())
+	}
+	if comp != nil && caps.Has(message.FeatureCompress) {
+		var ok bool
+		if data, ok = comp.Compress(data); ok {
+			opts = append(opts, frame.CompressedSlices())
+		}
+		// 已压缩的消息不再经 permessage-deflate 压一遍
+		conn.EnableWriteCompression(!ok)
+	}
+	if binary {
+		return slicesBinarySend(name, conn, data, sliceSize, opts...)
+	}
+	return slicesTextSend(name, conn, data, sliceSize, opts...)
+}
+
+// errorReply 与 fn 帧 payload 同 ID 的错误回复。分片数超出对端能力的帧发不出去时用它代替，
+// 双方拿到 frame.ErrTooManySlices，而不是对端的解析错误或等到超时
+func errorReply(payload []byte, err error) []byte {
+	b, _ := fn.Encode(actions.ACTION_REPLY_ERROR, fn.Id(payload), []byte(err.Error()))
+	return b
+}
+
+// failCall 发不出去的调用以错误结果交给本端等待中的 SendData
+func failCall(result chan []byte, payload []byte, err error) {
+	select {
+	case result <- errorReply(payload, err):
+	default:
 	}
-	return fmt.Sprintf("%02d", ids)
 }
 
 // 分块发送数据
-func slicesTextSend(n string, conn *websocket.Conn, data []byte, sliceSize int) error {
+func slicesTextSend(n string, conn *websocket.Conn, data []byte, sliceSize int, opts ...frame.FrameOption) error {
 	// data 按大小分成多个块发送
-	slices, err := frame.Split(n, data, sliceSize, frame.TextMessage)
+	slices, err := frame.Split(n, data, sliceSize, frame.TextMessage, opts...)
 	if err != nil {
 		return err
 	}
@@ -58,9 +174,9 @@ func slicesTextSend(n string, conn *websocket.Conn, data []byte, sliceSize int)
 }
 
 // 分块发送数据
-func slicesBinarySend(n string, conn *websocket.Conn, data []byte, sliceSize int) error {
+func slicesBinarySend(n string, conn *websocket.Conn, data []byte, sliceSize int, opts ...frame.FrameOption) error {
 	// data 按大小分成多个块发送
-	slices, err := frame.Split(n, data, sliceSize, frame.BinaryMessage)
+	slices, err := frame.Split(n, data, sliceSize, frame.BinaryMessage, opts...)
 	if err != nil {
 		return err
 	}
@@ -86,43 +202,12 @@ func dealData(ctx context.Context, m []byte, handler handler.IServerHandleMessag
 	return nil
 }
 
-func receiveMessage(conn IReadConn, messageType byte, message []byte) ([]byte, error) {
+// receiveMessage 把一个分片交给连接的重组表，消息收齐时返回完整数据，否则返回 nil；
+// 不同片名的分片可以交错到达
+func receiveMessage(ra *frame.Reassembler, messageType byte, message []byte) ([]byte, error) {
 	sc, err := frame.FromType(message, messageType)
 	if err != nil {
 		return nil, err
 	}
-	id := sc.N
-	dataSize := sc.S
-	// 接收完整数据
-	data := make([]byte, 0, dataSize)
-	data = append(data, sc.D...)
-	if int(dataSize) <= len(data) && sc.I == sc.T-1 {
-		return data, nil
-	}
-
-	for {
-		msgType, message, err := conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				return nil, err
-			}
-		}
-		if message == nil || msgType == CloseMessage || msgType == PingMessage || msgType == PongMessage {
-			return nil, fmt.Errorf("message is nil or msgType is close or ping or pong message")
-		}
-
-		slices, err := frame.FromType(message, byte(msgType))
-		if err != nil {
-			return nil, err
-		}
-
-		if id != slices.N {
-			return nil, fmt.Errorf("id not match")
-		}
-		data = append(data, slices.D...)
-		// realSize := utf8.RuneCountInString(string(data))
-		if int(dataSize) <= len(data) && slices.I == slices.T-1 {
-			return data, nil
-		}
-	}
+	return ra.Push(sc)
 }
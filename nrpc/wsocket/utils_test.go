@@ -0,0 +1,26 @@
+package wsocket
+
+import "testing"
+
+func TestSliceNames(t *testing.T) {
+	var a, b sliceNames
+	if n := a.next(); n != "00" {
+		t.Fatalf("first = %q", n)
+	}
+	// 每个连接各自计数
+	if n := b.next(); n != "00" {
+		t.Fatalf("other channel first = %q", n)
+	}
+	seen := map[string]bool{"00": true}
+	total := len(sliceNameChars) * len(sliceNameChars)
+	for i := 1; i < total; i++ {
+		n := a.next()
+		if len(n) != 2 || seen[n] {
+			t.Fatalf("name %d = %q", i, n)
+		}
+		seen[n] = true
+	}
+	if n := a.next(); n != "00" {
+		t.Fatalf("after %d names = %q, want wrap to 00", total, n)
+	}
+}
@@ -2,6 +2,7 @@ package wsocket
 
 import (
 	"context"
+	"crypto/ecdh"
 	"encoding/json"
 	"errors"
 	"log"
@@ -14,6 +15,8 @@ import (
 	"github.com/w6xian/sloth/v3/actions"
 	"github.com/w6xian/sloth/v3/bucket"
 	"github.com/w6xian/sloth/v3/decoder/fn"
+	"github.com/w6xian/sloth/v3/decoder/frame"
+	"github.com/w6xian/sloth/v3/decoder/seal"
 	"github.com/w6xian/sloth/v3/internal/logger"
 	"github.com/w6xian/sloth/v3/internal/utils"
 	"github.com/w6xian/sloth/v3/internal/utils/id"
@@ -47,6 +50,17 @@ type LocalClient struct {
 	WriteBufferSize int
 	BroadcastSize   int
 	KeepAlive       bool
+	TextFrames      bool
+	// CompressThreshold 压缩阈值，<=0 不压缩
+	CompressThreshold int
+	WsCompression     bool
+	// Encrypt/EncryptRequired 见 option.Options
+	Encrypt         bool
+	EncryptRequired bool
+	EncryptKey      []byte
+	// key 本次握手的 X25519 私钥
+	key     *ecdh.PrivateKey
+	version string
 
 	defaultHeader message.Header
 	header        map[string]string
@@ -100,6 +114,13 @@ func NewLocalClient(connect trpc.ICallRpc, options ...option.ConnectOption) *Loc
 	s.BroadcastSize = opt.BroadcastSize
 	s.SliceSize = opt.SliceSize
 	s.KeepAlive = opt.KeepAlive
+	s.TextFrames = opt.TextFrames
+	s.CompressThreshold = opt.CompressThreshold
+	s.WsCompression = opt.WsCompression
+	s.Encrypt = opt.Encrypt || opt.EncryptRequired
+	s.EncryptRequired = opt.EncryptRequired
+	s.EncryptKey = opt.EncryptKey
+	s.version = opt.Version
 	s.header = make(map[string]string)
 	s.handler = nil
 
@@ -141,8 +162,28 @@ func (c *LocalClient) ListenAndServe(ctx context.Context) error {
 		for k, v := range c.header {
 			header[k] = []string{v}
 		}
+		header.Set(CompressHeader, compressDeflate)
+		// 每次握手（含重连）使用新的密钥
+		c.key = nil
+		if c.Encrypt {
+			if c.key, err = seal.GenerateKey(); err != nil {
+				return err
+			}
+			header.Set(KeyHeader, encodeKey(c.key))
+		}
 
-		conn, resp, err := websocket.DefaultDialer.Dial(addr, header)
+		dialer := *websocket.DefaultDialer
+		dialer.EnableCompression = c.WsCompression
+		if header.Get("Sec-WebSocket-Protocol") == "" {
+			dialer.Subprotocols = clientSubprotocols(c.TextFrames)
+		}
+		conn, resp, err := dialer.Dial(addr, header)
+		if err == nil && len(dialer.Subprotocols) > 0 {
+			if err = checkSubprotocol(dialer.Subprotocols, conn.Subprotocol()); err != nil {
+				conn.Close()
+				return err
+			}
+		}
 		if err != nil && c.KeepAlive {
 			// 1-30 秒重试
 			retry := utils.RandInt64(1, 30)
@@ -169,6 +210,33 @@ func (c *LocalClient) ListenAndServe(ctx context.Context) error {
 	return nil
 }
 
+// handshake 服务端回了公钥时建立加密会话；要求加密而服务端不支持时返回 ErrEncryptRequired，
+// 密钥确认值对不上（PSK 不一致或公钥被替换）时返回 seal.ErrKeyMismatch
+func (c *LocalClient) handshake(ch *WsChannelClient, resp *http.Response) error {
+	if c.key == nil {
+		return nil
+	}
+	peer := ""
+	if resp != nil {
+		peer = resp.Header.Get(KeyHeader)
+	}
+	if peer == "" {
+		if c.EncryptRequired {
+			return ErrEncryptRequired
+		}
+		return nil
+	}
+	sess, err := newSession(c.key, peer, true, c.EncryptKey)
+	if err != nil {
+		return err
+	}
+	if err := verifyConfirm(sess, resp.Header.Get(KeyConfirmHeader)); err != nil {
+		return err
+	}
+	ch.sess = sess
+	return nil
+}
+
 func (c *LocalClient) SetAuthInfo(auth *auth.AuthInfo) error {
 	if auth == nil {
 		return errors.New("auth is nil")
@@ -198,11 +266,37 @@ func (c *LocalClient) ClientWs(ctx context.Context, conn *websocket.Conn, resp *
 	// 链接session
 	closeChan := make(chan struct{}, 1)
 	// 全局client websocket连接
-	wsConn := NewWsChannelClient(c.Connect)
+	wsConn := NewWsChannelClient(c.Connect, WithTextFrames(c.TextFrames))
+	if err := c.handshake(wsConn, resp); err != nil {
+		c.log(logger.Error, "handshake err = %v", err)
+		if c.handler != nil {
+			c.handler.OnError(ctx, resp, c, wsConn, err)
+		}
+		conn.Close()
+		return
+	}
 	c.client = wsConn
 	//default broadcast size eq 512
 	wsConn.conn = conn
 	wsConn.RoomId = 0
+	wsConn.proto = conn.Subprotocol()
+	wsConn.text = c.TextFrames || wsConn.proto == SubprotocolText
+	if wsConn.text {
+		wsConn.binary.Store(false)
+	}
+	if resp != nil {
+		wsConn.comp = newCompressor(c.CompressThreshold, resp.Header)
+	}
+	// 服务端选定了 sloth 子协议才发 Hello（旧版服务端不认识），写协程启动前发送，保证它是本连接的第一条消息
+	if wsConn.proto != "" {
+		hello, err := sealFrame(wsConn.sess, helloFrame(c.localHello(wsConn)))
+		if err == nil {
+			err = slicesSend(wsConn.names.next(), conn, wsConn.binary.Load(), wsConn.caps.Load(), wsConn.comp, hello, int(c.SliceSize))
+		}
+		if err != nil {
+			c.log(logger.Error, "send hello err = %v", err)
+		}
+	}
 	parentCtx := ctx
 	ctx, cancel := context.WithCancel(ctx)
 	//get data from websocket conn
@@ -218,6 +312,14 @@ func (c *LocalClient) ClientWs(ctx context.Context, conn *websocket.Conn, resp *
 	}
 }
 
+// Capabilities 当前连接与服务端协商出的能力，未连接或协商完成前为 nil
+func (c *LocalClient) Capabilities() *message.Capabilities {
+	if ch, ok := c.client.(interface{ Capabilities() *message.Capabilities }); ok {
+		return ch.Capabilities()
+	}
+	return nil
+}
+
 func (c *LocalClient) DefaultHeader() message.Header {
 	return c.defaultHeader
 }
@@ -304,8 +406,17 @@ func (c *LocalClient) writePump(ctx context.Context, ch *WsChannelClient, closeC
 				ch.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := slicesTextSend(getSliceName(), ch.conn, msg.Body, sliceSize); err != nil {
-				return
+			// 只有 fn 帧加密，Push 的其他消息以明文发送（见 sloth.WithEncryption）
+			body, err := sealFrame(ch.sess, msg.Body)
+			if err != nil {
+				c.log(logger.Error, "sealFrame err = %v", err)
+				continue
+			}
+			if err := slicesSend(ch.names.next(), ch.conn, ch.binary.Load(), ch.caps.Load(), ch.comp, body, sliceSize); err != nil {
+				if !errors.Is(err, frame.ErrTooManySlices) {
+					return
+				}
+				c.log(logger.Error, "send dropped: %v", err)
 			}
 		case payload, ok := <-ch.rpcCaller:
 			/*
@@ -322,9 +433,18 @@ func (c *LocalClient) writePump(ctx context.Context, ch *WsChannelClient, closeC
 				ch.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := slicesTextSend(getSliceName(), ch.conn, payload, sliceSize); err != nil {
-				c.log(logger.Error, "slicesBinarySend err = %v", err.Error())
-				return
+			body, err := sealFrame(ch.sess, payload)
+			if err != nil {
+				c.log(logger.Error, "sealFrame err = %v", err)
+				continue
+			}
+			if err := slicesSend(ch.names.next(), ch.conn, ch.binary.Load(), ch.caps.Load(), ch.comp, body, sliceSize); err != nil {
+				c.log(logger.Error, "slicesSend err = %v", err.Error())
+				if !errors.Is(err, frame.ErrTooManySlices) {
+					return
+				}
+				// 调用超出对端能力发不出去，以错误结果交给等待中的 SendData
+				failCall(ch.rpcResult, payload, err)
 			}
 		case payload, ok := <-ch.rpcBacker:
 			/*
@@ -342,7 +462,20 @@ func (c *LocalClient) writePump(ctx context.Context, ch *WsChannelClient, closeC
 				return
 			}
 
-			if err := slicesTextSend(getSliceName(), ch.conn, payload, sliceSize); err != nil {
+			body, err := sealFrame(ch.sess, payload)
+			if err != nil {
+				c.log(logger.Error, "sealFrame err = %v", err)
+				continue
+			}
+			err = slicesSend(ch.names.next(), ch.conn, ch.binary.Load(), ch.caps.Load(), ch.comp, body, sliceSize)
+			if errors.Is(err, frame.ErrTooManySlices) && fn.IsFn(payload) {
+				// 回复超出对端能力，改发同 ID 的错误回复
+				c.log(logger.Error, "reply %d: %v", fn.Id(payload), err)
+				if body, err = sealFrame(ch.sess, errorReply(payload, err)); err == nil {
+					err = slicesSend(ch.names.next(), ch.conn, ch.binary.Load(), ch.caps.Load(), ch.comp, body, sliceSize)
+				}
+			}
+			if err != nil && !errors.Is(err, frame.ErrTooManySlices) {
 				return
 			}
 
@@ -386,9 +519,13 @@ func (c *LocalClient) readPump(ctx context.Context, ch *WsChannelClient, closeCh
 		ch.conn.SetReadDeadline(time.Now().Add(c.PongWait))
 		return nil
 	})
-	// 要防止OnOpen阻塞，导致readPump阻塞
-	if c.handler != nil {
-		go c.handler.OnReady(ctx, resp, c, ch)
+	// OnReady 在收到服务端 Hello 后触发（超时按旧版处理）；服务端没有选定 sloth 子协议的是旧版，
+	// 不会回 Hello，直接按旧版触发 OnReady
+	if ch.proto == "" {
+		c.settle(ctx, resp, ch, nil)
+	} else {
+		timer := time.AfterFunc(helloTimeout, func() { c.settle(ctx, resp, ch, nil) })
+		defer timer.Stop()
 	}
 	for {
 		// 主动关闭
@@ -419,19 +556,34 @@ func (c *LocalClient) readPump(ctx context.Context, ch *WsChannelClient, closeCh
 			continue
 		}
 		// 消息体可能太大，需要分片接收后再解析
-		// 实现分片接收的函数
-		m, err := receiveMessage(ch.conn, byte(messageType), msg)
+		// 不同消息的分片可能交错到达，按片名重组，未收齐时继续读
+		m, err := receiveMessage(ch.reasm, byte(messageType), msg)
 		if err != nil {
 			if c.handler != nil {
 				c.handler.OnError(ctx, resp, c, ch, err)
 			}
 			continue
 		}
+		if m == nil {
+			continue
+		}
 		tlvFrame, err := tlv.Deserialize(m)
 		if err == nil {
 			m = tlvFrame.Value()
 		}
-		if _, err := fn.Action(m); err == nil {
+		if action, err := fn.Action(m); err == nil {
+			if m, err = openFrame(ch.sess, m); err != nil {
+				if c.handler != nil {
+					c.handler.OnError(ctx, resp, c, ch, err)
+				}
+				continue
+			}
+			if action == actions.ACTION_HELLO {
+				if err := c.onHello(ctx, resp, ch, m); err != nil && c.handler != nil {
+					c.handler.OnError(ctx, resp, c, ch, err)
+				}
+				continue
+			}
 			if err := c.HandleFn(ctx, ch, m); err != nil {
 				if c.handler != nil {
 					c.handler.OnError(ctx, resp, c, ch, err)
@@ -445,6 +597,33 @@ func (c *LocalClient) readPump(ctx context.Context, ch *WsChannelClient, closeCh
 	}
 }
 
+func (c *LocalClient) localHello(ch *WsChannelClient) *message.Hello {
+	return localHello(c.version, c.MaxMessageSize, ch.text, ch.sess != nil)
+}
+
+func (c *LocalClient) onHello(ctx context.Context, resp *http.Response, ch *WsChannelClient, data []byte) error {
+	peer, err := message.ParseHello(fn.Data(data))
+	if err != nil {
+		return err
+	}
+	c.settle(ctx, resp, ch, peer)
+	return nil
+}
+
+// settle 确定连接能力并触发 OnReady，每个连接只执行一次；peer 为 nil 表示旧版服务端（仍按二进制分片发送）
+func (c *LocalClient) settle(ctx context.Context, resp *http.Response, ch *WsChannelClient, peer *message.Hello) {
+	ch.helloOnce.Do(func() {
+		caps := message.Negotiate(c.localHello(ch), peer)
+		ch.caps.Store(caps)
+		if peer != nil && !caps.Has(message.FeatureBinary) {
+			ch.binary.Store(false)
+		}
+		if c.handler != nil {
+			go c.handler.OnReady(ctx, resp, c, ch)
+		}
+	})
+}
+
 func (c *LocalClient) HandleFn(ctx context.Context, ch *WsChannelClient, data []byte) error {
 	action, err := fn.Action(data)
 	if err != nil {
@@ -506,6 +685,10 @@ func (c *LocalClient) Room(roomId int64) *bucket.Room {
 	return nil
 }
 
+func (c *LocalClient) ServerRoom(roomId int64) *bucket.ServerRoom {
+	return nil
+}
+
 func (c *LocalClient) Broadcast(ctx context.Context, msg *message.Msg) (err error) {
 	return nil
 }
@@ -3,6 +3,7 @@ package wsocket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -14,6 +15,8 @@ import (
 	"github.com/w6xian/sloth/v3/actions"
 	"github.com/w6xian/sloth/v3/bucket"
 	"github.com/w6xian/sloth/v3/decoder/fn"
+	"github.com/w6xian/sloth/v3/decoder/frame"
+	"github.com/w6xian/sloth/v3/decoder/seal"
 	"github.com/w6xian/sloth/v3/internal/logger"
 	"github.com/w6xian/sloth/v3/internal/tools"
 	"github.com/w6xian/sloth/v3/internal/utils"
@@ -47,8 +50,22 @@ type WsServer struct {
 	WriteBufferSize int
 	BroadcastSize   int
 	SliceSize       int64
+	TextFrames      bool
 	header          map[string]string
 	originDomain    []string
+
+	// CompressThreshold 压缩阈值，<=0 不压缩
+	CompressThreshold int
+	WsCompression     bool
+	// Encrypt/EncryptRequired 见 option.Options
+	Encrypt         bool
+	EncryptRequired bool
+	EncryptKey      []byte
+	version         string
+	// SubprotocolRequired 见 option.Options
+	SubprotocolRequired bool
+	// rooms 跨 bucket 的房间注册表
+	rooms *bucket.Registry
 }
 
 // 实现 options.ConnectOption
@@ -97,6 +114,7 @@ func NewWsServer(server trpc.ICallRpc, opts ...option.ConnectOption) *WsServer {
 			bucket.WithRoomSize(opt.RoomSize),
 			bucket.WithRoutineAmount(opt.RoutineAmount),
 			bucket.WithRoutineSize(opt.RoutineSize),
+			bucket.WithMaxDevices(opt.MaxDevices, opt.DevicePolicy),
 		)
 	}
 	s := &WsServer{
@@ -115,8 +133,17 @@ func NewWsServer(server trpc.ICallRpc, opts ...option.ConnectOption) *WsServer {
 		WriteBufferSize: opt.WriteBufferSize,
 		BroadcastSize:   opt.BroadcastSize,
 		SliceSize:       opt.SliceSize,
+		TextFrames:      opt.TextFrames,
 		header:          make(map[string]string),
 	}
+	s.CompressThreshold = opt.CompressThreshold
+	s.WsCompression = opt.WsCompression
+	s.Encrypt = opt.Encrypt || opt.EncryptRequired
+	s.EncryptRequired = opt.EncryptRequired
+	s.EncryptKey = opt.EncryptKey
+	s.version = opt.Version
+	s.SubprotocolRequired = opt.SubprotocolRequired
+	s.rooms = bucket.NewRegistry(bs)
 	for _, opt := range opts {
 		opt(s)
 	}
@@ -139,6 +166,9 @@ func (s *WsServer) Channel(userId int64) bucket.IChannel {
 	return nil
 }
 
+// Room 返回第一个有房间 roomId 的 bucket 中的分片，只含该 bucket 的成员。
+//
+// Deprecated: 请使用 ServerRoom，它汇总所有 bucket 的成员。
 func (s *WsServer) Room(roomId int64) *bucket.Room {
 	for _, b := range s.Buckets {
 		if b == nil {
@@ -150,6 +180,17 @@ func (s *WsServer) Room(roomId int64) *bucket.Room {
 	}
 	return nil
 }
+
+// ServerRoom 房间 roomId 的全服视图（汇总所有 bucket），房间不存在时返回 nil
+func (s *WsServer) ServerRoom(roomId int64) *bucket.ServerRoom {
+	return s.rooms.Room(roomId)
+}
+
+// Rooms 跨 bucket 的房间注册表
+func (s *WsServer) Rooms() *bucket.Registry {
+	return s.rooms
+}
+
 func (s *WsServer) AllBuckets() []*bucket.Bucket {
 	return s.Buckets
 }
@@ -180,6 +221,13 @@ func (s *WsServer) ListenAndServe(ctx context.Context) error {
 		}
 	}()
 	s.router.HandleFunc(s.uriPath, func(w http.ResponseWriter, r *http.Request) {
+		// 升级前选定子协议，OnConnect 中即可用 Subprotocol(r) 取到
+		proto, err := selectSubprotocol(r, s.TextFrames, s.SubprotocolRequired)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r = withSubprotocol(r, proto)
 		if s.handler != nil {
 			if err := s.handler.OnConnect(ctx, r); err != nil {
 				log.Printf("OnConnect err %v", err)
@@ -195,14 +243,37 @@ func (s *WsServer) ListenAndServe(ctx context.Context) error {
 }
 func (s *WsServer) serveWs(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	var upGrader = websocket.Upgrader{
-		ReadBufferSize:  s.ReadBufferSize,
-		WriteBufferSize: s.WriteBufferSize,
+		ReadBufferSize:    s.ReadBufferSize,
+		WriteBufferSize:   s.WriteBufferSize,
+		EnableCompression: s.WsCompression,
+	}
+	if proto := Subprotocol(r); proto != "" {
+		upGrader.Subprotocols = []string{proto}
 	}
 	// 构建header
 	header := make(http.Header)
 	for k, v := range s.header {
 		header[k] = []string{v}
 	}
+	// 服务端总能解压客户端的压缩分片
+	header.Set(CompressHeader, compressDeflate)
+	// 客户端带上公钥时完成密钥交换，升级前失败直接返回 HTTP 错误
+	var sess *seal.Session
+	if peer := r.Header.Get(KeyHeader); s.Encrypt && peer != "" {
+		priv, err := seal.GenerateKey()
+		if err == nil {
+			sess, err = newSession(priv, peer, false, s.EncryptKey)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		header.Set(KeyHeader, encodeKey(priv))
+		header.Set(KeyConfirmHeader, encodeConfirm(sess))
+	} else if s.EncryptRequired {
+		http.Error(w, ErrEncryptRequired.Error(), http.StatusForbidden)
+		return
+	}
 
 	upGrader.CheckOrigin = func(r *http.Request) bool {
 		if r == nil {
@@ -233,6 +304,10 @@ func (s *WsServer) serveWs(ctx context.Context, w http.ResponseWriter, r *http.R
 	ch := NewWsChannelServer(s.Connect)
 	//default broadcast size eq 512
 	ch.Conn = conn
+	ch.comp = newCompressor(s.CompressThreshold, r.Header)
+	ch.sess = sess
+	ch.proto = conn.Subprotocol()
+	ch.text = s.TextFrames || ch.proto == SubprotocolText
 	// 需要确认客户端是否合法，一个是JWT,一个是ClientID
 	go s.readPump(ctx, r, ch)
 	//send data to websocket conn
@@ -269,8 +344,12 @@ func (s *WsServer) writePump(ctx context.Context, r *http.Request, ch *WsChannel
 				ch.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := slicesTextSend(getSliceName(), ch.Conn, utils.Serialize(msg), 512); err != nil {
-				return
+			// 推送不是 fn 帧，加密连接上同样以明文发送（见 sloth.WithEncryption）
+			if err := slicesSend(ch.names.next(), ch.Conn, ch.binary.Load(), ch.caps.Load(), ch.comp, utils.Serialize(msg), 512); err != nil {
+				if !errors.Is(err, frame.ErrTooManySlices) {
+					return
+				}
+				s.log(logger.Error, "broadcast dropped: %v", err)
 			}
 		case payload, ok := <-ch.rpcCaller:
 			if ch.Conn == nil {
@@ -282,8 +361,17 @@ func (s *WsServer) writePump(ctx context.Context, r *http.Request, ch *WsChannel
 				ch.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			if err := slicesTextSend(getSliceName(), ch.Conn, payload, 512); err != nil {
-				return
+			body, err := sealFrame(ch.sess, payload)
+			if err != nil {
+				s.log(logger.Error, "sealFrame err = %v", err)
+				continue
+			}
+			if err := slicesSend(ch.names.next(), ch.Conn, ch.binary.Load(), ch.caps.Load(), ch.comp, body, 512); err != nil {
+				if !errors.Is(err, frame.ErrTooManySlices) {
+					return
+				}
+				// 调用超出对端能力发不出去，以错误结果交给等待中的 SendData
+				failCall(ch.rpcResult, payload, err)
 			}
 		case payload, ok := <-ch.rpcBacker:
 			if ch.Conn == nil {
@@ -296,7 +384,20 @@ func (s *WsServer) writePump(ctx context.Context, r *http.Request, ch *WsChannel
 				return
 			}
 
-			if err := slicesTextSend(getSliceName(), ch.Conn, payload, 512); err != nil {
+			body, err := sealFrame(ch.sess, payload)
+			if err != nil {
+				s.log(logger.Error, "sealFrame err = %v", err)
+				continue
+			}
+			err = slicesSend(ch.names.next(), ch.Conn, ch.binary.Load(), ch.caps.Load(), ch.comp, body, 512)
+			if errors.Is(err, frame.ErrTooManySlices) && fn.IsFn(payload) {
+				// 回复超出对端能力，改发同 ID 的错误回复
+				s.log(logger.Error, "reply %d: %v", fn.Id(payload), err)
+				if body, err = sealFrame(ch.sess, errorReply(payload, err)); err == nil {
+					err = slicesSend(ch.names.next(), ch.Conn, ch.binary.Load(), ch.caps.Load(), ch.comp, body, 512)
+				}
+			}
+			if err != nil && !errors.Is(err, frame.ErrTooManySlices) {
 				return
 			}
 		case <-ticker.C:
@@ -340,9 +441,13 @@ func (s *WsServer) readPump(ctx context.Context, r *http.Request, ch *WsChannelS
 		return nil
 	})
 
-	// OnOpen  可以发送消息了
-	if s.handler != nil {
-		go s.handler.OnReady(ctx, r, s, ch)
+	// 声明了 sloth 子协议的客户端升级后立即发送 Hello，OnReady 在协商完成后触发（超时按旧版处理）；
+	// 没有声明子协议的是旧版客户端，不会发 Hello，直接按旧版触发 OnReady
+	if ch.proto == "" {
+		s.settle(ctx, r, ch, nil)
+	} else {
+		timer := time.AfterFunc(helloTimeout, func() { s.settle(ctx, r, ch, nil) })
+		defer timer.Stop()
 	}
 
 	for {
@@ -365,22 +470,42 @@ func (s *WsServer) readPump(ctx context.Context, r *http.Request, ch *WsChannelS
 			s.log(logger.Info, "server readPump，message is nil or messageType is -1")
 			continue
 		}
+		// 对端发送二进制分片即表示能解析二进制分片，之后的回复改用二进制
+		if messageType == websocket.BinaryMessage && !ch.text {
+			ch.binary.Store(true)
+		}
 		//@call HandleCall 处理调用方法
 		// 消息体可能太大，需要分片接收后再解析
-		// 实现分片接收的函数
-
-		m, err := receiveMessage(ch.Conn, byte(messageType), msg)
+		// 不同消息的分片可能交错到达，按片名重组，未收齐时继续读
+		m, err := receiveMessage(ch.reasm, byte(messageType), msg)
 		if err != nil {
 			if s.handler != nil {
 				s.handler.OnError(ctx, r, s, ch, err)
 			}
 			continue
 		}
+		if m == nil {
+			continue
+		}
 		tlvFrame, err := tlv.Deserialize(m)
 		if err == nil {
 			m = tlvFrame.Value()
 		}
-		if _, err := fn.Action(m); err == nil {
+		if action, err := fn.Action(m); err == nil {
+			if m, err = openFrame(ch.sess, m); err != nil {
+				if s.handler != nil {
+					s.handler.OnError(ctx, r, s, ch, err)
+				}
+				continue
+			}
+			if action == actions.ACTION_HELLO {
+				if err := s.onHello(ctx, r, ch, m); err != nil && s.handler != nil {
+					s.handler.OnError(ctx, r, s, ch, err)
+				}
+				continue
+			}
+			// 第一条消息不是 Hello：旧版客户端
+			s.settle(ctx, r, ch, nil)
 			if err := s.HandleFn(ctx, r, ch, m); err != nil {
 				if s.handler != nil {
 					s.handler.OnError(ctx, r, s, ch, err)
@@ -388,12 +513,43 @@ func (s *WsServer) readPump(ctx context.Context, r *http.Request, ch *WsChannelS
 			}
 			continue
 		}
+		s.settle(ctx, r, ch, nil)
 		if s.handler != nil {
 			s.handler.OnData(ctx, r, s, ch, messageType, m)
 		}
 	}
 }
 
+func (s *WsServer) onHello(ctx context.Context, r *http.Request, ch *WsChannelServer, data []byte) error {
+	peer, err := message.ParseHello(fn.Data(data))
+	if err != nil {
+		return err
+	}
+	s.settle(ctx, r, ch, peer)
+	return nil
+}
+
+// settle 确定连接能力并触发 OnReady，每个连接只执行一次；peer 为 nil 表示旧版客户端。
+// 收到 Hello 时回复服务端的 Hello，双方声明 binary 时直接改用二进制分片
+func (s *WsServer) settle(ctx context.Context, r *http.Request, ch *WsChannelServer, peer *message.Hello) {
+	ch.helloOnce.Do(func() {
+		local := localHello(s.version, s.MaxMessageSize, ch.text, ch.sess != nil)
+		caps := message.Negotiate(local, peer)
+		ch.caps.Store(caps)
+		if peer != nil {
+			if caps.Has(message.FeatureBinary) {
+				ch.binary.Store(true)
+			}
+			if err := ch.Send(helloFrame(local)); err != nil {
+				s.log(logger.Error, "send hello err = %v", err)
+			}
+		}
+		if s.handler != nil {
+			go s.handler.OnReady(ctx, r, s, ch)
+		}
+	})
+}
+
 func (s *WsServer) HandleFn(ctx context.Context, r *http.Request, ch *WsChannelServer, data []byte) error {
 	action, err := fn.Action(data)
 	if err != nil {
@@ -0,0 +1,159 @@
+package wsocket_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/w6xian/sloth/v3"
+	"github.com/w6xian/sloth/v3/bucket"
+	"github.com/w6xian/sloth/v3/option"
+	"github.com/w6xian/sloth/v3/types"
+)
+
+type Echo struct{}
+
+func (e *Echo) Say(ctx context.Context, s string) (string, error) {
+	return "echo:" + s, nil
+}
+
+type serverHandler struct {
+	connect chan *http.Request
+	ready   chan bucket.IChannel
+	errs    chan error
+}
+
+func newServerHandler() *serverHandler {
+	return &serverHandler{
+		connect: make(chan *http.Request, 4),
+		ready:   make(chan bucket.IChannel, 4),
+		errs:    make(chan error, 16),
+	}
+}
+
+func (h *serverHandler) OnConnect(ctx context.Context, r *http.Request) error {
+	h.connect <- r
+	return nil
+}
+func (h *serverHandler) OnReady(ctx context.Context, r *http.Request, s types.IBucket, ch bucket.IChannel) error {
+	h.ready <- ch
+	return nil
+}
+func (h *serverHandler) OnClose(ctx context.Context, r *http.Request, s types.IBucket, ch bucket.IChannel) error {
+	return nil
+}
+func (h *serverHandler) OnData(ctx context.Context, r *http.Request, s types.IBucket, ch bucket.IChannel, msgType int, message []byte) error {
+	return nil
+}
+func (h *serverHandler) OnError(ctx context.Context, r *http.Request, s types.IBucket, ch bucket.IChannel, err error) error {
+	select {
+	case h.errs <- err:
+	default:
+	}
+	return nil
+}
+
+type clientHandler struct {
+	connect chan *http.Response
+	ready   chan types.IConnInfo
+	errs    chan error
+}
+
+func newClientHandler() *clientHandler {
+	return &clientHandler{
+		connect: make(chan *http.Response, 4),
+		ready:   make(chan types.IConnInfo, 4),
+		errs:    make(chan error, 16),
+	}
+}
+
+func (h *clientHandler) OnConnect(ctx context.Context, resp *http.Response) error {
+	h.connect <- resp
+	return nil
+}
+func (h *clientHandler) OnReady(ctx context.Context, resp *http.Response, c types.IConnRpc, ch types.IConnInfo) error {
+	h.ready <- ch
+	return nil
+}
+func (h *clientHandler) OnData(ctx context.Context, resp *http.Response, c types.IConnRpc, ch types.IConnInfo, msgType int, message []byte) error {
+	return nil
+}
+func (h *clientHandler) OnClose(ctx context.Context, resp *http.Response, c types.IConnRpc, ch types.IConnInfo) error {
+	return nil
+}
+func (h *clientHandler) OnError(ctx context.Context, resp *http.Response, c types.IConnRpc, ch types.IConnInfo, err error) error {
+	select {
+	case h.errs <- err:
+	default:
+	}
+	return nil
+}
+
+var paths atomic.Int32
+
+// serve 启动服务端，返回 host:port 与路径；每个测试用独立的路由，不注册到 http.DefaultServeMux
+func serve(t *testing.T, h *serverHandler, opts ...sloth.ConnOption) (string, string) {
+	t.Helper()
+	path := fmt.Sprintf("/ws%d", paths.Add(1))
+	router := mux.NewRouter()
+	srv := sloth.ServerConn(sloth.DefaultServer(), opts...)
+	srv.Register("echo", new(Echo), "")
+	err := srv.Listen(t.Context(), "ws", "127.0.0.1:0",
+		option.WithRouterWithoutHandle(router),
+		option.WithUriPath(path),
+		option.WithOrigin("*"),
+		option.WithServerHandleMessage(h),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.ServeAsync()
+	t.Cleanup(func() { srv.Close() })
+	hs := httptest.NewServer(router)
+	t.Cleanup(hs.Close)
+	// 等路由注册完成
+	for i := 0; ; i++ {
+		resp, err := http.Get(hs.URL + path)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				break
+			}
+		}
+		if i == 100 {
+			t.Fatal("server not ready")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// 探测请求也会触发 OnConnect，丢掉
+	for len(h.connect) > 0 {
+		<-h.connect
+	}
+	return strings.TrimPrefix(hs.URL, "http://"), path
+}
+
+// dial 连接服务端，返回客户端调用入口
+func dial(t *testing.T, addr, path string, h *clientHandler, opts ...sloth.ConnOption) *sloth.ServerRpc {
+	t.Helper()
+	cli := sloth.DefaultClient()
+	conn := sloth.ClientConn(cli, opts...)
+	go conn.Dial(t.Context(), "ws", addr, option.WithUriPath(path), option.WithClientHandleMessage(h))
+	return cli
+}
+
+func recv[T any](t *testing.T, c chan T) T {
+	t.Helper()
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for %T", *new(T))
+	}
+	return *new(T)
+}
@@ -1,6 +1,10 @@
 package option
 
-import "time"
+import (
+	"time"
+
+	"github.com/w6xian/sloth/v3/bucket"
+)
 
 type Options struct {
 	// ReadWait is the duration for which the server allows a client to read a message.
@@ -27,6 +31,10 @@ type Options struct {
 	RoutineAmount uint64
 	// RoutineSize is the size of the buffer used to store messages for each goroutine.
 	RoutineSize int
+	// MaxDevices is the maximum number of concurrent devices per user, <=0 means unlimited.
+	MaxDevices int
+	// DevicePolicy decides what happens when a user exceeds MaxDevices.
+	DevicePolicy bucket.DevicePolicy
 	// SliceSize is the size of the slice used to store messages for each client.
 	SliceSize int64
 	// KeepAlive is the duration for which the server allows a client to keep the connection alive.
@@ -46,6 +54,30 @@ type Options struct {
 
 	TLSCertFile string
 	TLSKeyFile  string
+
+	// StrictArgs 结构体参数拒绝未知 JSON 字段
+	StrictArgs bool
+	// TextFrames 只发送 JSON 文本分片（兼容只认文本分片的旧浏览器端）；
+	// 默认客户端发送二进制分片，服务端在对端发来二进制分片后改用二进制分片回复
+	TextFrames bool
+	// CompressThreshold 超过该字节数的消息以 deflate 压缩后分片发送（对端在握手中声明支持时），<=0 关闭
+	CompressThreshold int
+	// WsCompression 启用 WebSocket permessage-deflate 扩展（浏览器端也支持）
+	WsCompression bool
+	// Encrypt 握手时交换 X25519 公钥，之后 fn 帧体以 AES-GCM 加密（对端不支持时退回明文）。
+	// 只加密 fn 帧（调用与回复），Room/广播推送的 message.Msg 仍是明文
+	Encrypt bool
+	// EncryptRequired 对端不支持加密时拒绝连接，隐含 Encrypt
+	EncryptRequired bool
+	// EncryptKey 双方预共享的密钥，混入密钥派生以认证握手中交换的公钥（防中间人），隐含 Encrypt
+	EncryptKey []byte
+	// SubprotocolRequired 拒绝未声明 sloth 子协议（Sec-WebSocket-Protocol）的客户端
+	SubprotocolRequired bool
+	// NodeId 调用 ID（decoder.NextId）的节点号，范围同 decoder.SetNodeId（0..1023），集群中每个进程不同；
+	// 零值表示未设置，保持进程默认（1），需要节点 0 时直接调用 decoder.SetNodeId(0)
+	NodeId int64
+	// Version 本端 SDK 版本，由 Connect 填写，随 Hello 发给对端
+	Version string
 }
 
 func NewOptions() *Options {
@@ -77,5 +109,6 @@ func NewOptions() *Options {
 		AutoBanTTL:       10 * time.Minute,
 		TLSCertFile: "",
 		TLSKeyFile:  "",
+		CompressThreshold: 4 << 10,
 	}
 }
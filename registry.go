@@ -0,0 +1,210 @@
+package sloth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/w6xian/sloth/v3/internal/logger"
+	"github.com/w6xian/sloth/v3/internal/ref"
+)
+
+// 服务注册表
+//
+// 服务名可以带点号分层（shop.order），调用名的最后一段是方法：shop.order.Create。
+// 同一服务可以并存多个版本，按 name@version 登记，调用方有三种方式选择版本：
+//
+//	client.Call(ctx, "shop.Create", ...)                                  // 默认版本
+//	client.CallWithHeader(ctx, message.Header{"service_version": "v2"}, "shop.Create", ...)
+//	client.Call(ctx, "shop@v2.Create", ...)                               // 直接寻址
+//
+// 直接寻址与 service_version 头同时出现时以直接寻址为准。
+const ServiceVersionHeader = "service_version"
+
+// ServiceOption 注册服务时的可选项
+type ServiceOption func(*serviceOptions)
+
+type serviceOptions struct {
+	version    string
+	deprecated string
+	isDefault  bool
+	replace    bool
+}
+
+// WithVersion 注册为指定版本，与同名服务的其他版本并存
+func WithVersion(version string) ServiceOption {
+	return func(o *serviceOptions) {
+		o.version = version
+	}
+}
+
+// WithDefaultVersion 未指定版本的调用使用该版本；不设置时为同名服务第一个注册的版本
+func WithDefaultVersion() ServiceOption {
+	return func(o *serviceOptions) {
+		o.isDefault = true
+	}
+}
+
+// WithDeprecated 标记为弃用，说明出现在 sys.Describe / sys.Contract 中，首次调用时打印日志
+func WithDeprecated(message string) ServiceOption {
+	return func(o *serviceOptions) {
+		o.deprecated = message
+	}
+}
+
+// WithReplace 已注册同名（同版本）服务时替换，进行中的调用不受影响
+func WithReplace() ServiceOption {
+	return func(o *serviceOptions) {
+		o.replace = true
+	}
+}
+
+// service_key serviceMap 的键：未带版本为 name，否则为 name@version
+func service_key(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+func check_service_name(name, version string) error {
+	if name == "" || strings.Contains(name, "@") || slices.Contains(strings.Split(name, "."), "") {
+		return fmt.Errorf("invalid service name %q", name)
+	}
+	if strings.Contains(version, "@") {
+		return fmt.Errorf("invalid service version %q", version)
+	}
+	return nil
+}
+
+// Register 注册一个服务，name是服务名，rcvr是服务实现，metadata是服务描述
+// @example
+//
+//	conn.Register("shop.order", &OrderV1{}, "订单", sloth.WithVersion("v1"), sloth.WithDeprecated("use v2"))
+//	conn.Register("shop.order", &OrderV2{}, "订单", sloth.WithVersion("v2"), sloth.WithDefaultVersion())
+func (c *Connect) Register(name string, rcvr any, metadata string, opts ...ServiceOption) error {
+	o := &serviceOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := check_service_name(name, o.version); err != nil {
+		return err
+	}
+	key := service_key(name, o.version)
+
+	funcs := ref.Register(rcvr)
+	funcs.D = metadata
+	funcs.S = c.Option.StrictArgs
+	funcs.Version = o.version
+	funcs.Deprecated = o.deprecated
+
+	c.svcMu.Lock()
+	defer c.svcMu.Unlock()
+	if _, ok := c.serviceMap[key]; ok && !o.replace {
+		return fmt.Errorf("service %s already registered", key)
+	}
+	c.serviceMap[key] = funcs
+	if !slices.Contains(c.versions[name], o.version) {
+		c.versions[name] = append(c.versions[name], o.version)
+	}
+	if _, ok := c.defaults[name]; !ok || o.isDefault {
+		c.defaults[name] = o.version
+	}
+	return nil
+}
+
+// Unregister 注销服务的一个版本（未带版本注册的传 ""），进行中的调用不受影响；
+// 注销的是默认版本时，改用最后注册的剩余版本
+func (c *Connect) Unregister(name string, version string) error {
+	if name == SysService {
+		return errors.New("can not unregister built-in service")
+	}
+	key := service_key(name, version)
+	c.svcMu.Lock()
+	defer c.svcMu.Unlock()
+	if _, ok := c.serviceMap[key]; !ok {
+		return fmt.Errorf("service %s not registered", key)
+	}
+	delete(c.serviceMap, key)
+	rest := slices.DeleteFunc(c.versions[name], func(v string) bool { return v == version })
+	if len(rest) == 0 {
+		delete(c.versions, name)
+		delete(c.defaults, name)
+		return nil
+	}
+	c.versions[name] = rest
+	if c.defaults[name] == version {
+		c.defaults[name] = rest[len(rest)-1]
+	}
+	return nil
+}
+
+// lookup 按服务名与版本查找；version 为空时使用默认版本。
+// name 也可以直接是 name@version，此时以它为准，忽略 version（调用名中的寻址优先于 service_version 头）
+func (c *Connect) lookup(name, version string) (*ref.ServiceFuncs, bool) {
+	if base, v, ok := strings.Cut(name, "@"); ok {
+		name, version = base, v
+		if version == "" {
+			return nil, false
+		}
+	}
+	c.svcMu.RLock()
+	defer c.svcMu.RUnlock()
+	if version == "" {
+		if def, ok := c.defaults[name]; ok {
+			version = def
+		}
+	}
+	fns, ok := c.serviceMap[service_key(name, version)]
+	return fns, ok
+}
+
+// registered 注册表中的一项
+type registered struct {
+	Key     string // name 或 name@version，同时是可直接寻址的服务名
+	Name    string
+	Default bool // 未指定版本时使用
+	Funcs   *ref.ServiceFuncs
+}
+
+// services 注册表快照，按 Key 排序
+func (c *Connect) services() []registered {
+	c.svcMu.RLock()
+	defer c.svcMu.RUnlock()
+	out := make([]registered, 0, len(c.serviceMap))
+	for key, fns := range c.serviceMap {
+		name := strings.TrimSuffix(key, "@"+fns.Version)
+		out = append(out, registered{
+			Key:     key,
+			Name:    name,
+			Default: c.defaults[name] == fns.Version,
+			Funcs:   fns,
+		})
+	}
+	slices.SortFunc(out, func(a, b registered) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return out
+}
+
+// service 取单个服务：name@version 精确匹配，或 name 的默认版本
+func (c *Connect) service(name string) (registered, bool) {
+	fns, ok := c.lookup(name, "")
+	if !ok {
+		return registered{}, false
+	}
+	for _, r := range c.services() {
+		if r.Funcs == fns {
+			return r, true
+		}
+	}
+	return registered{}, false
+}
+
+// warn_deprecated 弃用服务首次被调用时打印日志
+func (c *Connect) warn_deprecated(service string, fns *ref.ServiceFuncs) {
+	if _, loaded := c.deprecatedLogged.LoadOrStore(fns, true); !loaded {
+		c.Log(logger.Warning, "[notice]service %s (version %q) is deprecated: %s", service, fns.Version, fns.Deprecated)
+	}
+}
@@ -0,0 +1,146 @@
+package sloth
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/w6xian/sloth/v3/types/trpc"
+)
+
+type versionedSvc struct {
+	v string
+}
+
+// Who 返回处理本次调用的实现与 service_version 头
+func (s *versionedSvc) Who(ctx context.Context) (string, error) {
+	header, _ := GetHeader(ctx)
+	return s.v + "|" + header.Get(ServiceVersionHeader), nil
+}
+
+func call_who(t *testing.T, c *Connect, method string, header map[string]string) (string, error) {
+	t.Helper()
+	out, err := c.CallFunc(context.Background(), nil, nil, &trpc.RpcCaller{Method: method, Header: header})
+	return string(out), err
+}
+
+func TestRegistryRouting(t *testing.T) {
+	c := ServerConn(DefaultServer())
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(c.Register("shop.order", &versionedSvc{v: "order"}, ""))
+	must(c.Register("shop", &versionedSvc{v: "shop-v1"}, "", WithVersion("v1")))
+	must(c.Register("shop", &versionedSvc{v: "shop-v2"}, "", WithVersion("v2"), WithDefaultVersion()))
+	must(c.Register("shop", &versionedSvc{v: "shop-v3"}, "", WithVersion("v3")))
+
+	cases := []struct {
+		method string
+		header map[string]string
+		want   string
+	}{
+		// 点号分层的服务名，最后一段为方法
+		{method: "shop.order.Who", want: "order|"},
+		// 未指定版本用默认版本，头中回写实际处理的版本
+		{method: "shop.Who", want: "shop-v2|v2"},
+		{method: "shop.Who", header: map[string]string{ServiceVersionHeader: "v1"}, want: "shop-v1|v1"},
+		{method: "shop@v3.Who", want: "shop-v3|v3"},
+		// 直接寻址优先于 service_version 头
+		{method: "shop@v1.Who", header: map[string]string{ServiceVersionHeader: "v3"}, want: "shop-v1|v1"},
+	}
+	for _, tc := range cases {
+		got, err := call_who(t, c, tc.method, tc.header)
+		if err != nil || got != tc.want {
+			t.Errorf("%s %v = %q, %v, want %q", tc.method, tc.header, got, err, tc.want)
+		}
+	}
+	for _, method := range []string{"shop@v9.Who", "shop@.Who", "shop.order@v1.Who", "nope.Who"} {
+		if _, err := call_who(t, c, method, nil); err == nil {
+			t.Errorf("%s: err = nil", method)
+		}
+	}
+	if _, err := call_who(t, c, "shop.Who", map[string]string{ServiceVersionHeader: "v9"}); err == nil {
+		t.Error("unknown header version: err = nil")
+	}
+	if !c.IsRegisteredService("shop@v1.Who") || c.IsRegisteredService("shop@v9.Who") {
+		t.Error("IsRegisteredService with @version")
+	}
+
+	// 注销默认版本后改用最后注册的剩余版本；全部注销后找不到服务
+	must(c.Unregister("shop", "v2"))
+	if got, _ := call_who(t, c, "shop.Who", nil); got != "shop-v3|v3" {
+		t.Errorf("after Unregister(v2) = %q", got)
+	}
+	must(c.Unregister("shop", "v3"))
+	if got, _ := call_who(t, c, "shop.Who", nil); got != "shop-v1|v1" {
+		t.Errorf("after Unregister(v3) = %q", got)
+	}
+	must(c.Unregister("shop", "v1"))
+	if _, err := call_who(t, c, "shop.Who", nil); err == nil {
+		t.Error("after Unregister(v1): err = nil")
+	}
+	if err := c.Unregister("shop", "v1"); err == nil {
+		t.Error("Unregister twice: err = nil")
+	}
+	if err := c.Unregister(SysService, ""); err == nil {
+		t.Error("Unregister(sys): err = nil")
+	}
+}
+
+func TestRegistryReplace(t *testing.T) {
+	c := ServerConn(DefaultServer())
+	if err := c.Register("shop", &versionedSvc{v: "a"}, "", WithVersion("v1")); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Register("shop", &versionedSvc{v: "b"}, "", WithVersion("v1"))
+	if err == nil || err.Error() != "service shop@v1 already registered" {
+		t.Fatalf("duplicate err = %v", err)
+	}
+	if got, _ := call_who(t, c, "shop.Who", nil); got != "a|v1" {
+		t.Fatalf("after duplicate = %q", got)
+	}
+	if err := c.Register("shop", &versionedSvc{v: "b"}, "", WithVersion("v1"), WithReplace()); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := call_who(t, c, "shop.Who", nil); got != "b|v1" {
+		t.Fatalf("after replace = %q", got)
+	}
+	for _, name := range []string{"", "a@b", "a..b", ".a"} {
+		if err := c.Register(name, &versionedSvc{}, ""); err == nil {
+			t.Errorf("Register(%q): err = nil", name)
+		}
+	}
+	if err := c.Register("shop", &versionedSvc{}, "", WithVersion("v@1")); err == nil {
+		t.Error("Register(version v@1): err = nil")
+	}
+}
+
+func TestRegistryDeprecated(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	c := ServerConn(DefaultServer())
+	if err := c.Register("shop", &versionedSvc{v: "old"}, "", WithVersion("v1"), WithDeprecated("use v2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Register("shop", &versionedSvc{v: "new"}, "", WithVersion("v2")); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	for i := 0; i < 3; i++ {
+		if _, err := call_who(t, c, "shop@v1.Who", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := call_who(t, c, "shop@v2.Who", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := strings.Count(buf.String(), "is deprecated: use v2"); n != 1 {
+		t.Fatalf("deprecation warnings = %d, want 1:\n%s", n, buf.String())
+	}
+}
@@ -0,0 +1,312 @@
+package schema
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @brief 由 Go 类型生成 JSON Schema（draft 2020-12 子集）
+ *
+ * 规则与 encoding/json 保持一致：
+ *   - 只导出字段，json:"-" 跳过，json:"name" 改名，omitempty 的字段不进 required；
+ *   - json:",string" 的数值/布尔字段按 string 描述；
+ *   - 匿名嵌入且未改名的结构体字段展开到外层；
+ *   - []byte 为 base64 字符串，time.Time 为 date-time 字符串；
+ *   - 具名结构体放入 $defs，通过 $ref 引用，支持递归类型；
+ *   - 指针、slice、map 可为 null：编码为 "type": ["array", "null"]，$ref 为 anyOf [$ref, null]。
+ */
+
+// DefsPrefix $ref 前缀
+const DefsPrefix = "#/$defs/"
+
+// Schema JSON Schema 节点
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Nullable             bool               `json:"-"` // 可为 null，见 MarshalJSON
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	PrefixItems          []*Schema          `json:"prefixItems,omitempty"` // 定长元组，如多返回值
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// schemaFields Schema 去掉方法，供 MarshalJSON/UnmarshalJSON 使用默认编码
+type schemaFields Schema
+
+// MarshalJSON Nullable 按 2020-12 编码：有 type 时为 ["type", "null"]，$ref 为 anyOf [{"$ref"}, {"type": "null"}]，
+// 其余（任意值）本就允许 null
+func (s Schema) MarshalJSON() ([]byte, error) {
+	switch {
+	case s.Nullable && s.Ref != "":
+		return json.Marshal(struct {
+			Ref   string    `json:"$ref,omitempty"`
+			AnyOf []*Schema `json:"anyOf"`
+			schemaFields
+		}{AnyOf: []*Schema{{Ref: s.Ref}, {Type: "null"}}, schemaFields: schemaFields(s)})
+	case s.Nullable && s.Type != "":
+		return json.Marshal(struct {
+			Type []string `json:"type"`
+			schemaFields
+		}{[]string{s.Type, "null"}, schemaFields(s)})
+	}
+	return json.Marshal(schemaFields(s))
+}
+
+// UnmarshalJSON MarshalJSON 的逆过程：type 数组与 anyOf 中的 null 还原为 Nullable
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	var in struct {
+		Type  json.RawMessage `json:"type"`
+		AnyOf []*Schema       `json:"anyOf"`
+		*schemaFields
+	}
+	in.schemaFields = (*schemaFields)(s)
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	if len(in.Type) > 0 {
+		var types []string
+		if in.Type[0] != '[' {
+			types = make([]string, 1)
+			if err := json.Unmarshal(in.Type, &types[0]); err != nil {
+				return err
+			}
+		} else if err := json.Unmarshal(in.Type, &types); err != nil {
+			return err
+		}
+		for _, t := range types {
+			if t == "null" {
+				s.Nullable = true
+			} else {
+				s.Type = t
+			}
+		}
+	}
+	for _, a := range in.AnyOf {
+		// {"type": "null"} 解码后为只有 Nullable 的节点
+		if a.Ref != "" {
+			s.Ref = a.Ref
+		} else if a.Nullable && a.Type == "" {
+			s.Nullable = true
+		}
+	}
+	return nil
+}
+
+var (
+	typeOfTime      = reflect.TypeOf(time.Time{})
+	typeOfRawJson   = reflect.TypeOf(json.RawMessage{})
+	typeOfMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Generator 生成器，多次调用 Schema 共享同一份 Defs
+type Generator struct {
+	Defs map[string]*Schema
+	refs map[reflect.Type]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		Defs: make(map[string]*Schema),
+		refs: make(map[reflect.Type]string),
+	}
+}
+
+// Of 单个类型的 Schema，结构体定义内联到 $defs 中返回
+func Of(t reflect.Type) (*Schema, map[string]*Schema) {
+	g := NewGenerator()
+	s := g.Schema(t)
+	return s, g.Defs
+}
+
+// Schema 返回类型 t 的 Schema，具名结构体写入 g.Defs 并返回 $ref
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		s := g.Schema(t.Elem())
+		if s.Ref != "" {
+			// $ref 节点不能带其他关键字，包一层
+			return &Schema{Ref: s.Ref, Nullable: true}
+		}
+		s.Nullable = true
+		return s
+	}
+	switch {
+	case t == typeOfTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == typeOfRawJson:
+		return &Schema{}
+	case t.Implements(typeOfMarshaler) || reflect.PointerTo(t).Implements(typeOfMarshaler):
+		// 自定义序列化，形状未知
+		return &Schema{Title: t.String()}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int_schema(t)
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "uint64", Minimum: float_ptr(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.Schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		n := t.Len()
+		return &Schema{Type: "array", Items: g.Schema(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.Schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		return g.struct_schema(t)
+	}
+	// interface、complex、chan、func 等：任意值
+	return &Schema{}
+}
+
+func (g *Generator) struct_schema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.object_schema(t)
+	}
+	if name, ok := g.refs[t]; ok {
+		return &Schema{Ref: DefsPrefix + name}
+	}
+	name := def_name(t)
+	// 同名不同包的类型加序号区分
+	for i := 2; g.Defs[name] != nil; i++ {
+		name = def_name(t) + "_" + strconv.Itoa(i)
+	}
+	g.refs[t] = name
+	g.Defs[name] = &Schema{} // 占位，支持递归
+	*g.Defs[name] = *g.object_schema(t)
+	return &Schema{Ref: DefsPrefix + name}
+}
+
+func (g *Generator) object_schema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+	if t.Name() != "" {
+		s.Title = t.String()
+	}
+	g.fields(t, s)
+	return s
+}
+
+// fields 把 t 的字段写入 s，匿名嵌入结构体展开
+func (g *Generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			et := ft
+			if et.Kind() == reflect.Pointer {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				g.fields(et, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := s.Properties[name]; ok {
+			// 外层字段优先于嵌入字段
+			continue
+		}
+		var fs *Schema
+		if has_opt(opts, "string") && is_scalar(ft) {
+			fs = &Schema{Type: "string"}
+		} else {
+			fs = g.Schema(ft)
+		}
+		s.Properties[name] = fs
+		if !has_opt(opts, "omitempty") && !has_opt(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func int_schema(t reflect.Type) *Schema {
+	s := &Schema{Type: "integer", Format: "int32"}
+	switch t.Kind() {
+	case reflect.Int8:
+		s.Minimum, s.Maximum = float_ptr(math.MinInt8), float_ptr(math.MaxInt8)
+	case reflect.Int16:
+		s.Minimum, s.Maximum = float_ptr(math.MinInt16), float_ptr(math.MaxInt16)
+	case reflect.Int32:
+		s.Minimum, s.Maximum = float_ptr(math.MinInt32), float_ptr(math.MaxInt32)
+	case reflect.Uint8:
+		s.Minimum, s.Maximum = float_ptr(0), float_ptr(math.MaxUint8)
+	case reflect.Uint16:
+		s.Minimum, s.Maximum = float_ptr(0), float_ptr(math.MaxUint16)
+	case reflect.Uint32:
+		s.Format = "int64"
+		s.Minimum, s.Maximum = float_ptr(0), float_ptr(math.MaxUint32)
+	}
+	return s
+}
+
+func is_scalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.String:
+		return true
+	}
+	return false
+}
+
+func has_opt(opts, name string) bool {
+	for opts != "" {
+		var o string
+		o, opts, _ = strings.Cut(opts, ",")
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// def_name $defs 中的键，形如 pkg.Type；泛型实参中的 / 等字符替换掉
+func def_name(t reflect.Type) string {
+	return strings.NewReplacer("/", "_", "[", "_", "]", "", ",", "_", " ", "", "*", "").Replace(t.String())
+}
+
+func float_ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type base struct {
+	Id      int64     `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type node struct {
+	base
+	Name     string            `json:"name"`
+	Note     string            `json:"note,omitempty"`
+	Count    uint16            `json:"count,string"`
+	Data     []byte            `json:"data"`
+	Tags     []string          `json:"tags"`
+	Attrs    map[string]int    `json:"attrs,omitempty"`
+	Parent   *node             `json:"parent"`
+	Children []node            `json:"children"`
+	Any      any               `json:"any"`
+	Skip     string            `json:"-"`
+	Inline   struct{ X bool }  `json:"inline"`
+	Fixed    [2]float32        `json:"fixed"`
+	Raw      json.RawMessage   `json:"raw"`
+	Nested   map[string][]node `json:"nested"`
+
+	hidden string
+}
+
+func TestSchema_Scalars(t *testing.T) {
+	cases := []struct {
+		in     any
+		typ    string
+		format string
+	}{
+		{true, "boolean", ""},
+		{int8(0), "integer", "int32"},
+		{int64(0), "integer", "int64"},
+		{uint32(0), "integer", "int64"},
+		{uint64(0), "integer", "uint64"},
+		{float32(0), "number", "float"},
+		{float64(0), "number", "double"},
+		{"", "string", ""},
+		{[]byte{}, "string", "byte"},
+		{time.Time{}, "string", "date-time"},
+	}
+	for _, c := range cases {
+		s, defs := Of(reflect.TypeOf(c.in))
+		if s.Type != c.typ || s.Format != c.format || len(defs) != 0 {
+			t.Errorf("%T: got %s/%s, want %s/%s", c.in, s.Type, s.Format, c.typ, c.format)
+		}
+	}
+	s, _ := Of(reflect.TypeOf(uint8(0)))
+	if *s.Minimum != 0 || *s.Maximum != 255 {
+		t.Errorf("uint8 range = [%v, %v]", *s.Minimum, *s.Maximum)
+	}
+}
+
+func TestSchema_Struct(t *testing.T) {
+	s, defs := Of(reflect.TypeOf(&node{}))
+	if s.Ref != DefsPrefix+"schema.node" || !s.Nullable {
+		t.Fatalf("root = %+v", s)
+	}
+	d := defs["schema.node"]
+	if d == nil || d.Type != "object" {
+		t.Fatalf("defs = %v", defs)
+	}
+	// 嵌入字段展开，json:"-" 与未导出字段跳过
+	want := []string{"id", "created", "name", "note", "count", "data", "tags", "attrs", "parent", "children", "any", "inline", "fixed", "raw", "nested"}
+	got := make([]string, 0, len(d.Properties))
+	for k := range d.Properties {
+		got = append(got, k)
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("properties = %v, want %v", got, want)
+	}
+	// omitempty 不进 required
+	if slices.Contains(d.Required, "note") || slices.Contains(d.Required, "attrs") || !slices.Contains(d.Required, "name") {
+		t.Fatalf("required = %v", d.Required)
+	}
+	if p := d.Properties["count"]; p.Type != "string" {
+		t.Errorf("count,string = %+v", p)
+	}
+	if p := d.Properties["parent"]; p.Ref != DefsPrefix+"schema.node" || !p.Nullable {
+		t.Errorf("parent = %+v", p)
+	}
+	if p := d.Properties["children"]; p.Type != "array" || p.Items.Ref != DefsPrefix+"schema.node" {
+		t.Errorf("children = %+v", p)
+	}
+	if p := d.Properties["nested"]; p.AdditionalProperties.Items.Ref != DefsPrefix+"schema.node" {
+		t.Errorf("nested = %+v", p)
+	}
+	if p := d.Properties["inline"]; p.Ref != "" || p.Properties["X"].Type != "boolean" {
+		t.Errorf("inline = %+v", p)
+	}
+	if p := d.Properties["fixed"]; p.Type != "array" || *p.MinItems != 2 || *p.MaxItems != 2 {
+		t.Errorf("fixed = %+v", p)
+	}
+	if _, err := json.Marshal(defs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 可为 null 按 2020-12 编码（没有 nullable 关键字），解码后还原
+func TestSchema_NullableJSON(t *testing.T) {
+	s, defs := Of(reflect.TypeOf(&node{}))
+	d := defs["schema.node"]
+	cases := []struct {
+		name string
+		in   *Schema
+		want string
+	}{
+		{"ref", s, `{"anyOf":[{"$ref":"#/$defs/schema.node"},{"type":"null"}]}`},
+		{"slice", d.Properties["tags"], `{"type":["array","null"],"items":{"type":"string"}}`},
+		{"map", d.Properties["attrs"], `{"type":["object","null"],"additionalProperties":{"type":"integer","format":"int64"}}`},
+		{"any", d.Properties["any"], `{}`},
+		{"fixed", d.Properties["fixed"], `{"type":"array","minItems":2,"maxItems":2,"items":{"type":"number","format":"float"}}`},
+	}
+	for _, c := range cases {
+		b, err := json.Marshal(c.in)
+		if err != nil || string(b) != c.want {
+			t.Errorf("%s: %s, %v; want %s", c.name, b, err, c.want)
+			continue
+		}
+		var back Schema
+		if err := json.Unmarshal(b, &back); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if back.Type != c.in.Type || back.Ref != c.in.Ref || back.Nullable != c.in.Nullable {
+			t.Errorf("%s: round trip %+v, want %+v", c.name, back, c.in)
+		}
+	}
+}
+
+func TestGenerator_SharedDefs(t *testing.T) {
+	g := NewGenerator()
+	a := g.Schema(reflect.TypeOf(node{}))
+	b := g.Schema(reflect.TypeOf([]*node{}))
+	if a.Ref != b.Items.Ref || len(g.Defs) != 1 {
+		t.Fatalf("a=%+v b=%+v defs=%d", a, b, len(g.Defs))
+	}
+}
@@ -10,14 +10,24 @@ type RpcNode struct {
 	Method  string
 }
 
-// server.method 格式
+// server.method 格式，server 可以分层：shop.order.Create -> shop.order / Create
 func GetNode(svr string) (*RpcNode, error) {
-	sm := strings.Split(svr, ".")
-	if len(sm) != 2 {
+	service, method, ok := split_node(svr)
+	if !ok {
 		return nil, fmt.Errorf("service %s format error", svr)
 	}
 	return &RpcNode{
-		Service: sm[0],
-		Method:  sm[1],
+		Service: service,
+		Method:  method,
 	}, nil
 }
+
+// split_node 拆分 server.method，最后一段为方法，服务名可以带点号分层（shop.order.Create）；
+// 不分配内存，供每次调用的热路径使用
+func split_node(svr string) (string, string, bool) {
+	i := strings.LastIndexByte(svr, '.')
+	if i <= 0 || i == len(svr)-1 {
+		return "", "", false
+	}
+	return svr[:i], svr[i+1:], true
+}
@@ -0,0 +1,441 @@
+package tsgen
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/w6xian/sloth/v3"
+	"github.com/w6xian/sloth/v3/schema"
+)
+
+/**
+ * @brief 由服务契约（sloth.Contract）生成 Web SDK（sock_rpc_v3.js / sloth_rpc_v3.js）之上的客户端存根
+ *
+ *   - TS：结构体生成 interface，每个服务生成 <Service>Client 类，方法参数/返回值带类型；
+ *   - JS：同样的类，挂到 globalThis.SlothApi，浏览器直接 <script> 引入；
+ *   - 参数按 Go 类型选择 ag 编码：int64/uint64 → BigInt，int8/16/32（rune）、uint*、float32 → AG.As*；
+ *   - 返回值兼容 ag 帧（SDK 已解码）与 utils.AnyToBytes 的原始字节（文本/JSON）。
+ */
+
+type Lang string
+
+const (
+	LangTS Lang = "ts"
+	LangJS Lang = "js"
+)
+
+// Generate 生成存根源码
+func Generate(ct *sloth.Contract, lang Lang) ([]byte, error) {
+	if lang != LangTS && lang != LangJS {
+		return nil, fmt.Errorf("tsgen: unknown lang %q", lang)
+	}
+	g := &generator{ct: ct, ts: lang == LangTS, names: def_names(ct.Defs)}
+	g.header()
+	if g.ts {
+		g.interfaces()
+	}
+	g.decoder()
+	for _, s := range ct.Services {
+		g.service(s)
+	}
+	g.footer()
+	return g.b.Bytes(), nil
+}
+
+// Handler 按请求路径后缀（.ts / .js）返回当前连接的存根，保证与运行中的服务一致
+// @example
+//
+//	http.Handle("/sloth_api.js", tsgen.Handler(conn))
+//	http.Handle("/sloth_api.ts", tsgen.Handler(conn))
+func Handler(c *sloth.Connect) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang, ctype := LangJS, "text/javascript; charset=utf-8"
+		if strings.HasSuffix(r.URL.Path, ".ts") {
+			lang, ctype = LangTS, "application/typescript; charset=utf-8"
+		}
+		src, err := Generate(c.Contract(), lang)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ctype)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(src)
+	})
+}
+
+type generator struct {
+	b     bytes.Buffer
+	ct    *sloth.Contract
+	ts    bool
+	names map[string]string // $defs 键 -> TS 类型名
+}
+
+func (g *generator) p(format string, args ...any) {
+	fmt.Fprintf(&g.b, format, args...)
+	g.b.WriteByte('\n')
+}
+
+// ann TS 类型标注，JS 下为空
+func (g *generator) ann(t string) string {
+	if !g.ts {
+		return ""
+	}
+	return ": " + t
+}
+
+func (g *generator) header() {
+	g.p("// Code generated by sloth tsgen. DO NOT EDIT.")
+	g.p("// server %s, version %s", g.ct.ServerId, g.ct.Version)
+	g.p("// 依赖 Web SDK：tools.js/slice.js/ag.js/fn.js/sock_rpc_v3.js（或合并后的 sloth_rpc_v3.js），AG 为全局对象。")
+	g.p("")
+	if g.ts {
+		g.p("declare const AG: any;")
+		g.p("")
+		g.p("/** SockRpcV3 中存根用到的部分 */")
+		g.p("export interface SlothRpc {")
+		g.p("  CallPromise(method: string, ...args: any[]): Promise<any>;")
+		g.p("}")
+		g.p("")
+		g.p("export interface Complex {")
+		g.p("  real: number;")
+		g.p("  imag: number;")
+		g.p("}")
+		g.p("")
+		return
+	}
+	g.p("(function (root) {")
+	g.p("'use strict';")
+	g.p("")
+}
+
+func (g *generator) footer() {
+	if g.ts {
+		return
+	}
+	names := make([]string, 0, len(g.ct.Services))
+	for _, s := range g.ct.Services {
+		names = append(names, client_name(s.Name))
+	}
+	g.p("root.SlothApi = { %s };", strings.Join(names, ", "))
+	g.p("})(typeof globalThis !== 'undefined' ? globalThis : (typeof window !== 'undefined' ? window : this));")
+}
+
+func (g *generator) interfaces() {
+	for _, key := range slices.Sorted(maps.Keys(g.ct.Defs)) {
+		d := g.ct.Defs[key]
+		g.p("/** %s */", key)
+		g.p("export interface %s %s", g.names[key], g.object(d, ""))
+		g.p("")
+	}
+}
+
+// object 结构体 schema 的 TS 对象字面量类型
+func (g *generator) object(s *schema.Schema, indent string) string {
+	if len(s.Properties) == 0 {
+		return "{}"
+	}
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		opt := "?"
+		if slices.Contains(s.Required, name) {
+			opt = ""
+		}
+		fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, prop_name(name), opt, g.json_type(s.Properties[name], indent+"  "))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// json_type JSON 层面的 TS 类型（结构体字段、复合参数/返回值）
+func (g *generator) json_type(s *schema.Schema, indent string) string {
+	if s == nil {
+		return "any"
+	}
+	t := "any"
+	switch {
+	case s.Ref != "":
+		t = g.names[strings.TrimPrefix(s.Ref, schema.DefsPrefix)]
+	case s.Type == "string":
+		t = "string"
+	case s.Type == "integer" || s.Type == "number":
+		t = "number"
+	case s.Type == "boolean":
+		t = "boolean"
+	case s.Type == "array" && len(s.PrefixItems) > 0:
+		items := make([]string, len(s.PrefixItems))
+		for i, item := range s.PrefixItems {
+			items[i] = g.json_type(item, indent)
+		}
+		t = "[" + strings.Join(items, ", ") + "]"
+	case s.Type == "array":
+		t = array_of(g.json_type(s.Items, indent))
+	case s.Type == "object" && s.AdditionalProperties != nil:
+		t = "Record<string, " + g.json_type(s.AdditionalProperties, indent) + ">"
+	case s.Type == "object":
+		t = g.object(s, indent)
+	}
+	// Nullable 由 schema 的 type 数组 / anyOf null 还原（见 schema.Schema.UnmarshalJSON）
+	if s.Nullable && t != "any" {
+		t += " | null"
+	}
+	return t
+}
+
+// decoder 返回值解码：SDK 对 ag 帧已解码，非 ag 帧为 utils.AnyToBytes 的原始字节
+func (g *generator) decoder() {
+	g.p("function decodeReply(kind%s, r%s)%s {", g.ann("string"), g.ann("any"), g.ann("any"))
+	g.p("  if (!(r instanceof Uint8Array) || kind === 'bytes') return r;")
+	g.p("  const s = new TextDecoder().decode(r);")
+	g.p("  if (kind === 'string') return s;")
+	g.p("  if (s === '') return null;")
+	g.p("  switch (kind) {")
+	g.p("    case 'bool': return s === 'true';")
+	g.p("    case 'int64': case 'uint64': return BigInt(s);")
+	g.p("    case 'int': case 'int8': case 'int16': case 'int32':")
+	g.p("    case 'uint': case 'uint8': case 'uint16': case 'uint32': case 'uintptr':")
+	g.p("    case 'float32': case 'float64': return Number(s);")
+	g.p("    case 'complex64': case 'complex128': {")
+	g.p("      const m = /^\\(([^+]+?)([+-][^i]+)i\\)$/.exec(s);")
+	g.p("      return m ? { real: Number(m[1]), imag: Number(m[2]) } : s;")
+	g.p("    }")
+	g.p("  }")
+	g.p("  try {")
+	g.p("    return JSON.parse(s);")
+	g.p("  } catch (_) {")
+	g.p("    return s; // any 类型的文本结果")
+	g.p("  }")
+	g.p("}")
+	g.p("")
+}
+
+func (g *generator) service(s sloth.ServiceContract) {
+	name := client_name(s.Name)
+	if s.Deprecated != "" {
+		g.p("/**")
+		g.p(" * %s 服务（%s）%s", s.Name, s.Type, s.Meta)
+		g.p(" * @deprecated %s", s.Deprecated)
+		g.p(" */")
+	} else {
+		g.p("/** %s 服务（%s）%s */", s.Name, s.Type, s.Meta)
+	}
+	if g.ts {
+		g.p("export class %s {", name)
+		g.p("  constructor(private rpc: SlothRpc) {}")
+	} else {
+		g.p("class %s {", name)
+		g.p("  constructor(rpc) { this.rpc = rpc; }")
+	}
+	for _, m := range s.Methods {
+		g.p("")
+		g.method(m)
+	}
+	g.p("}")
+	g.p("")
+}
+
+func (g *generator) method(m sloth.MethodContract) {
+	decls := make([]string, 0, len(m.Params))
+	args := make([]string, 0, len(m.Params)+1)
+	args = append(args, strconv.Quote(m.Method))
+	types := make([]string, 0, len(m.Params))
+	for i, p := range m.Params {
+		pn := ident(p.Name, i)
+		types = append(types, p.Type)
+		if m.Variadic && i == len(m.Params)-1 {
+			// 可变参数逐个编码为独立的参数
+			p.Type = strings.TrimPrefix(p.Type, "...")
+			decls = append(decls, "..."+pn+g.ann(array_of(g.param_type(p))))
+			if e := encode_expr("v", p); e != "v" {
+				pn = fmt.Sprintf("%s.map((v%s) => %s)", pn, g.ann("any"), e)
+			}
+			args = append(args, "..."+pn)
+			continue
+		}
+		decls = append(decls, pn+g.ann(g.param_type(p)))
+		args = append(args, encode_expr(pn, p))
+	}
+	ret := "void"
+	if !m.Notify {
+		ret = g.result_type(m)
+	}
+	g.p("  /** %s(%s) %s */", m.Method, strings.Join(types, ", "), m.ResultType)
+	g.p("  %s(%s)%s {", m.Name, strings.Join(decls, ", "), g.ann("Promise<"+ret+">"))
+	call := fmt.Sprintf("this.rpc.CallPromise(%s)", strings.Join(args, ", "))
+	if m.Notify {
+		g.p("    return %s.then(() => undefined);", call)
+	} else {
+		g.p("    return %s.then((r%s) => decodeReply(%q, r));", call, g.ann("any"), m.ResultKind)
+	}
+	g.p("  }")
+}
+
+// param_type 参数的 TS 类型（ag 层面）
+func (g *generator) param_type(p sloth.ParamContract) string {
+	t := scalar_type(p.Kind, true)
+	if t == "" {
+		s := *p.Schema
+		s.Nullable = false
+		t = g.json_type(&s, "  ")
+	}
+	if strings.HasPrefix(p.Type, "*") || p.Schema.Nullable {
+		t += " | null"
+	}
+	return t
+}
+
+func (g *generator) result_type(m sloth.MethodContract) string {
+	t := scalar_type(m.ResultKind, false)
+	if t == "" {
+		return g.json_type(m.Result, "  ")
+	}
+	if strings.HasPrefix(m.ResultType, "*") {
+		t += " | null"
+	}
+	return t
+}
+
+// scalar_type 标量 Kind 的 TS 类型；复合类型返回空串
+func scalar_type(kind string, param bool) string {
+	switch kind {
+	case "bool":
+		return "boolean"
+	case "string":
+		return "string"
+	case "bytes":
+		return "Uint8Array"
+	case "int64", "uint64":
+		if param {
+			return "bigint | number"
+		}
+		return "bigint"
+	case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32", "uintptr", "float32", "float64":
+		return "number"
+	case "complex64", "complex128":
+		return "Complex"
+	case "interface":
+		return "any"
+	}
+	return ""
+}
+
+// encode_expr 按 Go 参数类型选择 ag 标签
+func encode_expr(v string, p sloth.ParamContract) string {
+	var e string
+	switch p.Kind {
+	case "int":
+		e = fmt.Sprintf("AG.Tagged(AG.ArgumentTypeInt, %s)", v)
+	case "int8":
+		e = fmt.Sprintf("AG.AsInt8(%s)", v)
+	case "int16":
+		e = fmt.Sprintf("AG.AsInt16(%s)", v)
+	case "int32":
+		e = fmt.Sprintf("AG.AsInt32(%s)", v)
+	case "int64":
+		e = fmt.Sprintf("AG.AsInt64(BigInt(%s))", v)
+	case "uint":
+		e = fmt.Sprintf("AG.AsUint(%s)", v)
+	case "uint8":
+		e = fmt.Sprintf("AG.AsUint8(%s)", v)
+	case "uint16":
+		e = fmt.Sprintf("AG.AsUint16(%s)", v)
+	case "uint32":
+		e = fmt.Sprintf("AG.AsUint32(%s)", v)
+	case "uint64":
+		e = fmt.Sprintf("AG.AsUint64(BigInt(%s))", v)
+	case "uintptr":
+		e = fmt.Sprintf("AG.AsUintptr(%s)", v)
+	case "float32":
+		e = fmt.Sprintf("AG.AsFloat32(%s)", v)
+	case "float64":
+		// 整数值的 number 默认按 Int 编码，这里显式标记
+		e = fmt.Sprintf("AG.Tagged(AG.ArgumentTypeFloat64, %s)", v)
+	case "complex64":
+		e = fmt.Sprintf("AG.AsComplex64(%s.real, %s.imag)", v, v)
+	case "complex128":
+		e = fmt.Sprintf("AG.Tagged(AG.ArgumentTypeComplex128, %s)", v)
+	default:
+		return v
+	}
+	if strings.HasPrefix(p.Type, "*") {
+		return fmt.Sprintf("%s == null ? null : %s", v, e)
+	}
+	return e
+}
+
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9_$]`)
+
+var reserved = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+	"let": true, "static": true, "yield": true, "await": true, "interface": true, "package": true,
+	"private": true, "protected": true, "public": true, "implements": true,
+}
+
+// ident 参数名，非法或保留字改为 argN
+func ident(name string, i int) string {
+	if name == "" || nonIdent.MatchString(name) || reserved[name] || (name[0] >= '0' && name[0] <= '9') {
+		return "arg" + strconv.Itoa(i)
+	}
+	return name
+}
+
+// prop_name 属性名，非法标识符加引号
+func prop_name(name string) string {
+	if name != "" && !nonIdent.MatchString(name) && !(name[0] >= '0' && name[0] <= '9') {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// client_name 服务名 -> 类名，v1 -> V1Client，shop.order -> ShopOrderClient
+func client_name(service string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(service, func(r rune) bool { return nonIdent.MatchString(string(r)) }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	b.WriteString("Client")
+	return b.String()
+}
+
+// def_names $defs 键（pkg.Type）-> TS 类型名：优先用 Type，重名时带上包名
+func def_names(defs map[string]*schema.Schema) map[string]string {
+	short := func(key string) string {
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			key = key[i+1:]
+		}
+		return key
+	}
+	// 与存根内置的类型名冲突时也带上包名
+	count := map[string]int{"SlothRpc": 1, "Complex": 1}
+	for key := range defs {
+		count[short(key)]++
+	}
+	names := make(map[string]string, len(defs))
+	for key := range defs {
+		n := short(key)
+		if count[n] > 1 {
+			n = key
+		}
+		names[key] = nonIdent.ReplaceAllString(n, "_")
+	}
+	return names
+}
+
+func array_of(t string) string {
+	if strings.ContainsAny(t, " |{") {
+		return "Array<" + t + ">"
+	}
+	return t + "[]"
+}
@@ -0,0 +1,166 @@
+package tsgen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/w6xian/sloth/v3"
+)
+
+type Item struct {
+	Id    int64             `json:"id"`
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags,omitempty"`
+	Attrs map[string]string `json:"attrs"`
+	Next  *Item             `json:"next"`
+}
+
+type shop struct{}
+
+func (s *shop) Find(ctx context.Context, id int64, n int16, r rune, score float64, ok *bool, raw []byte) (*Item, error) {
+	return nil, nil
+}
+func (s *shop) List(ctx context.Context, items []Item, filter map[string]int) ([]Item, error) {
+	return nil, nil
+}
+func (s *shop) Count(ctx context.Context, u uint64) (uint64, error) { return 0, nil }
+func (s *shop) Ping(ctx context.Context, msg string) error          { return nil }
+func (s *shop) Sum(ctx context.Context, base int, n ...int64) (int64, error) {
+	return 0, nil
+}
+func (s *shop) Pair(ctx context.Context, key string) (string, *Item, error) { return "", nil, nil }
+
+func contract(t *testing.T) *sloth.Contract {
+	t.Helper()
+	conn := sloth.ServerConn(sloth.DefaultServer())
+	if err := conn.Register("shop.v1", &shop{}, "shop service"); err != nil {
+		t.Fatal(err)
+	}
+	ct, ok := conn.ContractService("shop.v1")
+	if !ok {
+		t.Fatal("service not found")
+	}
+	return ct
+}
+
+func TestGenerate_TS(t *testing.T) {
+	src, err := Generate(contract(t), LangTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"export interface Item {\n  attrs: Record<string, string> | null;\n  id: number;\n  name: string;\n  next: Item | null;\n  tags?: string[] | null;\n}",
+		"export class ShopV1Client {",
+		"Find(arg0: bigint | number, arg1: number, arg2: number, arg3: number, arg4: boolean | null, arg5: Uint8Array): Promise<Item | null> {",
+		`this.rpc.CallPromise("shop.v1.Find", AG.AsInt64(BigInt(arg0)), AG.AsInt16(arg1), AG.AsInt32(arg2), AG.Tagged(AG.ArgumentTypeFloat64, arg3), arg4, arg5)`,
+		`decodeReply("struct", r)`,
+		"List(arg0: Item[] | null, arg1: Record<string, number> | null): Promise<Item[] | null> {",
+		"Count(arg0: bigint | number): Promise<bigint> {",
+		`AG.AsUint64(BigInt(arg0))`,
+		// notify 方法返回 void
+		"Ping(arg0: string): Promise<void> {",
+		`this.rpc.CallPromise("shop.v1.Ping", arg0).then(() => undefined)`,
+		// 可变参数逐个编码
+		"Sum(arg0: number, ...arg1: Array<bigint | number>): Promise<bigint> {",
+		`this.rpc.CallPromise("shop.v1.Sum", AG.Tagged(AG.ArgumentTypeInt, arg0), ...arg1.map((v: any) => AG.AsInt64(BigInt(v))))`,
+		// 多返回值为元组
+		"Pair(arg0: string): Promise<[string, Item | null]> {",
+		`decodeReply("tuple", r)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+// sloth-contract 经 JSON 拿到的契约（可为 null 编码为 type 数组与 anyOf）生成同样的存根
+func TestGenerate_FromJSON(t *testing.T) {
+	ct := contract(t)
+	want, err := Generate(ct, LangTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "nullable") || !strings.Contains(string(b), `"anyOf"`) {
+		t.Fatalf("contract json = %s", b)
+	}
+	var back sloth.Contract
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Generate(&back, LangTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGenerate_Deprecated(t *testing.T) {
+	conn := sloth.ServerConn(sloth.DefaultServer())
+	if err := conn.Register("shop", &shop{}, "shop service", sloth.WithVersion("v1"), sloth.WithDeprecated("use v2")); err != nil {
+		t.Fatal(err)
+	}
+	ct, ok := conn.ContractService("shop")
+	if !ok {
+		t.Fatal("service not found")
+	}
+	src, err := Generate(ct, LangTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		" * @deprecated use v2\n */\nexport class ShopV1Client {",
+		`this.rpc.CallPromise("shop@v1.Count", `,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestGenerate_JS(t *testing.T) {
+	src, err := Generate(contract(t), LangJS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+	for _, bad := range []string{"interface ", ": Promise<", "export "} {
+		if strings.Contains(out, bad) {
+			t.Errorf("js output contains %q", bad)
+		}
+	}
+	for _, want := range []string{
+		"class ShopV1Client {",
+		"Find(arg0, arg1, arg2, arg3, arg4, arg5) {",
+		"root.SlothApi = { ShopV1Client };",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if _, err := Generate(contract(t), "go"); err == nil {
+		t.Fatal("want error for unknown lang")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	conn := sloth.ServerConn(sloth.DefaultServer())
+	h := Handler(conn)
+	for path, ctype := range map[string]string{"/sloth_api.ts": "application/typescript", "/sloth_api.js": "text/javascript"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), ctype) || !strings.Contains(w.Body.String(), "SysClient") {
+			t.Errorf("%s: code=%d type=%s", path, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -7,6 +7,10 @@ type AuthInfo struct {
 	RoomId int64  `json:"room_id"`
 	Token  string `json:"token"`
 	Ts     int64  `json:"ts"`
+
+	// DeviceId 设备/会话 ID，同一用户的不同设备可同时在线。
+	// 框架不会自动登记，应用的登录服务需自行调用 Bucket.PutDevice（Put 不区分设备）
+	DeviceId string `json:"device_id,omitempty"`
 }
 
 func (a *AuthInfo) Json() ([]byte, error) {
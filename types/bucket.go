@@ -18,6 +18,11 @@ type IConnInfo interface {
 type IBucket interface {
 	Bucket(userId int64) *bucket.Bucket
 	Channel(userId int64) bucket.IChannel
+	// Room 某个 bucket 中的房间分片
+	//
+	// Deprecated: 请使用 ServerRoom。
 	Room(roomId int64) *bucket.Room
+	// ServerRoom 汇总所有 bucket 的房间视图
+	ServerRoom(roomId int64) *bucket.ServerRoom
 	Broadcast(ctx context.Context, msg *message.Msg) error
 }
@@ -9,7 +9,12 @@ import (
 
 type IServer interface {
 	Bucket(userId int64) *bucket.Bucket
+	// Room 某个 bucket 中的房间分片
+	//
+	// Deprecated: 请使用 ServerRoom。
 	Room(roomId int64) *bucket.Room
+	// ServerRoom 汇总所有 bucket 的房间视图
+	ServerRoom(roomId int64) *bucket.ServerRoom
 	Channel(userId int64) bucket.IChannel
 	Broadcast(ctx context.Context, msg *message.Msg) error
 	AllBuckets() []*bucket.Bucket